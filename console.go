@@ -9,26 +9,39 @@ import (
 	"github.com/jedib0t/go-pretty/table"
 	"github.com/schollz/progressbar/v3"
 	"go.uber.org/zap"
+	"io"
 	"os"
-	"path"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 var (
-	nspFolder   = flag.String("f", "", "path to NSP folder")
-	recursive   = flag.Bool("r", true, "recursively scan sub folders")
-	mode        = flag.String("m", "", "**deprecated**")
-	progressBar *progressbar.ProgressBar
+	nspFolder      = flag.String("f", "", "path to NSP folder")
+	recursive      = flag.Bool("r", true, "recursively scan sub folders")
+	mode           = flag.String("m", "", "**deprecated**")
+	printTree      = flag.Bool("t", false, "print the local library as a tree")
+	httpAddress    = flag.String("http", "", "serve the library reports as an HTTP API on the given address (e.g. 127.0.0.1:8080)")
+	installerIndex = flag.String("installer-index", "", "write an installer (Tinfoil/Awoo) index of the library to the given file")
+	installerUrl   = flag.String("installer-url", "", "base URL of the library files in the installer index")
+	sidecarsFolder = flag.String("sidecars", "", "write a JSON metadata file per title to the given folder")
+	exportFile     = flag.String("export", "", "export the library files to the given .json or .csv file")
+	duplicateFiles = flag.Bool("duplicate-files", false, "list the files having the exact same content (compares the files content)")
+	concurrency    = flag.Int("concurrency", 0, "max number of files read concurrently (e.g. 1 for a NAS), 0 uses the scan_options")
+	recheck        = flag.Bool("recheck", false, "read again the files which failed to be read by the previous scans")
+	rescan         = flag.String("rescan", "", "comma separated files or folders to read again, ignoring their cached metadata")
+	progressBar    *progressbar.ProgressBar
 )
 
 type Console struct {
 	baseFolder  string
 	sugarLogger *zap.SugaredLogger
+	//the text reports (tree/tables) output, see settings output_encoding
+	output io.Writer
 }
 
 func CreateConsole(baseFolder string, sugarLogger *zap.SugaredLogger) *Console {
-	return &Console{baseFolder: baseFolder, sugarLogger: sugarLogger}
+	return &Console{baseFolder: baseFolder, sugarLogger: sugarLogger, output: os.Stdout}
 }
 
 func (c *Console) Start() {
@@ -38,7 +51,15 @@ func (c *Console) Start() {
 		fmt.Println("note : the mode option ('-m') is deprecated, please use the settings.json to control options.")
 	}
 
+	if httpAddress != nil && *httpAddress != "" {
+		if err := CreateServer(c.baseFolder, c.sugarLogger).Start(*httpAddress); err != nil {
+			fmt.Printf("failed to start the HTTP API :%v\n", err)
+		}
+		return
+	}
+
 	settingsObj := settings.ReadSettings(c.baseFolder)
+	c.output = process.NewEncodingWriter(os.Stdout, settingsObj.OutputEncoding)
 
 	//1. load the titles JSON object
 	fmt.Printf("Downlading latest switch titles json file")
@@ -87,8 +108,10 @@ func (c *Console) Start() {
 	fmt.Printf("\n\nScanning folder [%v]", folderToScan)
 	progressBar = progressbar.New(2000)
 	keys, _ := settings.InitSwitchKeys(c.baseFolder)
-	if keys == nil || keys.GetKey("header_key") == "" {
-		fmt.Printf("\n!!NOTE!!: keys file was not found, deep scan is disabled, library will be based on file tags.\n %v", err)
+	if _, keysErr := settings.SwitchKeys(); keysErr != nil {
+		fmt.Printf("\n!!NOTE!!: %v, deep scan is disabled, library will be based on file tags.\n", keysErr)
+	} else if keys == nil || keys.GetKey("header_key") == "" {
+		fmt.Printf("\n!!NOTE!!: keys file was not found, deep scan is disabled, library will be based on file tags.\n")
 	}
 
 	recursiveMode := settingsObj.ScanRecursively
@@ -102,23 +125,90 @@ func (c *Console) Start() {
 		return
 	}
 	defer localDbManager.Close()
+	if concurrency != nil {
+		localDbManager.SetMaxConcurrency(*concurrency)
+	}
+	if recheck != nil && *recheck {
+		localDbManager.SetRecheckFailedFiles(true)
+	}
+	if !localDbManager.CacheEnabled() {
+		fmt.Printf("\n!!NOTE!!: unable to write to [%v], scan results will not be cached.\n", c.baseFolder)
+	}
 
 	scanFolders := settingsObj.ScanFolders
 	scanFolders = append(scanFolders, folderToScan)
 
-	localDB, err := localDbManager.CreateLocalSwitchFilesDB(scanFolders, c, recursiveMode, true)
+	var rescanPaths []string
+	if rescan != nil && *rescan != "" {
+		rescanPaths = strings.Split(*rescan, ",")
+	}
+	localDB, err := localDbManager.CreateLocalSwitchFilesDBWithRescan(scanFolders, rescanPaths, c, recursiveMode)
 	if err != nil {
 		fmt.Printf("\nfailed to process local folder\n %v", err)
 		return
 	}
+	if removed, err := localDbManager.PruneMissing(scanFolders); err != nil {
+		c.sugarLogger.Warnf("failed to prune the metadata cache - %v", err)
+	} else if removed != 0 {
+		c.sugarLogger.Infof("removed %v stale metadata cache entries", removed)
+	}
 	progressBar.Finish()
+	localDB.RelativePaths = settingsObj.RelativePaths
+	if len(localDB.ScanErrors) != 0 {
+		fmt.Printf("\n!!NOTE!!: %d files/folders could not be read, the library may be incomplete (see the log for details).\n", len(localDB.ScanErrors))
+		for _, scanErr := range localDB.ScanErrors {
+			c.sugarLogger.Warnf("unable to read %v", scanErr.Error())
+		}
+	}
+	if localDB.LowConfidence {
+		fmt.Printf("\n!!NOTE!!: quick scan (file names only), the library grouping is approximate. set \"scan_depth\" to \"full\" for an accurate scan.\n")
+	} else if unverified := localDB.UnverifiedFiles(); len(unverified) != 0 {
+		fmt.Printf("\n!!NOTE!!: %d files were identified by their name only (unverified), add prod.keys for an accurate scan.\n", len(unverified))
+	}
+	if localDB.CacheMisses != 0 {
+		fmt.Printf("\n%d cached, %d parsed (cache saved ~%v)\n", localDB.CacheHits, localDB.CacheMisses, localDB.CacheTimeSaved.Round(time.Second))
+	}
 
 	p := (float32(len(localDB.TitlesMap)) / float32(len(titlesDB.TitlesMap))) * 100
 
 	fmt.Printf("Local library completion status: %.2f%% (have %d titles, out of %d titles)\n", p, len(localDB.TitlesMap), len(titlesDB.TitlesMap))
 
+	if firmware, titles := process.MaxRequiredFirmware(localDB); firmware != "" {
+		fmt.Printf("To run your entire library you need firmware %v (required by %d titles)\n", firmware, len(titles))
+	}
+
+	if ticketless := process.TicketsReport(localDB).Ticketless; len(ticketless) != 0 {
+		fmt.Printf("%d files have no ticket (ticketless), some install methods can't install them\n", len(ticketless))
+	}
+
+	if printTree != nil && *printTree {
+		fmt.Println()
+		_ = process.WriteTree(localDB, c.output)
+	}
+
 	c.processIssues(localDB)
 
+	if installerIndex != nil && *installerIndex != "" {
+		c.writeInstallerIndex(localDB, *installerIndex, *installerUrl)
+	}
+
+	if exportFile != nil && *exportFile != "" {
+		c.exportLibrary(localDB, *exportFile)
+	}
+
+	if duplicateFiles != nil && *duplicateFiles {
+		c.processDuplicateFiles(localDbManager, localDB)
+	}
+
+	if sidecarsFolder != nil && *sidecarsFolder != "" {
+		written, err := process.WriteSidecars(localDB, titlesDB, *sidecarsFolder, process.SidecarFormatJSON, false)
+		if err != nil {
+			fmt.Printf("\nfailed to write the title metadata files :%v\n", err)
+		} else {
+			fmt.Printf("\n%d title metadata files written to [%v]\n", written, *sidecarsFolder)
+		}
+	}
+
 	if settingsObj.OrganizeOptions.DeleteOldUpdateFiles {
 		progressBar = progressbar.New(2000)
 		fmt.Printf("\nDeleting old updates\n")
@@ -129,7 +219,7 @@ func (c *Console) Start() {
 	if settingsObj.OrganizeOptions.RenameFiles || settingsObj.OrganizeOptions.CreateFolderPerGame {
 		progressBar = progressbar.New(2000)
 		fmt.Printf("\nStarting library organization\n")
-		process.OrganizeByFolders(folderToScan, localDB, titlesDB, c)
+		process.OrganizeByFolders(folderToScan, localDB, localDbManager.SplitSchemes(), titlesDB, c)
 		progressBar.Finish()
 	}
 
@@ -153,16 +243,80 @@ func (c *Console) processIssues(localDB *db.LocalSwitchFilesDB) {
 		return
 	}
 	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
+	t.SetOutputMirror(c.output)
 	t.SetStyle(table.StyleColoredBright)
 	t.AppendHeader(table.Row{"#", "Skipped file", "Reason"})
 	i := 0
 	for k, v := range localDB.Skipped {
-		t.AppendRow([]interface{}{i, path.Join(k.BaseFolder, k.FileName), v})
+		t.AppendRow([]interface{}{i, localDB.FilePath(k), v})
 		i++
 	}
 	t.AppendFooter(table.Row{"", "", "", "", "Total", len(localDB.Skipped)})
 	t.Render()
+	for _, group := range localDB.SkippedSummary() {
+		fmt.Fprintf(c.output, "%v: %d files\n", group.Reason, len(group.Files))
+	}
+}
+
+func (c *Console) processDuplicateFiles(localDbManager *db.LocalSwitchDBManager, localDB *db.LocalSwitchFilesDB) {
+	progressBar = progressbar.New(2000)
+	fmt.Printf("\nLooking for identical files\n")
+	groups, err := localDbManager.FindDuplicateFiles(localDB, c)
+	progressBar.Finish()
+	if err != nil {
+		fmt.Printf("\nfailed to look for identical files :%v\n", err)
+		return
+	}
+	if len(groups) == 0 {
+		fmt.Printf("\nNo identical files found\n")
+		return
+	}
+	fmt.Print("\nIdentical files:\n\n")
+	t := table.NewWriter()
+	t.SetOutputMirror(c.output)
+	t.SetStyle(table.StyleColoredBright)
+	t.AppendHeader(table.Row{"#", "File", "Size"})
+	for i, group := range groups {
+		for _, file := range group {
+			t.AppendRow([]interface{}{i, localDB.FilePath(file), file.Size})
+		}
+	}
+	t.AppendFooter(table.Row{"", "Total", len(groups)})
+	t.Render()
+}
+
+func (c *Console) exportLibrary(localDB *db.LocalSwitchFilesDB, path string) {
+	file, err := os.Create(path)
+	if err != nil {
+		fmt.Printf("\nfailed to create the export file :%v\n", err)
+		return
+	}
+	defer file.Close()
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		err = localDB.ExportCSV(file)
+	} else {
+		err = localDB.ExportJSON(file)
+	}
+	if err != nil {
+		fmt.Printf("\nfailed to export the library :%v\n", err)
+		return
+	}
+	fmt.Printf("\nLibrary exported to [%v]\n", path)
+}
+
+func (c *Console) writeInstallerIndex(localDB *db.LocalSwitchFilesDB, path string, baseUrl string) {
+	file, err := os.Create(path)
+	if err != nil {
+		fmt.Printf("\nfailed to create the installer index :%v\n", err)
+		return
+	}
+	defer file.Close()
+	err = process.ExportInstallerIndex(localDB, baseUrl, file, false)
+	if err != nil {
+		fmt.Printf("\nfailed to write the installer index :%v\n", err)
+		return
+	}
+	fmt.Printf("\nInstaller index written to [%v]\n", path)
 }
 
 func (c *Console) processMissingUpdates(localDB *db.LocalSwitchFilesDB, titlesDB *db.SwitchTitlesDB) {
@@ -174,7 +328,7 @@ func (c *Console) processMissingUpdates(localDB *db.LocalSwitchFilesDB, titlesDB
 		return
 	}
 	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
+	t.SetOutputMirror(c.output)
 	t.SetStyle(table.StyleColoredBright)
 	t.AppendHeader(table.Row{"#", "Title", "TitleId", "Local version", "Latest Version", "Update Date"})
 	i := 0
@@ -200,7 +354,7 @@ func (c *Console) processMissingDLC(localDB *db.LocalSwitchFilesDB, titlesDB *db
 		return
 	}
 	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
+	t.SetOutputMirror(c.output)
 	t.SetStyle(table.StyleColoredBright)
 	t.AppendHeader(table.Row{"#", "Title", "TitleId", "Missing DLCs (titleId - Name)"})
 	i := 0
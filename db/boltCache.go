@@ -0,0 +1,175 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"os"
+	"strings"
+	"time"
+)
+
+// BoltCache is a MetadataCache stored in a bolt DB file, each table ("<table>/<key>") is stored in its own bucket.
+// only a single process can open the file for writing (see OpenBoltCacheReadOnly)
+type BoltCache struct {
+	db *bolt.DB
+}
+
+func OpenBoltCache(path string) (*BoltCache, error) {
+	// It will be created if it doesn't exist.
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err == bolt.ErrTimeout {
+		return nil, fmt.Errorf("unable to open %v, the file is locked (is another instance running?)", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &BoltCache{db: db}, nil
+}
+
+// OpenBoltCacheReadOnly opens an existing bolt DB file for reading, several processes can open the file read-only
+// at the same time, but not while a process has it opened for writing (see OpenBoltCache)
+func OpenBoltCacheReadOnly(path string) (*BoltCache, error) {
+	//bolt would create a missing file (and keep it locked on failure)
+	if info, err := os.Stat(path); err != nil {
+		return nil, err
+	} else if info.Size() == 0 {
+		return nil, fmt.Errorf("unable to open %v read-only, the file is empty", path)
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{ReadOnly: true, Timeout: 1 * time.Second})
+	if err == bolt.ErrTimeout {
+		return nil, fmt.Errorf("unable to open %v read-only, the file is locked for writing (is another instance running?)", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &BoltCache{db: db}, nil
+}
+
+func (c *BoltCache) Close() error {
+	return c.db.Close()
+}
+
+func (c *BoltCache) Get(key string) ([]byte, bool) {
+	var result []byte
+	found := false
+	_ = c.db.View(func(tx *bolt.Tx) error {
+		val, ok := boltTxCache{tx: tx}.Get(key)
+		if ok {
+			//the value is only valid during the transaction
+			result = append([]byte{}, val...)
+			found = true
+		}
+		return nil
+	})
+	return result, found
+}
+
+func (c *BoltCache) Put(key string, val []byte) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return boltTxCache{tx: tx}.Put(key, val)
+	})
+}
+
+func (c *BoltCache) Delete(key string) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return boltTxCache{tx: tx}.Delete(key)
+	})
+}
+
+func (c *BoltCache) ForEach(prefix string, fn func(key string, val []byte) error) error {
+	return c.db.View(func(tx *bolt.Tx) error {
+		return boltTxCache{tx: tx}.ForEach(prefix, fn)
+	})
+}
+
+// Batch runs all the operations in a single transaction
+func (c *BoltCache) Batch(fn func(cache MetadataCache) error) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return fn(boltTxCache{tx: tx})
+	})
+}
+
+// boltTxCache runs the MetadataCache operations in a bolt transaction
+type boltTxCache struct {
+	tx *bolt.Tx
+}
+
+// keys without a table are stored in this bucket
+const boltDefaultBucket = "default"
+
+// splitBoltKey returns the bucket and the key within the bucket
+func splitBoltKey(key string) (string, string) {
+	if i := strings.Index(key, "/"); i > 0 {
+		return key[:i], key[i+1:]
+	}
+	return boltDefaultBucket, key
+}
+
+func joinBoltKey(bucket string, key string) string {
+	if bucket == boltDefaultBucket {
+		return key
+	}
+	return bucket + "/" + key
+}
+
+func (c boltTxCache) Get(key string) ([]byte, bool) {
+	bucket, bucketKey := splitBoltKey(key)
+	b := c.tx.Bucket([]byte(bucket))
+	if b == nil {
+		return nil, false
+	}
+	val := b.Get([]byte(bucketKey))
+	return val, val != nil
+}
+
+func (c boltTxCache) Put(key string, val []byte) error {
+	bucket, bucketKey := splitBoltKey(key)
+	b, err := c.tx.CreateBucketIfNotExists([]byte(bucket))
+	if err != nil {
+		return fmt.Errorf("create bucket: %s", err)
+	}
+	return b.Put([]byte(bucketKey), val)
+}
+
+func (c boltTxCache) Delete(key string) error {
+	bucket, bucketKey := splitBoltKey(key)
+	b := c.tx.Bucket([]byte(bucket))
+	if b == nil {
+		return nil
+	}
+	return b.Delete([]byte(bucketKey))
+}
+
+func (c boltTxCache) ForEach(prefix string, fn func(key string, val []byte) error) error {
+	forEachInBucket := func(bucket string, b *bolt.Bucket, keyPrefix string) error {
+		cursor := b.Cursor()
+		for k, v := cursor.Seek([]byte(keyPrefix)); k != nil && bytes.HasPrefix(k, []byte(keyPrefix)); k, v = cursor.Next() {
+			//nested buckets have no value
+			if v == nil {
+				continue
+			}
+			if err := fn(joinBoltKey(bucket, string(k)), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if strings.Contains(prefix, "/") {
+		bucket, keyPrefix := splitBoltKey(prefix)
+		b := c.tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		return forEachInBucket(bucket, b, keyPrefix)
+	}
+	return c.tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		if string(name) == boltDefaultBucket {
+			return forEachInBucket(boltDefaultBucket, b, prefix)
+		}
+		if !strings.HasPrefix(string(name), prefix) {
+			return nil
+		}
+		return forEachInBucket(string(name), b, "")
+	})
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LoadVersionsCatalog loads the latest version of each title (base title id for updates, DLC title id for DLC)
+// from a title id keyed JSON file. the supported formats are:
+//   - {"<titleId>": <version>}
+//   - {"<titleId>": {"<version>": "<release date>", ...}} (titledb versions.json)
+//   - {"<titleId>": {"version": <version>, ...}} (titledb titles.json)
+func LoadVersionsCatalog(path string) (map[string]int, error) {
+	entries, err := readCatalogFile(path)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]int{}
+	for titleId, value := range entries {
+		version, ok, err := parseCatalogVersion(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid versions catalog %v - title id [%v]: %v", path, titleId, err)
+		}
+		if ok {
+			result[titleId] = version
+		}
+	}
+	return result, nil
+}
+
+// LoadDLCCatalog loads the DLC title ids of each base title id from a title id keyed JSON file.
+// the supported formats are:
+//   - {"<base titleId>": ["<DLC titleId>", ...]}
+//   - {"<DLC titleId>": "<base titleId>"}
+//   - {"<titleId>": {...}} (titledb titles.json, DLC are matched to their base by id)
+func LoadDLCCatalog(path string) (map[string][]string, error) {
+	entries, err := readCatalogFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dlcs := map[string]map[string]struct{}{}
+	add := func(baseId string, dlcId string) error {
+		baseId, dlcId = strings.ToLower(baseId), strings.ToLower(dlcId)
+		if err := validateTitleId(dlcId); err != nil {
+			return err
+		}
+		if _, ok := dlcs[baseId]; !ok {
+			dlcs[baseId] = map[string]struct{}{}
+		}
+		dlcs[baseId][dlcId] = struct{}{}
+		return nil
+	}
+	for titleId, value := range entries {
+		var err error
+		var dlcIds []string
+		var baseId string
+		switch {
+		case json.Unmarshal(value, &dlcIds) == nil:
+			for _, dlcId := range dlcIds {
+				if err = add(titleId, dlcId); err != nil {
+					break
+				}
+			}
+		case json.Unmarshal(value, &baseId) == nil:
+			if err = validateTitleId(baseId); err == nil {
+				err = add(baseId, titleId)
+			}
+		case len(value) > 0 && value[0] == '{':
+			if strings.HasSuffix(titleId, "000") || strings.HasSuffix(titleId, "800") {
+				continue
+			}
+			if baseId, err = BaseTitleId(titleId); err == nil {
+				err = add(baseId, titleId)
+			}
+		default:
+			err = errors.New("expected a list of DLC title ids, a base title id or an object")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid DLC catalog %v - title id [%v]: %v", path, titleId, err)
+		}
+	}
+
+	result := map[string][]string{}
+	for baseId, ids := range dlcs {
+		for dlcId := range ids {
+			result[baseId] = append(result[baseId], dlcId)
+		}
+		sort.Strings(result[baseId])
+	}
+	return result, nil
+}
+
+// readCatalogFile reads a title id keyed JSON object, the title ids are validated and lower cased
+func readCatalogFile(path string) (map[string]json.RawMessage, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var raw map[string]json.RawMessage
+	if err := decodeToJsonObject(file, &raw); err != nil {
+		return nil, fmt.Errorf("invalid catalog %v - %v", path, err)
+	}
+	result := make(map[string]json.RawMessage, len(raw))
+	for titleId, value := range raw {
+		if err := validateTitleId(titleId); err != nil {
+			return nil, fmt.Errorf("invalid catalog %v - %v", path, err)
+		}
+		result[strings.ToLower(titleId)] = value
+	}
+	return result, nil
+}
+
+// parseCatalogVersion returns the (latest) version held by a catalog value, ok is false when the value
+// holds no version (e.g. a title without updates)
+func parseCatalogVersion(value json.RawMessage) (int, bool, error) {
+	var number json.Number
+	if err := json.Unmarshal(value, &number); err == nil {
+		version, err := parseVersion(string(number))
+		return version, err == nil, err
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(value, &object); err != nil {
+		return 0, false, errors.New("expected a version or an object")
+	}
+	if versionValue, ok := object["version"]; ok {
+		if string(versionValue) == "null" || string(versionValue) == `""` {
+			return 0, false, nil
+		}
+		if err := json.Unmarshal(versionValue, &number); err != nil {
+			return 0, false, errors.New("invalid version " + string(versionValue))
+		}
+		version, err := parseVersion(string(number))
+		return version, err == nil, err
+	}
+	latest := -1
+	for key := range object {
+		version, err := parseVersion(key)
+		if err != nil {
+			return 0, false, err
+		}
+		if version > latest {
+			latest = version
+		}
+	}
+	return latest, latest != -1, nil
+}
+
+func parseVersion(value string) (int, error) {
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 0 {
+		return 0, errors.New("invalid version [" + value + "]")
+	}
+	return version, nil
+}
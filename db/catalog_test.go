@@ -0,0 +1,123 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeCatalog(t *testing.T, folder string, content string) string {
+	file, err := ioutil.TempFile(folder, "catalog*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return file.Name()
+}
+
+func tempCatalogFolder(t *testing.T) string {
+	folder, err := ioutil.TempDir("", "slm-catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return folder
+}
+
+func TestLoadVersionsCatalog(t *testing.T) {
+	folder := tempCatalogFolder(t)
+	defer os.RemoveAll(folder)
+
+	tests := []struct {
+		name     string
+		content  string
+		expected map[string]int
+	}{
+		{"plain", `{"0100000000010000": 131072, "0100000000011001": "65536"}`,
+			map[string]int{"0100000000010000": 131072, "0100000000011001": 65536}},
+		{"versions.json", `{"0100000000010000": {"65536": "2017-11-08", "262144": "2018-02-21"}, "01007EF00011E000": {}}`,
+			map[string]int{"0100000000010000": 262144}},
+		{"titles.json", `{"01007EF00011E000": {"id": "01007EF00011E000", "version": 196608}, "0100000000010000": {"version": null}}`,
+			map[string]int{"01007ef00011e000": 196608}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			versions, err := LoadVersionsCatalog(writeCatalog(t, folder, test.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(versions, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, versions)
+			}
+		})
+	}
+}
+
+func TestLoadVersionsCatalogInvalid(t *testing.T) {
+	folder := tempCatalogFolder(t)
+	defer os.RemoveAll(folder)
+
+	for _, content := range []string{
+		`[1, 2]`,
+		`{"0100000000010000": 1`,
+		`{"not-a-title-id": 65536}`,
+		`{"0100000000010000": "latest"}`,
+		`{"0100000000010000": -1}`,
+		`{"0100000000010000": {"v1": "2017-11-08"}}`,
+	} {
+		if _, err := LoadVersionsCatalog(writeCatalog(t, folder, content)); err == nil {
+			t.Errorf("expected an error for %v", content)
+		}
+	}
+	if _, err := LoadVersionsCatalog(filepath.Join(os.TempDir(), "missing-catalog.json")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
+
+func TestLoadDLCCatalog(t *testing.T) {
+	folder := tempCatalogFolder(t)
+	defer os.RemoveAll(folder)
+
+	tests := []struct {
+		name     string
+		content  string
+		expected map[string][]string
+	}{
+		{"lists", `{"0100000000010000": ["0100000000011002", "0100000000011001"]}`,
+			map[string][]string{"0100000000010000": {"0100000000011001", "0100000000011002"}}},
+		{"dlc to base", `{"0100000000011001": "0100000000010000", "01007EF00011F001": "01007EF00011E000"}`,
+			map[string][]string{"0100000000010000": {"0100000000011001"}, "01007ef00011e000": {"01007ef00011f001"}}},
+		{"titles.json", `{"01007EF00011E000": {"name": "base"}, "01007EF00011E800": {}, "01007EF00011F001": {"name": "dlc"}}`,
+			map[string][]string{"01007ef00011e000": {"01007ef00011f001"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dlcs, err := LoadDLCCatalog(writeCatalog(t, folder, test.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(dlcs, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, dlcs)
+			}
+		})
+	}
+}
+
+func TestLoadDLCCatalogInvalid(t *testing.T) {
+	folder := tempCatalogFolder(t)
+	defer os.RemoveAll(folder)
+
+	for _, content := range []string{
+		`{"0100000000010000": ["bad-id"]}`,
+		`{"0100000000011001": "bad-id"}`,
+		`{"0100000000010000": 12}`,
+	} {
+		if _, err := LoadDLCCatalog(writeCatalog(t, folder, content)); err == nil {
+			t.Errorf("expected an error for %v", content)
+		}
+	}
+}
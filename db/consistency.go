@@ -0,0 +1,102 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+)
+
+type ConsistencyIssueType int
+
+const (
+	//updates are present but the base game is not
+	CONSISTENCY_UPDATE_WITHOUT_BASE ConsistencyIssueType = iota
+	//DLC are present but the base game is not
+	CONSISTENCY_DLC_WITHOUT_BASE
+	//the base file is already at (or above) the update version (e.g. a newer cartridge revision), the update is useless
+	CONSISTENCY_UPDATE_NOT_NEWER
+	//a DLC requires a higher application version than the local base and updates
+	CONSISTENCY_DLC_REQUIRES_UPDATE
+)
+
+func (t ConsistencyIssueType) String() string {
+	switch t {
+	case CONSISTENCY_UPDATE_WITHOUT_BASE:
+		return "update_without_base"
+	case CONSISTENCY_DLC_WITHOUT_BASE:
+		return "dlc_without_base"
+	case CONSISTENCY_UPDATE_NOT_NEWER:
+		return "update_not_newer"
+	case CONSISTENCY_DLC_REQUIRES_UPDATE:
+		return "dlc_requires_update"
+	}
+	return "unknown"
+}
+
+type ConsistencyIssue struct {
+	//the base title id of the title (the TitlesMap key)
+	TitleId     string               `json:"title_id"`
+	Type        ConsistencyIssueType `json:"type"`
+	Description string               `json:"description"`
+}
+
+// Consistency audits the base, updates and DLC held for each title - updates or DLC present without their base,
+// updates which don't apply to the local base (the base is already at the update version) and DLC requiring a higher
+// application version than the local base and updates. issues are ordered by title id and type
+func (l *LocalSwitchFilesDB) Consistency() []ConsistencyIssue {
+	var issues []ConsistencyIssue
+	for titleId, title := range l.TitlesMap {
+		baseStatus := "base is present"
+		if !title.BaseExist {
+			baseStatus = "base is missing"
+		}
+		latest := 0
+		for version := range title.Updates {
+			if version > latest {
+				latest = version
+			}
+		}
+
+		if !title.BaseExist && len(title.Updates) != 0 {
+			issues = append(issues, ConsistencyIssue{TitleId: titleId, Type: CONSISTENCY_UPDATE_WITHOUT_BASE,
+				Description: fmt.Sprintf("%v update(s) up to v%v are present, %v", len(title.Updates), latest, baseStatus)})
+		}
+		if !title.BaseExist && len(title.Dlc) != 0 {
+			issues = append(issues, ConsistencyIssue{TitleId: titleId, Type: CONSISTENCY_DLC_WITHOUT_BASE,
+				Description: fmt.Sprintf("%v DLC are present, %v", len(title.Dlc), baseStatus)})
+		}
+		if !title.BaseExist {
+			continue
+		}
+
+		installed := latest
+		if title.File.Metadata != nil && title.File.Metadata.Version > installed {
+			installed = title.File.Metadata.Version
+		}
+		if title.File.Metadata != nil && len(title.Updates) != 0 && title.File.Metadata.Version >= latest {
+			issues = append(issues, ConsistencyIssue{TitleId: titleId, Type: CONSISTENCY_UPDATE_NOT_NEWER,
+				Description: fmt.Sprintf("the base is v%v, the latest update v%v doesn't apply to it", title.File.Metadata.Version, latest)})
+		}
+
+		dlcIds := make([]string, 0, len(title.Dlc))
+		for dlcId := range title.Dlc {
+			dlcIds = append(dlcIds, dlcId)
+		}
+		sort.Strings(dlcIds)
+		for _, dlcId := range dlcIds {
+			dlc := title.Dlc[dlcId]
+			if dlc.Metadata == nil || dlc.Metadata.RequiredApplicationVersion <= installed {
+				continue
+			}
+			issues = append(issues, ConsistencyIssue{TitleId: titleId, Type: CONSISTENCY_DLC_REQUIRES_UPDATE,
+				Description: fmt.Sprintf("DLC %v requires v%v, the latest local version is v%v (%v)", dlcId,
+					dlc.Metadata.RequiredApplicationVersion, installed, baseStatus)})
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].TitleId != issues[j].TitleId {
+			return issues[i].TitleId < issues[j].TitleId
+		}
+		return issues[i].Type < issues[j].Type
+	})
+	return issues
+}
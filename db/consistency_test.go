@@ -0,0 +1,42 @@
+package db
+
+import "testing"
+
+func TestConsistency(t *testing.T) {
+	revision := testSwitchFile("base rev.xci", "0100000000030000", 131072)
+	dlc := testSwitchFile("dlc.nsp", "0100000000011001", 0)
+	dlc.Metadata.RequiredApplicationVersion = 196608
+	files := []SwitchFileInfo{
+		testSwitchFile("base.nsp", "0100000000010000", 0),
+		testSwitchFile("update.nsp", "0100000000010800", 131072),
+		dlc,
+		//no base
+		testSwitchFile("orphan update.nsp", "0100000000020800", 65536),
+		testSwitchFile("orphan dlc.nsp", "0100000000021001", 0),
+		revision,
+		testSwitchFile("old update.nsp", "0100000000030800", 65536),
+		//consistent
+		testSwitchFile("base4.nsp", "0100000000040000", 0),
+		testSwitchFile("update4.nsp", "0100000000040800", 65536),
+	}
+	localDB := Group(files, GroupOptions{})
+
+	issues := localDB.Consistency()
+	expected := []ConsistencyIssue{
+		{TitleId: "0100000000010000", Type: CONSISTENCY_DLC_REQUIRES_UPDATE},
+		{TitleId: "0100000000020000", Type: CONSISTENCY_UPDATE_WITHOUT_BASE},
+		{TitleId: "0100000000020000", Type: CONSISTENCY_DLC_WITHOUT_BASE},
+		{TitleId: "0100000000030000", Type: CONSISTENCY_UPDATE_NOT_NEWER},
+	}
+	if len(issues) != len(expected) {
+		t.Fatalf("expected %v issues, got %+v", len(expected), issues)
+	}
+	for i := range expected {
+		if issues[i].TitleId != expected[i].TitleId || issues[i].Type != expected[i].Type {
+			t.Errorf("expected %v %v, got %+v", expected[i].TitleId, expected[i].Type, issues[i])
+		}
+		if issues[i].Description == "" {
+			t.Errorf("expected a description for %+v", issues[i])
+		}
+	}
+}
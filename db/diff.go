@@ -0,0 +1,150 @@
+package db
+
+import (
+	"sort"
+)
+
+// LibraryDiff is what changed between two library snapshots (see DiffLibraries), titles are keyed by their
+// base title id
+type LibraryDiff struct {
+	//the titles only found in the new / old library, sorted
+	AddedTitles   []string `json:"added_titles"`
+	RemovedTitles []string `json:"removed_titles"`
+	//the titles whose base, updates or DLC changed (including the added and removed titles), sorted by title id
+	Titles []TitleDiff `json:"titles"`
+	//the files holding the same content (title id and version) at another path, sorted by title id and version
+	Renamed []RenamedFile `json:"renamed"`
+}
+
+// TitleDiff is what changed in a title between two library snapshots
+type TitleDiff struct {
+	TitleId     string `json:"title_id"`
+	Name        string `json:"name"`
+	BaseAdded   bool   `json:"base_added"`
+	BaseRemoved bool   `json:"base_removed"`
+	//sorted versions
+	AddedUpdates   []int `json:"added_updates"`
+	RemovedUpdates []int `json:"removed_updates"`
+	//sorted DLC title ids
+	AddedDlc   []string `json:"added_dlc"`
+	RemovedDlc []string `json:"removed_dlc"`
+}
+
+// RenamedFile is a content found at another path in the new library
+type RenamedFile struct {
+	TitleId string `json:"title_id"`
+	Version int    `json:"version"`
+	OldPath string `json:"old_path"`
+	NewPath string `json:"new_path"`
+}
+
+// IsEmpty returns true when the libraries hold the same contents at the same paths
+func (d LibraryDiff) IsEmpty() bool {
+	return len(d.Titles) == 0 && len(d.Renamed) == 0
+}
+
+// DiffLibraries returns what changed from the old library to the new one - the added and removed titles, updates and
+// DLC (DLC are compared by title id), and the contents whose file moved. the paths are reported as in each
+// library (see FilePath). either library may be nil (empty)
+func DiffLibraries(oldLibrary *LocalSwitchFilesDB, newLibrary *LocalSwitchFilesDB) LibraryDiff {
+	if oldLibrary == nil {
+		oldLibrary = &LocalSwitchFilesDB{}
+	}
+	if newLibrary == nil {
+		newLibrary = &LocalSwitchFilesDB{}
+	}
+	diff := LibraryDiff{}
+	keys := map[string]struct{}{}
+	for key := range oldLibrary.TitlesMap {
+		keys[key] = struct{}{}
+	}
+	for key := range newLibrary.TitlesMap {
+		keys[key] = struct{}{}
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+
+	empty := &SwitchGameFiles{}
+	for _, key := range sortedKeys {
+		oldTitle, inOld := oldLibrary.TitlesMap[key]
+		newTitle, inNew := newLibrary.TitlesMap[key]
+		titleDiff := TitleDiff{TitleId: key}
+		switch {
+		case !inOld:
+			diff.AddedTitles = append(diff.AddedTitles, key)
+			oldTitle = empty
+			titleDiff.Name = newTitle.Name()
+		case !inNew:
+			diff.RemovedTitles = append(diff.RemovedTitles, key)
+			newTitle = empty
+			titleDiff.Name = oldTitle.Name()
+		default:
+			titleDiff.Name = newTitle.Name()
+		}
+
+		titleDiff.BaseAdded = newTitle.BaseExist && !oldTitle.BaseExist
+		titleDiff.BaseRemoved = oldTitle.BaseExist && !newTitle.BaseExist
+		if oldTitle.BaseExist && newTitle.BaseExist {
+			diff.Renamed = appendRenamed(diff.Renamed, oldLibrary, oldTitle.File, newLibrary, newTitle.File)
+		}
+
+		for version, update := range newTitle.Updates {
+			if oldUpdate, ok := oldTitle.Updates[version]; ok {
+				diff.Renamed = appendRenamed(diff.Renamed, oldLibrary, oldUpdate, newLibrary, update)
+			} else {
+				titleDiff.AddedUpdates = append(titleDiff.AddedUpdates, version)
+			}
+		}
+		for version := range oldTitle.Updates {
+			if _, ok := newTitle.Updates[version]; !ok {
+				titleDiff.RemovedUpdates = append(titleDiff.RemovedUpdates, version)
+			}
+		}
+		for id, dlc := range newTitle.Dlc {
+			if oldDlc, ok := oldTitle.Dlc[id]; ok {
+				diff.Renamed = appendRenamed(diff.Renamed, oldLibrary, oldDlc, newLibrary, dlc)
+			} else {
+				titleDiff.AddedDlc = append(titleDiff.AddedDlc, id)
+			}
+		}
+		for id := range oldTitle.Dlc {
+			if _, ok := newTitle.Dlc[id]; !ok {
+				titleDiff.RemovedDlc = append(titleDiff.RemovedDlc, id)
+			}
+		}
+		sort.Ints(titleDiff.AddedUpdates)
+		sort.Ints(titleDiff.RemovedUpdates)
+		sort.Strings(titleDiff.AddedDlc)
+		sort.Strings(titleDiff.RemovedDlc)
+
+		if titleDiff.BaseAdded || titleDiff.BaseRemoved || len(titleDiff.AddedUpdates) != 0 || len(titleDiff.RemovedUpdates) != 0 ||
+			len(titleDiff.AddedDlc) != 0 || len(titleDiff.RemovedDlc) != 0 {
+			diff.Titles = append(diff.Titles, titleDiff)
+		}
+	}
+
+	sort.Slice(diff.Renamed, func(i, j int) bool {
+		if diff.Renamed[i].TitleId != diff.Renamed[j].TitleId {
+			return diff.Renamed[i].TitleId < diff.Renamed[j].TitleId
+		}
+		if diff.Renamed[i].Version != diff.Renamed[j].Version {
+			return diff.Renamed[i].Version < diff.Renamed[j].Version
+		}
+		return diff.Renamed[i].NewPath < diff.Renamed[j].NewPath
+	})
+	return diff
+}
+
+// appendRenamed records the content when its file moved between the libraries
+func appendRenamed(renamed []RenamedFile, oldLibrary *LocalSwitchFilesDB, oldFile SwitchFileInfo, newLibrary *LocalSwitchFilesDB,
+	newFile SwitchFileInfo) []RenamedFile {
+	oldPath, newPath := oldLibrary.FilePath(oldFile.ExtendedInfo), newLibrary.FilePath(newFile.ExtendedInfo)
+	if oldPath == newPath || newFile.Metadata == nil {
+		return renamed
+	}
+	return append(renamed, RenamedFile{TitleId: newFile.Metadata.TitleId, Version: newFile.Metadata.Version,
+		OldPath: oldPath, NewPath: newPath})
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffLibraries(t *testing.T) {
+	oldLibrary := Group([]SwitchFileInfo{
+		testSwitchFile("mario.nsp", "0100000000010000", 0),
+		testSwitchFile("mario update.nsp", "0100000000010800", 65536),
+		testSwitchFile("mario dlc1.nsp", "0100000000011001", 0),
+		testSwitchFile("zelda.nsp", "0100000000020000", 0),
+	}, GroupOptions{KeepOldUpdates: true})
+	newLibrary := Group([]SwitchFileInfo{
+		testSwitchFile("Super Mario Odyssey.nsp", "0100000000010000", 0),
+		testSwitchFile("mario update.nsp", "0100000000010800", 65536),
+		testSwitchFile("mario update2.nsp", "0100000000010800", 131072),
+		testSwitchFile("mario dlc2.nsp", "0100000000011002", 0),
+		testSwitchFile("kirby update.nsp", "0100000000030800", 65536),
+	}, GroupOptions{KeepOldUpdates: true})
+
+	diff := DiffLibraries(oldLibrary, newLibrary)
+	if !reflect.DeepEqual(diff.AddedTitles, []string{"0100000000030000"}) ||
+		!reflect.DeepEqual(diff.RemovedTitles, []string{"0100000000020000"}) {
+		t.Errorf("unexpected added %v / removed %v titles", diff.AddedTitles, diff.RemovedTitles)
+	}
+	if len(diff.Titles) != 3 {
+		t.Fatalf("expected 3 changed titles, got %+v", diff.Titles)
+	}
+	mario := diff.Titles[0]
+	if mario.TitleId != "0100000000010000" || mario.BaseAdded || mario.BaseRemoved ||
+		!reflect.DeepEqual(mario.AddedUpdates, []int{131072}) || len(mario.RemovedUpdates) != 0 ||
+		!reflect.DeepEqual(mario.AddedDlc, []string{"0100000000011002"}) ||
+		!reflect.DeepEqual(mario.RemovedDlc, []string{"0100000000011001"}) {
+		t.Errorf("unexpected mario diff %+v", mario)
+	}
+	zelda, kirby := diff.Titles[1], diff.Titles[2]
+	if zelda.TitleId != "0100000000020000" || !zelda.BaseRemoved {
+		t.Errorf("unexpected zelda diff %+v", zelda)
+	}
+	if kirby.TitleId != "0100000000030000" || kirby.BaseAdded || !reflect.DeepEqual(kirby.AddedUpdates, []int{65536}) {
+		t.Errorf("unexpected kirby diff %+v", kirby)
+	}
+	if len(diff.Renamed) != 1 || diff.Renamed[0].TitleId != "0100000000010000" ||
+		diff.Renamed[0].OldPath != "/games/mario.nsp" || diff.Renamed[0].NewPath != "/games/Super Mario Odyssey.nsp" {
+		t.Errorf("unexpected renamed files %+v", diff.Renamed)
+	}
+
+	if diff := DiffLibraries(newLibrary, newLibrary); !diff.IsEmpty() {
+		t.Errorf("expected no changes, got %+v", diff)
+	}
+	if diff := DiffLibraries(nil, newLibrary); len(diff.AddedTitles) != 2 || len(diff.Titles) != 2 {
+		t.Errorf("expected all the titles to be added, got %+v", diff)
+	}
+}
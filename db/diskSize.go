@@ -0,0 +1,71 @@
+package db
+
+import (
+	"sort"
+)
+
+// TitleDiskSize is the space taken on the disk by the files of a title
+type TitleDiskSize struct {
+	TitleId     string `json:"title_id"`
+	Name        string `json:"name"`
+	BaseSize    int64  `json:"base_size"`
+	UpdatesSize int64  `json:"updates_size"`
+	DlcSize     int64  `json:"dlc_size"`
+	TotalSize   int64  `json:"total_size"`
+}
+
+// SizeOf returns the space taken on the disk by the base, all the updates and all the DLC files of the title
+// (duplicates excluded). all the parts of split files are counted, and a file holding several contents is counted once
+func SizeOf(game *SwitchGameFiles) int64 {
+	return diskSize(game).TotalSize
+}
+
+// LibrarySizeBreakdown returns the space taken on the disk by each title (see SizeOf), sorted by the total size
+// (descending) then title id
+func (l *LocalSwitchFilesDB) LibrarySizeBreakdown() []TitleDiskSize {
+	result := make([]TitleDiskSize, 0, len(l.TitlesMap))
+	for _, switchFile := range l.TitlesMap {
+		result = append(result, diskSize(switchFile))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TotalSize != result[j].TotalSize {
+			return result[i].TotalSize > result[j].TotalSize
+		}
+		return result[i].TitleId < result[j].TitleId
+	})
+	return result
+}
+
+func diskSize(game *SwitchGameFiles) TitleDiskSize {
+	size := TitleDiskSize{TitleId: game.TitleId(), Name: game.Name()}
+	//a multi-content file is listed in several slots, it is counted in the first one (base, updates then DLC)
+	counted := map[ExtendedFileInfo]struct{}{}
+	fileSize := func(file SwitchFileInfo) int64 {
+		if _, ok := counted[file.ExtendedInfo]; ok {
+			return 0
+		}
+		counted[file.ExtendedInfo] = struct{}{}
+		return groupedFileSize(file)
+	}
+	if game.BaseExist {
+		size.BaseSize = fileSize(game.File)
+	}
+	versions := make([]int, 0, len(game.Updates))
+	for version := range game.Updates {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	for _, version := range versions {
+		size.UpdatesSize += fileSize(game.Updates[version])
+	}
+	dlcIds := make([]string, 0, len(game.Dlc))
+	for dlcId := range game.Dlc {
+		dlcIds = append(dlcIds, dlcId)
+	}
+	sort.Strings(dlcIds)
+	for _, dlcId := range dlcIds {
+		size.DlcSize += fileSize(game.Dlc[dlcId])
+	}
+	size.TotalSize = size.BaseSize + size.UpdatesSize + size.DlcSize
+	return size
+}
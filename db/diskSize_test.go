@@ -0,0 +1,45 @@
+package db
+
+import (
+	"github.com/giwty/switch-library-manager/fileio"
+	"testing"
+)
+
+func TestLibrarySizeBreakdown(t *testing.T) {
+	sized := func(file SwitchFileInfo, size int64) SwitchFileInfo {
+		file.ExtendedInfo.Size = size
+		return file
+	}
+	//a split base, only the first part is listed with its own size
+	base := sized(testSwitchFile("base.nsp.00", "0100000000010000", 0), 4)
+	base.Split = &fileio.SplitFileInfo{NumParts: 3, TotalSize: 10}
+	//a XCI holding a base and its update
+	xciBase := sized(testSwitchFile("game.xci", "0100000000020000", 0), 50)
+	xciUpdate := sized(testSwitchFile("game.xci", "0100000000020800", 65536), 50)
+
+	localDB := Group([]SwitchFileInfo{
+		base,
+		sized(testSwitchFile("update1.nsp", "0100000000010800", 65536), 5),
+		sized(testSwitchFile("update2.nsp", "0100000000010800", 131072), 6),
+		sized(testSwitchFile("dlc.nsp", "0100000000011001", 0), 7),
+		sized(testSwitchFile("copy of dlc.nsp", "0100000000011001", 0), 7),
+		xciBase,
+		xciUpdate,
+	}, GroupOptions{})
+
+	breakdown := localDB.LibrarySizeBreakdown()
+	if len(breakdown) != 2 {
+		t.Fatalf("expected 2 titles, got %+v", breakdown)
+	}
+	if breakdown[0].TitleId != "0100000000020000" || breakdown[0].TotalSize != 50 || breakdown[0].BaseSize != 50 ||
+		breakdown[0].UpdatesSize != 0 {
+		t.Errorf("expected the multi-content file to be counted once, got %+v", breakdown[0])
+	}
+	expected := TitleDiskSize{TitleId: "0100000000010000", Name: breakdown[1].Name, BaseSize: 10, UpdatesSize: 11, DlcSize: 7, TotalSize: 28}
+	if breakdown[1] != expected {
+		t.Errorf("expected %+v, got %+v", expected, breakdown[1])
+	}
+	if size := SizeOf(localDB.TitlesMap["0100000000010000"]); size != 28 {
+		t.Errorf("expected SizeOf 28, got %v", size)
+	}
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"go.uber.org/zap"
+	"path/filepath"
+	"sort"
+)
+
+// FindDuplicateFiles returns the groups of library files (grouped and skipped) having the exact same content,
+// regardless of their folder and file name. only files sharing their size with another file are hashed, the hashes
+// are cached (by path, size and modification time) so following calls only hash new or modified files.
+// split files are not compared. the groups and the files in each group are sorted by path
+func (ldb *LocalSwitchDBManager) FindDuplicateFiles(localDB *LocalSwitchFilesDB, progress ProgressUpdater) ([][]ExtendedFileInfo, error) {
+	bySize := map[int64][]ExtendedFileInfo{}
+	for _, file := range libraryFiles(localDB) {
+		if file.IsDir || ldb.splitSchemes.IsSplitPart(filepath.Join(file.BaseFolder, file.FileName)) {
+			continue
+		}
+		bySize[file.Size] = append(bySize[file.Size], file)
+	}
+	var candidates []ExtendedFileInfo
+	for _, files := range bySize {
+		if len(files) > 1 {
+			candidates = append(candidates, files...)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return fileKey(candidates[i]) < fileKey(candidates[j])
+	})
+
+	byHash := map[string][]ExtendedFileInfo{}
+	computed := map[string]interface{}{}
+	for i, file := range candidates {
+		if progress != nil {
+			progress.UpdateProgress(i+1, len(candidates), "hash:"+file.ContentName())
+		}
+		cacheKey := fileCacheKey(file, filepath.Join(file.BaseFolder, file.FileName))
+		hash := ""
+		if err := ldb.db.GetEntry(DB_TABLE_CONTENT_HASHES, cacheKey, &hash); err != nil {
+			zap.S().Warnf("%v", err)
+		}
+		if hash == "" {
+			var err error
+			hash, err = ldb.hashFile(file, file.Size, true)
+			if err != nil {
+				zap.S().Warnf("[file:%v] failed to hash the file [reason: %v]", file.ContentName(), err)
+				continue
+			}
+			computed[cacheKey] = hash
+		}
+		byHash[hash] = append(byHash[hash], file)
+	}
+	if err := ldb.db.AddEntries(DB_TABLE_CONTENT_HASHES, computed); err != nil {
+		return nil, err
+	}
+
+	var result [][]ExtendedFileInfo
+	for _, files := range byHash {
+		if len(files) > 1 {
+			result = append(result, files)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return fileKey(result[i][0]) < fileKey(result[j][0])
+	})
+	return result, nil
+}
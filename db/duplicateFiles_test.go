@@ -0,0 +1,61 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindDuplicateFiles(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+	if err := os.Mkdir(filepath.Join(gamesFolder, "backup"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"Super Mario Odyssey [0100000000010000][v0].nsp":             "base data",
+		"backup/Mario copy [0100000000010000][v0].nsp":               "base data",
+		"Super Mario Odyssey [0100000000010800][v65536].nsp":         "upd1 data",
+		"backup/Super Mario Odyssey [0100000000010800][v131072].nsp": "upd2 data",
+		"Zelda [0100000000020000][v0].nsp":                           "other size",
+	}
+	for fileName, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(gamesFolder, fileName), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	localDB, err := manager.CreateLocalSwitchFilesDB([]string{gamesFolder}, nil, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		groups, err := manager.FindDuplicateFiles(localDB, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(groups) != 1 || len(groups[0]) != 2 {
+			t.Fatalf("expected a single group of 2 identical files, got %+v", groups)
+		}
+		if groups[0][0].FileName != "Super Mario Odyssey [0100000000010000][v0].nsp" ||
+			groups[0][1].FileName != "Mario copy [0100000000010000][v0].nsp" {
+			t.Errorf("unexpected identical files %v / %v", groups[0][0].FileName, groups[0][1].FileName)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"github.com/giwty/switch-library-manager/settings"
+	"strings"
+)
+
+// titleExclusions drops the contents whose title id starts with one of the configured prefixes
+type titleExclusions struct {
+	//lower case
+	prefixes []string
+	report   bool
+}
+
+func newTitleExclusions(options settings.ScanExclusions) titleExclusions {
+	exclusions := titleExclusions{report: options.ReportExcluded}
+	for _, prefix := range options.TitleIdPrefixes {
+		prefix = strings.ToLower(strings.TrimSpace(prefix))
+		if prefix != "" {
+			exclusions.prefixes = append(exclusions.prefixes, prefix)
+		}
+	}
+	return exclusions
+}
+
+func (e titleExclusions) excluded(titleId string) bool {
+	titleId = strings.ToLower(titleId)
+	for _, prefix := range e.prefixes {
+		if strings.HasPrefix(titleId, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// excludeTitles drops the excluded contents of the file. a file whose contents are all excluded is reported as
+// skipped (REASON_EXCLUDED) when configured, it is otherwise left out silently
+func (ldb *LocalSwitchDBManager) excludeTitles(file ExtendedFileInfo, switchFiles []SwitchFileInfo,
+	skip *SkippedFile) ([]SwitchFileInfo, *SkippedFile) {
+	if len(ldb.exclusions.prefixes) == 0 || len(switchFiles) == 0 {
+		return switchFiles, skip
+	}
+	var kept []SwitchFileInfo
+	var excludedIds []string
+	for _, switchFile := range switchFiles {
+		if ldb.exclusions.excluded(switchFile.Metadata.TitleId) {
+			excludedIds = append(excludedIds, switchFile.Metadata.TitleId)
+			continue
+		}
+		kept = append(kept, switchFile)
+	}
+	if len(kept) == 0 && skip == nil && ldb.exclusions.report {
+		skip = &SkippedFile{ReasonCode: REASON_EXCLUDED, ReasonText: "title id excluded (" + strings.Join(excludedIds, ", ") + ")"}
+	}
+	return kept, skip
+}
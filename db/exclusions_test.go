@@ -0,0 +1,76 @@
+package db
+
+import (
+	"github.com/giwty/switch-library-manager/settings"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanExclusions(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+
+	for _, fileName := range []string{
+		"Super Mario Odyssey [0100000000010000][v0].nsp",
+		"Homebrew [0100FFFF000A0000][v0].nsp",
+		"Demos/Zelda Demo [0100000000020000][v0].nsp",
+	} {
+		path := filepath.Join(gamesFolder, fileName)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//the excluded folder is not listed
+	filter, err := newScanFilter(settings.ScanFilter{}, settings.ScanExclusions{PathGlobs: []string{filepath.Join(gamesFolder, "DEMOS")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []ExtendedFileInfo
+	if _, err := scanFolder(gamesFolder, true, false, false, filter, &files, nil, scanLimits{maxDepth: 64, maxFiles: 100, folderDepth: -1}); err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected the demos folder to be excluded, got %v", files)
+	}
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	manager.exclusions = newTitleExclusions(settings.ScanExclusions{TitleIdPrefixes: []string{" 0100ffff "}})
+	switchFiles, skipped := manager.GatherFiles(files, nil)
+	if len(switchFiles) != 1 || switchFiles[0].Metadata.TitleId != "0100000000010000" || len(skipped) != 0 {
+		t.Fatalf("expected the homebrew to be dropped silently, got %v (skipped %v)", switchFiles, skipped)
+	}
+
+	manager.exclusions = newTitleExclusions(settings.ScanExclusions{TitleIdPrefixes: []string{"0100FFFF"}, ReportExcluded: true})
+	switchFiles, skipped = manager.GatherFiles(files, nil)
+	if len(switchFiles) != 1 || len(skipped) != 1 {
+		t.Fatalf("expected the homebrew to be reported as skipped, got %v (skipped %v)", switchFiles, skipped)
+	}
+	for file, skip := range skipped {
+		if file.FileName != "Homebrew [0100FFFF000A0000][v0].nsp" || skip.ReasonCode != REASON_EXCLUDED {
+			t.Errorf("unexpected skipped file %v - %+v", file.FileName, skip)
+		}
+	}
+
+	if _, err := newScanFilter(settings.ScanFilter{}, settings.ScanExclusions{PathGlobs: []string{"[a-"}}); err == nil {
+		t.Errorf("expected an error for an invalid pattern")
+	}
+}
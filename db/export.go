@@ -0,0 +1,100 @@
+package db
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// LibraryEntry is a flat, serializable view of a library file (one entry per base, update and DLC)
+type LibraryEntry struct {
+	TitleId string `json:"title_id"`
+	Name    string `json:"name"`
+	//Base, Update or DLC
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+	Path    string `json:"path"`
+	//total size of all the parts for split files
+	Size  int64 `json:"size"`
+	Split bool  `json:"split"`
+}
+
+var libraryEntryTypeOrder = map[string]int{"Base": 0, "Update": 1, "DLC": 2}
+
+// Entries flattens the library into an entry per base, update and DLC file, sorted by title id, type and version
+func (l *LocalSwitchFilesDB) Entries() []LibraryEntry {
+	var result []LibraryEntry
+	for _, switchFile := range l.TitlesMap {
+		name := ""
+		if switchFile.BaseExist {
+			name = switchFile.Name()
+		}
+		add := func(file SwitchFileInfo, titleType string) {
+			if file.Metadata == nil {
+				return
+			}
+			entry := LibraryEntry{TitleId: file.Metadata.TitleId, Name: name, Type: titleType, Version: file.Metadata.Version,
+				Path: l.FilePath(file.ExtendedInfo), Size: file.ExtendedInfo.Size, Split: file.Split != nil}
+			if file.Split != nil && file.Split.TotalSize != 0 {
+				entry.Size = file.Split.TotalSize
+			}
+			if entry.Name == "" {
+				entry.Name = file.Name()
+			}
+			result = append(result, entry)
+		}
+		if switchFile.BaseExist {
+			add(switchFile.File, "Base")
+		}
+		for _, update := range switchFile.Updates {
+			add(update, "Update")
+		}
+		for _, dlc := range switchFile.Dlc {
+			add(dlc, "DLC")
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TitleId != result[j].TitleId {
+			return result[i].TitleId < result[j].TitleId
+		}
+		if result[i].Type != result[j].Type {
+			return libraryEntryTypeOrder[result[i].Type] < libraryEntryTypeOrder[result[j].Type]
+		}
+		if result[i].Version != result[j].Version {
+			return result[i].Version < result[j].Version
+		}
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
+
+// ExportJSON writes the library entries (see Entries) as a JSON array
+func (l *LocalSwitchFilesDB) ExportJSON(w io.Writer) error {
+	entries := l.Entries()
+	if entries == nil {
+		entries = []LibraryEntry{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
+
+// ExportCSV writes the library entries (see Entries) as CSV, with a header row
+func (l *LocalSwitchFilesDB) ExportCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{"title_id", "name", "type", "version", "path", "size", "split"})
+	if err != nil {
+		return err
+	}
+	for _, entry := range l.Entries() {
+		err = writer.Write([]string{entry.TitleId, entry.Name, entry.Type, strconv.Itoa(entry.Version), entry.Path,
+			strconv.FormatInt(entry.Size, 10), strconv.FormatBool(entry.Split)})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	localDB := Group([]SwitchFileInfo{
+		testSwitchFile("Game [0100000000010000][v0].nsp", "0100000000010000", 0),
+		testSwitchFile("Game [0100000000010800][v65536].nsp", "0100000000010800", 65536),
+		testSwitchFile("Game, DLC [0100000000011001][v0].nsp", "0100000000011001", 0),
+	}, GroupOptions{})
+
+	var csvOutput bytes.Buffer
+	if err := localDB.ExportCSV(&csvOutput); err != nil {
+		t.Fatal(err)
+	}
+	expected := "title_id,name,type,version,path,size,split\n" +
+		"0100000000010000,Game,Base,0,/games/Game [0100000000010000][v0].nsp,1,false\n" +
+		"0100000000010800,Game,Update,65536,/games/Game [0100000000010800][v65536].nsp,1,false\n" +
+		"0100000000011001,Game,DLC,0,\"/games/Game, DLC [0100000000011001][v0].nsp\",1,false\n"
+	if csvOutput.String() != expected {
+		t.Errorf("unexpected CSV output:\n%v", csvOutput.String())
+	}
+
+	var jsonOutput bytes.Buffer
+	if err := localDB.ExportJSON(&jsonOutput); err != nil {
+		t.Fatal(err)
+	}
+	var entries []LibraryEntry
+	if err := json.NewDecoder(strings.NewReader(jsonOutput.String())).Decode(&entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[1].Type != "Update" || entries[1].Version != 65536 {
+		t.Errorf("unexpected JSON entries %+v", entries)
+	}
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+const (
+	DEFAULT_VERSION_PATTERN  = `\[[vV]?(?P<version>[0-9]{1,10})]`
+	DEFAULT_TITLE_ID_PATTERN = `\[(?P<titleId>[A-Z,a-z0-9]{16})]`
+)
+
+// ParserConfig holds the patterns used to parse the title id and version from file names, an empty pattern
+// falls back to the default (see DEFAULT_VERSION_PATTERN, DEFAULT_TITLE_ID_PATTERN)
+type ParserConfig struct {
+	//must contain a (?P<version>...) group
+	VersionPattern string
+	//must contain a (?P<titleId>...) group
+	TitleIdPattern string
+}
+
+// fileNameParser extracts the title id and version from a file name (used when the file metadata can't be read)
+type fileNameParser struct {
+	versionRegex *regexp.Regexp
+	titleIdRegex *regexp.Regexp
+}
+
+var defaultFileNameParser = &fileNameParser{
+	versionRegex: regexp.MustCompile(DEFAULT_VERSION_PATTERN),
+	titleIdRegex: regexp.MustCompile(DEFAULT_TITLE_ID_PATTERN),
+}
+
+// newFileNameParser creates a parser from custom patterns, an empty pattern falls back to the default.
+// the patterns must contain a 'version' / 'titleId' named capture group
+func newFileNameParser(versionPattern string, titleIdPattern string) (*fileNameParser, error) {
+	parser := &fileNameParser{versionRegex: defaultFileNameParser.versionRegex, titleIdRegex: defaultFileNameParser.titleIdRegex}
+	var err error
+	if versionPattern != "" {
+		if parser.versionRegex, err = compilePattern(versionPattern, "version"); err != nil {
+			return nil, err
+		}
+	}
+	if titleIdPattern != "" {
+		if parser.titleIdRegex, err = compilePattern(titleIdPattern, "titleId"); err != nil {
+			return nil, err
+		}
+	}
+	return parser, nil
+}
+
+func compilePattern(pattern string, group string) (*regexp.Regexp, error) {
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file name pattern [%v] - %v", pattern, err)
+	}
+	if subexpIndex(regex, group) == -1 {
+		return nil, fmt.Errorf("invalid file name pattern [%v] - missing the (?P<%v>...) capture group", pattern, group)
+	}
+	return regex, nil
+}
+
+func subexpIndex(regex *regexp.Regexp, name string) int {
+	for i, subexpName := range regex.SubexpNames() {
+		if subexpName == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *fileNameParser) parseVersion(fileName string) (*int, error) {
+	res := p.versionRegex.FindStringSubmatch(fileName)
+	if res == nil {
+		return nil, errors.New("failed to parse name - no version id found")
+	}
+	ver, err := strconv.Atoi(res[subexpIndex(p.versionRegex, "version")])
+	if err != nil {
+		return nil, errors.New("failed to parse name - no version id found")
+	}
+	return &ver, nil
+}
+
+func (p *fileNameParser) parseTitleId(fileName string) (*string, error) {
+	res := p.titleIdRegex.FindStringSubmatch(fileName)
+	if res == nil {
+		return nil, errors.New("failed to parse name - no title id found")
+	}
+	titleId, _, err := ValidateTitleId(res[subexpIndex(p.titleIdRegex, "titleId")])
+	if err != nil {
+		return nil, errors.New("failed to parse name - " + err.Error())
+	}
+	return &titleId, nil
+}
@@ -0,0 +1,35 @@
+package db
+
+import "testing"
+
+func TestFileNameParserCustomPatterns(t *testing.T) {
+	parser, err := newFileNameParser(`\([vV]?(?P<version>[0-9]{1,10})\)`, `\((?P<titleId>[A-Fa-f0-9]{16})\)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	titleId, err := parser.parseTitleId("Game (0100ABCDEF123000)(v131072).nsp")
+	if err != nil || *titleId != "0100abcdef123000" {
+		t.Errorf("unexpected title id %v (%v)", titleId, err)
+	}
+	version, err := parser.parseVersion("Game (0100ABCDEF123000)(v131072).nsp")
+	if err != nil || *version != 131072 {
+		t.Errorf("unexpected version %v (%v)", version, err)
+	}
+
+	//the default patterns expect brackets
+	if _, err := defaultFileNameParser.parseTitleId("Game (0100ABCDEF123000)(v131072).nsp"); err == nil {
+		t.Errorf("expected the default pattern not to match")
+	}
+}
+
+func TestFileNameParserInvalidPatterns(t *testing.T) {
+	for _, patterns := range [][2]string{
+		{`\[v(?P<ver>[0-9]+)]`, ""},
+		{"", `\[(?P<id>[0-9a-f]{16})]`},
+		{`(`, ""},
+	} {
+		if _, err := newFileNameParser(patterns[0], patterns[1]); err == nil {
+			t.Errorf("expected %v to be rejected", patterns)
+		}
+	}
+}
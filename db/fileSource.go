@@ -0,0 +1,55 @@
+package db
+
+import (
+	"github.com/giwty/switch-library-manager/switchfs"
+	"io"
+	"os"
+)
+
+// FileSource opens the library files for reading, e.g. to read the files stored on a remote server (SFTP) without
+// mounting it. the metadata is read with range reads, the files are never read as a whole
+type FileSource interface {
+	//returns a reader of the file content and its size
+	Open(filePath string) (switchfs.ReadAtCloser, int64, error)
+}
+
+// SetFileSource makes the scans read the NSP/NSZ and XCI/XCZ files content from the source, nil restores the local
+// files. the files are still listed from the local scan folders - files listed otherwise (e.g. from the remote server)
+// can be read with GatherFiles. split files, archive entries and .cnmt.xml sidecars are always read locally
+func (ldb *LocalSwitchDBManager) SetFileSource(source FileSource) {
+	ldb.fileSource = source
+}
+
+// readMetadata reads the metadata of the file from the file source (the local file by default)
+func (ldb *LocalSwitchDBManager) readMetadata(filePath string,
+	read func(ra io.ReaderAt, size int64) (map[string]*switchfs.ContentMetaAttributes, error)) (map[string]*switchfs.ContentMetaAttributes, error) {
+	source := ldb.fileSource
+	if source == nil {
+		source = localFileSource{limiter: ldb.ioLimiter, splitSchemes: ldb.splitSchemes}
+	}
+	reader, size, err := source.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return read(reader, size)
+}
+
+// localFileSource reads the local files (the whole split file for a split part named after the schemes),
+// the reads are counted against the limiter (see settings.ScanOptions.IOConcurrency)
+type localFileSource struct {
+	limiter      *switchfs.IOLimiter
+	splitSchemes switchfs.SplitSchemes
+}
+
+func (s localFileSource) Open(filePath string) (switchfs.ReadAtCloser, int64, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	file, err := s.splitSchemes.OpenFile(filePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.limiter.Limit(file), info.Size(), nil
+}
@@ -0,0 +1,247 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/giwty/switch-library-manager/settings"
+	"github.com/giwty/switch-library-manager/switchfs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// well formed (but wrong) keys, the files are read with them
+var testProdKeys = "header_key = " + strings.Repeat("00", 32) + "\n"
+
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (memoryFile) Close() error {
+	return nil
+}
+
+type memoryFileSource struct {
+	sync.Mutex
+	files  map[string][]byte
+	opened []string
+}
+
+func (s *memoryFileSource) Open(filePath string) (switchfs.ReadAtCloser, int64, error) {
+	s.Lock()
+	defer s.Unlock()
+	s.opened = append(s.opened, filePath)
+	data, ok := s.files[filePath]
+	if !ok {
+		return nil, 0, errors.New("no such remote file")
+	}
+	return memoryFile{bytes.NewReader(data)}, int64(len(data)), nil
+}
+
+func TestFileSource(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	//the files are only read when keys are available
+	if err := ioutil.WriteFile(filepath.Join(baseFolder, "prod.keys"), []byte(testProdKeys), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := settings.InitSwitchKeys(baseFolder); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		settings.ReadSettings(baseFolder).Prodkeys = ""
+		os.Remove(filepath.Join(baseFolder, "prod.keys"))
+		settings.InitSwitchKeys(baseFolder)
+	}()
+
+	manager, err := NewLocalSwitchDBManagerWithCache(baseFolder, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nspPath := filepath.Join("/remote", "Super Mario Odyssey [0100000000010000][v0].nsp")
+	xciPath := filepath.Join("/remote", "Zelda [0100000000020000][v0].xci")
+	source := &memoryFileSource{files: map[string][]byte{nspPath: []byte("not a PFS0 header")}}
+	manager.SetFileSource(source)
+
+	files := []ExtendedFileInfo{
+		{FileName: filepath.Base(nspPath), BaseFolder: "/remote", Size: 17},
+		{FileName: filepath.Base(xciPath), BaseFolder: "/remote", Size: 1},
+	}
+	switchFiles, skipped := manager.GatherFiles(files, nil)
+	if len(source.opened) != 2 {
+		t.Errorf("expected both files to be read from the source, got %v", source.opened)
+	}
+	//the files can't be parsed, they are identified by their name
+	if len(switchFiles) != 2 || len(skipped) != 2 {
+		t.Fatalf("expected 2 files identified by their name, got %v (skipped %v)", switchFiles, skipped)
+	}
+	if skip := skipped[files[1]]; skip.ReasonCode != REASON_MALFORMED_FILE || !strings.Contains(skip.ReasonText, "no such remote file") {
+		t.Errorf("expected the source error to be reported, got %+v", skip)
+	}
+}
+
+type deniedFileSource struct{}
+
+func (deniedFileSource) Open(filePath string) (switchfs.ReadAtCloser, int64, error) {
+	return nil, 0, &os.PathError{Op: "open", Path: filePath, Err: os.ErrPermission}
+}
+
+func TestEmptyAndUnreadableFiles(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	if err := ioutil.WriteFile(filepath.Join(baseFolder, "prod.keys"), []byte(testProdKeys), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := settings.InitSwitchKeys(baseFolder); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		settings.ReadSettings(baseFolder).Prodkeys = ""
+		os.Remove(filepath.Join(baseFolder, "prod.keys"))
+		settings.InitSwitchKeys(baseFolder)
+	}()
+
+	manager, err := NewLocalSwitchDBManagerWithCache(baseFolder, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.SetFileSource(deniedFileSource{})
+
+	empty := ExtendedFileInfo{FileName: "Super Mario Odyssey [0100000000010000][v0].nsp", BaseFolder: "/remote", Size: 0}
+	denied := ExtendedFileInfo{FileName: "Zelda [0100000000020000][v0].nsp", BaseFolder: "/remote", Size: 10}
+	switchFiles, skipped := manager.GatherFiles([]ExtendedFileInfo{empty, denied}, nil)
+	//neither file is identified by its name
+	if len(switchFiles) != 0 {
+		t.Errorf("expected no files, got %v", switchFiles)
+	}
+	if skipped[empty].ReasonCode != REASON_EMPTY_FILE {
+		t.Errorf("expected the empty file to be skipped as empty, got %+v", skipped[empty])
+	}
+	if skipped[denied].ReasonCode != REASON_PERMISSION || !strings.Contains(skipped[denied].ReasonText, "permission denied") {
+		t.Errorf("expected the unreadable file to be skipped on permission, got %+v", skipped[denied])
+	}
+	if ReasonName(REASON_EMPTY_FILE) != "empty file" || ReasonName(REASON_PERMISSION) != "permission denied" {
+		t.Errorf("unexpected reason names %v, %v", ReasonName(REASON_EMPTY_FILE), ReasonName(REASON_PERMISSION))
+	}
+}
+
+func TestKeylessScan(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	settings.InitSwitchKeys(baseFolder)
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	//a NSP holding a plain cnmt.xml (PFS0 header, a single file entry, the name table and the file)
+	cnmtXml := []byte("<ContentMeta><Type>Application</Type><Id>0x0100000000010000</Id><Version>0</Version></ContentMeta>")
+	name := []byte("0123456789abcdef0123456789abcdef.cnmt.xml\x00")
+	nsp := make([]byte, 0x10+0x18)
+	copy(nsp, "PFS0")
+	binary.LittleEndian.PutUint32(nsp[0x4:0x8], 1)
+	binary.LittleEndian.PutUint32(nsp[0x8:0xC], uint32(len(name)))
+	binary.LittleEndian.PutUint64(nsp[0x18:0x20], uint64(len(cnmtXml)))
+	nsp = append(append(nsp, name...), cnmtXml...)
+
+	verified := ExtendedFileInfo{FileName: "cached.nsp", BaseFolder: "/remote", Size: 100}
+	manager.db.AddEntry(DB_TABLE_FILE_SCAN_METADATA, fileCacheKey(verified, filepath.Join("/remote", "cached.nsp")),
+		map[string]*switchfs.ContentMetaAttributes{"0100000000020000": {TitleId: "0100000000020000", Type: "BASE"}})
+	manager.SetFileSource(&memoryFileSource{files: map[string][]byte{filepath.Join("/remote", "game.nsp"): nsp}})
+
+	files := []ExtendedFileInfo{
+		//no title id in the name, identified by the cnmt.xml inside the NSP
+		{FileName: "game.nsp", BaseFolder: "/remote", Size: int64(len(nsp))},
+		{FileName: "Zelda [0100000000030000][v0].nsp", BaseFolder: "/remote", Size: 10},
+		verified,
+	}
+	switchFiles, skipped := manager.GatherFiles(files, nil)
+	if len(switchFiles) != 3 || len(skipped) != 0 {
+		t.Fatalf("expected 3 files, got %v (skipped %v)", switchFiles, skipped)
+	}
+	unverified := map[string]bool{}
+	for _, switchFile := range switchFiles {
+		unverified[switchFile.Metadata.TitleId] = switchFile.Unverified()
+	}
+	//the cached metadata was read with keys
+	if !unverified["0100000000010000"] || !unverified["0100000000030000"] || unverified["0100000000020000"] {
+		t.Errorf("unexpected unverified files %v", unverified)
+	}
+
+	localDB := Group(switchFiles, GroupOptions{})
+	if len(localDB.UnverifiedFiles()) != 2 {
+		t.Errorf("expected 2 unverified library files, got %v", localDB.UnverifiedFiles())
+	}
+}
+
+func TestKeyErrors(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	if err := ioutil.WriteFile(filepath.Join(baseFolder, "prod.keys"), []byte(testProdKeys), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := settings.InitSwitchKeys(baseFolder); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		settings.ReadSettings(baseFolder).Prodkeys = ""
+		os.Remove(filepath.Join(baseFolder, "prod.keys"))
+		settings.InitSwitchKeys(baseFolder)
+	}()
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	//a NSP holding a meta NCA, whose header the test keys don't decrypt
+	name := []byte("0123456789abcdef0123456789abcdef.cnmt.nca\x00")
+	nsp := make([]byte, 0x10+0x18)
+	copy(nsp, "PFS0")
+	binary.LittleEndian.PutUint32(nsp[0x4:0x8], 1)
+	binary.LittleEndian.PutUint32(nsp[0x8:0xC], uint32(len(name)))
+	binary.LittleEndian.PutUint64(nsp[0x18:0x20], 0xC00)
+	nsp = append(append(nsp, name...), make([]byte, 0xC00)...)
+
+	keysFile := ExtendedFileInfo{FileName: "Zelda [0100000000030000][v0].nsp", BaseFolder: "/remote", Size: int64(len(nsp))}
+	malformed := ExtendedFileInfo{FileName: "Super Mario Odyssey [0100000000010000][v0].nsp", BaseFolder: "/remote", Size: 17}
+	manager.SetFileSource(&memoryFileSource{files: map[string][]byte{
+		filepath.Join("/remote", keysFile.FileName):  nsp,
+		filepath.Join("/remote", malformed.FileName): []byte("not a PFS0 header"),
+	}})
+
+	switchFiles, skipped := manager.GatherFiles([]ExtendedFileInfo{keysFile, malformed}, nil)
+	if skipped[keysFile].ReasonCode != REASON_KEY_ERROR || skipped[malformed].ReasonCode != REASON_MALFORMED_FILE {
+		t.Errorf("expected a key error and a malformed file, got %v", skipped)
+	}
+	//the files are still identified by their name
+	if len(switchFiles) != 2 {
+		t.Errorf("expected 2 files identified by name, got %v", switchFiles)
+	}
+	if err := readKeysError(skipped); err == nil || !strings.Contains(err.Error(), "header_key") {
+		t.Errorf("expected the header_key to be reported, got %v", err)
+	}
+	//fixing the keys fixes the file, its failure is not cached
+	if manager.cachedFailure(fileCacheKey(keysFile, filepath.Join("/remote", keysFile.FileName))) != nil {
+		t.Errorf("expected the key error not to be cached")
+	}
+}
@@ -0,0 +1,221 @@
+package db
+
+import (
+	"github.com/giwty/switch-library-manager/switchfs"
+	"testing"
+)
+
+func testSwitchFile(fileName string, titleId string, version int) SwitchFileInfo {
+	return SwitchFileInfo{
+		ExtendedInfo: ExtendedFileInfo{FileName: fileName, BaseFolder: "/games/", Size: 1},
+		Metadata:     &switchfs.ContentMetaAttributes{TitleId: titleId, Version: version},
+		Format:       "nsp",
+	}
+}
+
+func TestGroup(t *testing.T) {
+	files := []SwitchFileInfo{
+		testSwitchFile("base.nsp", "0100000000010000", 0),
+		testSwitchFile("update1.nsp", "0100000000010800", 65536),
+		testSwitchFile("update2.nsp", "0100000000010800", 131072),
+		testSwitchFile("copy of base.nsp", "0100000000010000", 0),
+		testSwitchFile("dlc.nsp", "0100000000011001", 0),
+		testSwitchFile("orphan update.nsp", "0100000000020800", 65536),
+	}
+
+	localDB := Group(files, GroupOptions{})
+
+	if len(localDB.TitlesMap) != 2 {
+		t.Fatalf("expected 2 titles, got %v", len(localDB.TitlesMap))
+	}
+	title := localDB.TitlesMap["0100000000010000"]
+	if title == nil || !title.BaseExist || title.File.ExtendedInfo.FileName != "base.nsp" {
+		t.Fatalf("expected base.nsp to be the base file, got %+v", title)
+	}
+	if title.LatestUpdate != 131072 || len(title.Updates) != 2 {
+		t.Errorf("expected 2 updates with latest 131072, got %v (%v)", len(title.Updates), title.LatestUpdate)
+	}
+	if _, ok := title.Dlc["0100000000011001"]; !ok {
+		t.Errorf("expected the DLC to be grouped under the title")
+	}
+	if len(title.Duplicates) != 1 || title.Duplicates[0].ExtendedInfo.FileName != "copy of base.nsp" {
+		t.Errorf("expected copy of base.nsp to be a duplicate, got %v", title.Duplicates)
+	}
+	if orphan := localDB.TitlesMap["0100000000020000"]; orphan == nil || orphan.BaseExist {
+		t.Errorf("expected the orphan update to be grouped without a base")
+	}
+
+	expectedSkipped := map[string]int{"update1.nsp": REASON_OLD_UPDATE, "copy of base.nsp": REASON_DUPLICATE}
+	if len(localDB.Skipped) != len(expectedSkipped) {
+		t.Errorf("expected %v skipped files, got %v", len(expectedSkipped), localDB.Skipped)
+	}
+	for file, skip := range localDB.Skipped {
+		if expectedSkipped[file.FileName] != skip.ReasonCode {
+			t.Errorf("unexpected skip reason for %v - %v", file.FileName, skip.ReasonCode)
+		}
+	}
+	if files[0].Metadata.Type != "" {
+		t.Errorf("expected the input metadata to be left untouched")
+	}
+}
+
+func TestGroupMultiContentUpdate(t *testing.T) {
+	bundleBase := testSwitchFile("bundle.nsp", "0100000000010000", 0)
+	bundleUpdate := testSwitchFile("bundle.nsp", "0100000000010800", 196608)
+	looseOld := testSwitchFile("update1.nsp", "0100000000010800", 65536)
+	looseOlder := testSwitchFile("update2.nsp", "0100000000010800", 131072)
+
+	orders := map[string][]SwitchFileInfo{
+		"loose first":  {looseOld, looseOlder, bundleBase, bundleUpdate},
+		"bundle first": {bundleBase, bundleUpdate, looseOld, looseOlder},
+		"mixed":        {looseOlder, bundleBase, bundleUpdate, looseOld},
+	}
+	for name, files := range orders {
+		t.Run(name, func(t *testing.T) {
+			localDB := Group(files, GroupOptions{})
+			title := localDB.TitlesMap["0100000000010000"]
+			if title == nil || !title.BaseExist || title.File.ExtendedInfo.FileName != "bundle.nsp" {
+				t.Fatalf("expected the bundle to provide the base, got %+v", title)
+			}
+			if !title.MultiContent {
+				t.Errorf("expected the title to be marked as multi-content")
+			}
+			if title.LatestUpdate != 196608 || title.Updates[title.LatestUpdate].ExtendedInfo.FileName != "bundle.nsp" {
+				t.Errorf("expected the bundled update to be the latest, got %v", title.LatestUpdate)
+			}
+			for _, loose := range []SwitchFileInfo{looseOld, looseOlder} {
+				if skipped, ok := localDB.Skipped[loose.ExtendedInfo]; !ok || skipped.ReasonCode != REASON_OLD_UPDATE {
+					t.Errorf("expected %v to be an old update, got %+v", loose.ExtendedInfo.FileName, skipped)
+				}
+			}
+			if skipped, ok := localDB.Skipped[bundleBase.ExtendedInfo]; ok {
+				t.Errorf("expected the bundle not to be skipped, got %+v", skipped)
+			}
+		})
+	}
+}
+
+func TestGroupMultiContentWithNewerLooseUpdate(t *testing.T) {
+	files := []SwitchFileInfo{
+		testSwitchFile("bundle.nsp", "0100000000010000", 0),
+		testSwitchFile("bundle.nsp", "0100000000010800", 65536),
+		testSwitchFile("update.nsp", "0100000000010800", 131072),
+	}
+	localDB := Group(files, GroupOptions{})
+	title := localDB.TitlesMap["0100000000010000"]
+	if title.LatestUpdate != 131072 {
+		t.Errorf("expected the loose update to be the latest, got %v", title.LatestUpdate)
+	}
+	if skipped, ok := localDB.Skipped[files[0].ExtendedInfo]; ok {
+		t.Errorf("expected the bundle (holding the base) not to be skipped, got %+v", skipped)
+	}
+}
+
+func TestGroupByBaseTitleId(t *testing.T) {
+	//two applications sharing the title id prefix (010000000001), which the prefix grouping merged
+	files := []SwitchFileInfo{
+		testSwitchFile("first.nsp", "0100000000010000", 0),
+		testSwitchFile("first update.nsp", "0100000000010800", 65536),
+		testSwitchFile("first dlc.nsp", "0100000000011001", 0),
+		testSwitchFile("second.nsp", "0100000000012000", 0),
+		testSwitchFile("second update.nsp", "0100000000012800", 131072),
+		testSwitchFile("second dlc.nsp", "0100000000013001", 0),
+	}
+	localDB := Group(files, GroupOptions{})
+	if len(localDB.TitlesMap) != 2 {
+		t.Fatalf("expected 2 titles, got %v", len(localDB.TitlesMap))
+	}
+	if len(localDB.Skipped) != 0 {
+		t.Errorf("expected no skipped files, got %+v", localDB.Skipped)
+	}
+	expected := map[string]struct {
+		base    string
+		update  int
+		dlcId   string
+		dlcFile string
+	}{
+		"0100000000010000": {"first.nsp", 65536, "0100000000011001", "first dlc.nsp"},
+		"0100000000012000": {"second.nsp", 131072, "0100000000013001", "second dlc.nsp"},
+	}
+	for key, e := range expected {
+		title := localDB.TitlesMap[key]
+		if title == nil || !title.BaseExist || title.File.ExtendedInfo.FileName != e.base {
+			t.Fatalf("%v - expected base %v, got %+v", key, e.base, title)
+		}
+		if title.LatestUpdate != e.update || len(title.Updates) != 1 {
+			t.Errorf("%v - expected a single update v%v, got %v", key, e.update, title.Updates)
+		}
+		if dlc, ok := title.Dlc[e.dlcId]; !ok || dlc.ExtendedInfo.FileName != e.dlcFile || len(title.Dlc) != 1 {
+			t.Errorf("%v - expected dlc %v, got %v", key, e.dlcFile, title.Dlc)
+		}
+	}
+}
+
+func TestGroupKeepOldUpdates(t *testing.T) {
+	files := []SwitchFileInfo{
+		testSwitchFile("base.nsp", "0100000000010000", 0),
+		testSwitchFile("update2.nsp", "0100000000010800", 131072),
+		testSwitchFile("update1.nsp", "0100000000010800", 65536),
+		testSwitchFile("update3.nsp", "0100000000010800", 196608),
+	}
+	localDB := Group(files, GroupOptions{KeepOldUpdates: true})
+	title := localDB.TitlesMap["0100000000010000"]
+	if len(title.Updates) != 3 || title.LatestUpdate != 196608 {
+		t.Errorf("expected all the updates with v196608 active, got %v (latest %v)", title.Updates, title.LatestUpdate)
+	}
+	if len(localDB.Skipped) != 0 {
+		t.Errorf("expected no skipped files, got %+v", localDB.Skipped)
+	}
+
+	localDB = Group(files, GroupOptions{})
+	if len(localDB.Skipped) != 2 {
+		t.Errorf("expected the old updates to be skipped by default, got %+v", localDB.Skipped)
+	}
+}
+
+func TestGetByTitleId(t *testing.T) {
+	localDB := Group([]SwitchFileInfo{
+		testSwitchFile("base.nsp", "0100000000010000", 0),
+		testSwitchFile("update.nsp", "0100000000010800", 65536),
+		testSwitchFile("dlc.nsp", "0100000000011001", 0),
+	}, GroupOptions{})
+
+	for _, titleId := range []string{"0100000000010000", "[0100000000010800]", "0100000000011001", "010000000001", " 0100000000010000 "} {
+		title, ok := localDB.GetByTitleId(titleId)
+		if !ok || title.File.ExtendedInfo.FileName != "base.nsp" {
+			t.Errorf("%v - expected the base game, got %+v", titleId, title)
+		}
+	}
+	if _, ok := localDB.GetByTitleId("0100000000020000"); ok {
+		t.Errorf("expected an unknown title not to be found")
+	}
+}
+
+func TestGroupDuplicatePreference(t *testing.T) {
+	small := testSwitchFile("a.nsp", "0100000000010000", 0)
+	large := testSwitchFile("b.nsp", "0100000000010000", 0)
+	large.ExtendedInfo.Size = 10
+	dlc1 := testSwitchFile("dlc b.nsp", "0100000000011001", 0)
+	dlc2 := testSwitchFile("dlc a.nsp", "0100000000011001", 0)
+
+	for _, order := range [][]SwitchFileInfo{{small, large, dlc1, dlc2}, {dlc2, large, dlc1, small}} {
+		title := Group(order, GroupOptions{}).TitlesMap["0100000000010000"]
+		if title.File.ExtendedInfo.FileName != "a.nsp" || title.Dlc["0100000000011001"].ExtendedInfo.FileName != "dlc a.nsp" {
+			t.Errorf("expected the smallest paths to be kept, got %v and %v", title.File.ExtendedInfo.FileName,
+				title.Dlc["0100000000011001"].ExtendedInfo.FileName)
+		}
+		title = Group(order, GroupOptions{PreferLargerFiles: true}).TitlesMap["0100000000010000"]
+		if title.File.ExtendedInfo.FileName != "b.nsp" {
+			t.Errorf("expected the largest file to be kept, got %v", title.File.ExtendedInfo.FileName)
+		}
+	}
+
+	//merged libraries follow the same rule
+	localDB := Group([]SwitchFileInfo{large}, GroupOptions{})
+	other := Group([]SwitchFileInfo{small}, GroupOptions{})
+	mergeTitles(localDB.TitlesMap["0100000000010000"], other.TitlesMap["0100000000010000"], localDB.Skipped, GroupOptions{})
+	title := localDB.TitlesMap["0100000000010000"]
+	if title.File.ExtendedInfo.FileName != "a.nsp" || localDB.Skipped[large.ExtendedInfo].ReasonCode != REASON_DUPLICATE {
+		t.Errorf("expected a.nsp to be kept and b.nsp to be a duplicate, got %v %v", title.File.ExtendedInfo.FileName, localDB.Skipped)
+	}
+}
@@ -0,0 +1,204 @@
+package db
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"sort"
+	"strings"
+)
+
+const (
+	SEVERITY_INFO = iota
+	SEVERITY_WARNING
+	SEVERITY_ERROR
+)
+
+const (
+	ISSUE_BASE_MISSING       = "base_missing"
+	ISSUE_UPDATE_AVAILABLE   = "update_available"
+	ISSUE_SUPERSEDED_UPDATES = "superseded_updates"
+	ISSUE_DUPLICATES         = "duplicates"
+	ISSUE_MIXED_FORMATS      = "mixed_formats"
+	ISSUE_DLC_INCOMPLETE     = "dlc_incomplete"
+	ISSUE_MISMATCHED_ID      = "mismatched_id"
+	ISSUE_SPLIT_PARTS        = "split_parts"
+)
+
+type Issue struct {
+	Code     string `json:"code"`
+	Severity int    `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// Health returns the issues detected for the title, most severe first (see HealthWithCatalog)
+func (s *SwitchGameFiles) Health() []Issue {
+	return s.HealthWithCatalog(nil)
+}
+
+// HealthWithCatalog returns the issues detected for the title, most severe first.
+// the catalog entry of the title is optional, without it the available updates and missing DLC are not checked
+func (s *SwitchGameFiles) HealthWithCatalog(catalog *SwitchTitle) []Issue {
+	var issues []Issue
+
+	if !s.BaseExist {
+		issues = append(issues, Issue{Code: ISSUE_BASE_MISSING, Severity: SEVERITY_ERROR, Message: "base game is missing"})
+	}
+
+	if catalog != nil {
+		latest := 0
+		for version := range catalog.Updates {
+			if version > latest {
+				latest = version
+			}
+		}
+		if latest > s.LatestUpdate {
+			issues = append(issues, Issue{Code: ISSUE_UPDATE_AVAILABLE, Severity: SEVERITY_WARNING,
+				Message: fmt.Sprintf("update v%v is available (local v%v)", latest, s.LatestUpdate)})
+		}
+
+		missing := 0
+		for dlcId := range catalog.Dlc {
+			if _, ok := s.Dlc[dlcId]; !ok {
+				missing++
+			}
+		}
+		if missing != 0 {
+			issues = append(issues, Issue{Code: ISSUE_DLC_INCOMPLETE, Severity: SEVERITY_INFO,
+				Message: fmt.Sprintf("%v of %v DLC are missing", missing, len(catalog.Dlc))})
+		}
+	}
+
+	if len(s.Updates) > 1 {
+		issues = append(issues, Issue{Code: ISSUE_SUPERSEDED_UPDATES, Severity: SEVERITY_INFO,
+			Message: fmt.Sprintf("%v old update files can be deleted", len(s.Updates)-1)})
+	}
+
+	if len(s.Duplicates) != 0 {
+		issues = append(issues, Issue{Code: ISSUE_DUPLICATES, Severity: SEVERITY_WARNING,
+			Message: fmt.Sprintf("%v duplicate files", len(s.Duplicates))})
+	}
+
+	files := s.files()
+	formats := map[string]struct{}{}
+	for _, file := range files {
+		if file.Format != "" {
+			formats[file.Format] = struct{}{}
+		}
+	}
+	if len(formats) > 1 {
+		names := make([]string, 0, len(formats))
+		for format := range formats {
+			names = append(names, format)
+		}
+		sort.Strings(names)
+		issues = append(issues, Issue{Code: ISSUE_MIXED_FORMATS, Severity: SEVERITY_INFO,
+			Message: "files in mixed formats (" + strings.Join(names, ", ") + ")"})
+	}
+
+	for _, file := range files {
+		if file.Split != nil && len(file.Split.Warnings) != 0 {
+			issues = append(issues, Issue{Code: ISSUE_SPLIT_PARTS, Severity: SEVERITY_WARNING,
+				Message: fmt.Sprintf("split file [%v] - %v", file.ExtendedInfo.FileName, strings.Join(file.Split.Warnings, ", "))})
+		}
+		if message := mismatchedId(s, file); message != "" {
+			issues = append(issues, Issue{Code: ISSUE_MISMATCHED_ID, Severity: SEVERITY_WARNING, Message: message})
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Severity != issues[j].Severity {
+			return issues[i].Severity > issues[j].Severity
+		}
+		return issues[i].Code < issues[j].Code
+	})
+	return issues
+}
+
+// files returns all the files of the title (base, updates, DLC and duplicates)
+func (s *SwitchGameFiles) files() []SwitchFileInfo {
+	return append(s.contentFiles(), s.Duplicates...)
+}
+
+// contentFiles returns the files the title is made of - base, updates (by version) and DLC (by title id)
+func (s *SwitchGameFiles) contentFiles() []SwitchFileInfo {
+	var files []SwitchFileInfo
+	if s.BaseExist {
+		files = append(files, s.File)
+	}
+	versions := make([]int, 0, len(s.Updates))
+	for version := range s.Updates {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	for _, version := range versions {
+		files = append(files, s.Updates[version])
+	}
+	dlcIds := make([]string, 0, len(s.Dlc))
+	for dlcId := range s.Dlc {
+		dlcIds = append(dlcIds, dlcId)
+	}
+	sort.Strings(dlcIds)
+	for _, dlcId := range dlcIds {
+		files = append(files, s.Dlc[dlcId])
+	}
+	return files
+}
+
+// mismatchedId checks that the file name tag and the application id of the file agree with its title id
+func mismatchedId(s *SwitchGameFiles, file SwitchFileInfo) string {
+	if file.Metadata == nil {
+		return ""
+	}
+	//compare the title prefix only, multi-content files are usually named after the base
+	if titleId, err := defaultFileNameParser.parseTitleId(file.ExtendedInfo.FileName); err == nil &&
+		len(file.Metadata.TitleId) == len(*titleId) &&
+		!strings.EqualFold((*titleId)[:len(*titleId)-4], file.Metadata.TitleId[:len(file.Metadata.TitleId)-4]) {
+		return fmt.Sprintf("file [%v] is named [%v] but contains [%v]", file.ExtendedInfo.FileName, *titleId, file.Metadata.TitleId)
+	}
+	if s.BaseExist && file.Metadata.ApplicationId != "" && s.File.Metadata != nil &&
+		!strings.EqualFold(file.Metadata.ApplicationId, s.File.Metadata.TitleId) {
+		return fmt.Sprintf("file [%v] targets application [%v], but the base is [%v]", file.ExtendedInfo.FileName,
+			file.Metadata.ApplicationId, s.File.Metadata.TitleId)
+	}
+	return ""
+}
+
+type UpdateMismatch struct {
+	TitleId             string
+	UpdateFile          ExtendedFileInfo
+	UpdateApplicationId string
+	BaseApplicationId   string
+}
+
+// ValidateUpdates checks that every update of a title with a base targets exactly the base application id
+// (the grouping only compares the title id prefix). files without an application id (e.g. parsed from the file name) are not checked.
+func (l *LocalSwitchFilesDB) ValidateUpdates() []UpdateMismatch {
+	var mismatches []UpdateMismatch
+	for _, switchFile := range l.TitlesMap {
+		if !switchFile.BaseExist || switchFile.File.Metadata == nil {
+			continue
+		}
+		baseId := switchFile.File.Metadata.ApplicationId
+		if baseId == "" {
+			baseId = switchFile.File.Metadata.TitleId
+		}
+		for _, update := range switchFile.files()[1:] {
+			if update.Metadata == nil || update.Metadata.Type != "Update" || update.Metadata.ApplicationId == "" {
+				continue
+			}
+			if !strings.EqualFold(update.Metadata.ApplicationId, baseId) {
+				zap.S().Warnf("update file [%v] targets application [%v], but the base is [%v]",
+					update.ExtendedInfo.FileName, update.Metadata.ApplicationId, baseId)
+				mismatches = append(mismatches, UpdateMismatch{TitleId: switchFile.TitleId(), UpdateFile: update.ExtendedInfo,
+					UpdateApplicationId: update.Metadata.ApplicationId, BaseApplicationId: baseId})
+			}
+		}
+	}
+	sort.Slice(mismatches, func(i, j int) bool {
+		if mismatches[i].TitleId != mismatches[j].TitleId {
+			return mismatches[i].TitleId < mismatches[j].TitleId
+		}
+		return mismatches[i].UpdateFile.FileName < mismatches[j].UpdateFile.FileName
+	})
+	return mismatches
+}
@@ -0,0 +1,29 @@
+package db
+
+import "testing"
+
+func TestValidateUpdates(t *testing.T) {
+	base := testSwitchFile("base.nsp", "0100000000010000", 0)
+	base.Metadata.ApplicationId = "0100000000010000"
+	update := testSwitchFile("update.nsp", "0100000000010800", 65536)
+	update.Metadata.ApplicationId = "0100000000010000"
+
+	localDB := Group([]SwitchFileInfo{base, update}, GroupOptions{})
+	if mismatches := localDB.ValidateUpdates(); len(mismatches) != 0 {
+		t.Errorf("expected no mismatches, got %+v", mismatches)
+	}
+
+	//same title id prefix, but the update belongs to a different application
+	mismatched := testSwitchFile("other update.nsp", "0100000000010800", 131072)
+	mismatched.Metadata.ApplicationId = "0100000000012000"
+
+	localDB = Group([]SwitchFileInfo{base, update, mismatched}, GroupOptions{})
+	mismatches := localDB.ValidateUpdates()
+	if len(mismatches) != 1 {
+		t.Fatalf("expected 1 mismatch, got %+v", mismatches)
+	}
+	if mismatches[0].UpdateFile.FileName != "other update.nsp" || mismatches[0].UpdateApplicationId != "0100000000012000" ||
+		mismatches[0].BaseApplicationId != "0100000000010000" || mismatches[0].TitleId != "0100000000010000" {
+		t.Errorf("unexpected mismatch %+v", mismatches[0])
+	}
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/giwty/switch-library-manager/settings"
+	"github.com/giwty/switch-library-manager/switchfs"
+	"go.uber.org/zap"
+	"io"
+	"path/filepath"
+	"sort"
+)
+
+// number of bytes hashed at the start and at the end of the files by the quick hash
+const quickHashChunkSize = 1024 * 1024
+
+type fileHash struct {
+	Size int64
+	Hash string
+	//hash of the whole file, or of its first and last chunks only
+	Full bool
+}
+
+// VerifyIntegrity hashes the library files (bases, updates and DLC) and compares the hashes to the ones stored by
+// the previous verification. files whose content changed without a size change, or which are shorter than
+// expected (truncated), are added to the Skipped map with REASON_CORRUPT. the hashes are stored by file path
+// (the hash of a corrupt file is kept, so it is reported until the file is fixed), it returns the number of corrupt files.
+// by default the first and last MB of the files are hashed, scan_options.full_hash hashes the whole files
+func (ldb *LocalSwitchDBManager) VerifyIntegrity(localDB *LocalSwitchFilesDB, progress ProgressUpdater) (int, error) {
+	full := settings.ReadSettings(ldb.baseFolder).ScanOptions.FullHash
+
+	files := map[string]SwitchFileInfo{}
+	for _, switchFile := range localDB.TitlesMap {
+		if switchFile.BaseExist {
+			files[fileKey(switchFile.File.ExtendedInfo)] = switchFile.File
+		}
+		for _, update := range switchFile.Updates {
+			files[fileKey(update.ExtendedInfo)] = update
+		}
+		for _, dlc := range switchFile.Dlc {
+			files[fileKey(dlc.ExtendedInfo)] = dlc
+		}
+	}
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	corrupt := 0
+	updated := map[string]interface{}{}
+	for i, path := range paths {
+		file := files[path]
+		if progress != nil {
+			progress.UpdateProgress(i+1, len(paths), "verify:"+file.ExtendedInfo.FileName)
+		}
+		size := file.ExtendedInfo.Size
+		if file.Split != nil && file.Split.TotalSize != 0 {
+			size = file.Split.TotalSize
+		}
+		hash, err := ldb.hashFile(file.ExtendedInfo, size, full)
+		if err != nil {
+			zap.S().Warnf("[file:%v] failed to verify the file [reason: %v]", file.ExtendedInfo.FileName, err)
+			localDB.Skipped[file.ExtendedInfo] = SkippedFile{ReasonCode: REASON_CORRUPT,
+				ReasonText: fmt.Sprintf("unable to read the file to its expected size (%v bytes) - %v", size, err)}
+			corrupt++
+			continue
+		}
+
+		var previous fileHash
+		if err := ldb.db.GetEntry(DB_TABLE_FILE_HASHES, path, &previous); err != nil {
+			zap.S().Warnf("%v", err)
+		}
+		if previous.Hash != "" && previous.Size == size && previous.Full == full && previous.Hash != hash {
+			zap.S().Warnf("[file:%v] the file content changed without a size change", file.ExtendedInfo.FileName)
+			localDB.Skipped[file.ExtendedInfo] = SkippedFile{ReasonCode: REASON_CORRUPT,
+				ReasonText: "file content changed since the previous verification (same size), the file may be corrupt"}
+			corrupt++
+			continue
+		}
+		if previous.Hash != hash || previous.Size != size || previous.Full != full {
+			updated[path] = fileHash{Size: size, Hash: hash, Full: full}
+		}
+	}
+	if err := ldb.db.AddEntries(DB_TABLE_FILE_HASHES, updated); err != nil {
+		return corrupt, err
+	}
+	return corrupt, nil
+}
+
+// hashFile hashes the file size and content (the first and last chunks only unless full is set),
+// an error is returned when the file is shorter than the given size
+func (ldb *LocalSwitchDBManager) hashFile(fileInfo ExtendedFileInfo, size int64, full bool) (string, error) {
+	var file switchfs.ReadAtCloser
+	var err error
+	if fileInfo.ArchiveEntry != "" {
+		file, _, err = switchfs.OpenZipEntry(filepath.Join(fileInfo.BaseFolder, fileInfo.FileName), fileInfo.ArchiveEntry)
+	} else {
+		file, err = ldb.splitSchemes.OpenFile(filepath.Join(fileInfo.BaseFolder, fileInfo.FileName))
+	}
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	reader := ldb.ioLimiter.Limit(file)
+
+	hash := sha256.New()
+	sizeBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(sizeBytes, uint64(size))
+	hash.Write(sizeBytes)
+
+	var sections []*io.SectionReader
+	if full || size <= 2*quickHashChunkSize {
+		sections = append(sections, io.NewSectionReader(reader, 0, size))
+	} else {
+		sections = append(sections, io.NewSectionReader(reader, 0, quickHashChunkSize),
+			io.NewSectionReader(reader, size-quickHashChunkSize, quickHashChunkSize))
+	}
+	for _, section := range sections {
+		n, err := io.Copy(hash, section)
+		if err != nil {
+			return "", err
+		}
+		if n != section.Size() {
+			return "", fmt.Errorf("file is truncated")
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyIntegrity(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+
+	base := filepath.Join(gamesFolder, "Super Mario Odyssey [0100000000010000][v0].nsp")
+	update := filepath.Join(gamesFolder, "Super Mario Odyssey [0100000000010800][v65536].nsp")
+	dlc := filepath.Join(gamesFolder, "Super Mario Odyssey [0100000000011001][v0].nsp")
+	for _, path := range []string{base, update, dlc} {
+		if err := ioutil.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	localDB, err := manager.CreateLocalSwitchFilesDB([]string{gamesFolder}, nil, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupt, err := manager.VerifyIntegrity(localDB, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corrupt != 0 || len(localDB.Skipped) != 0 {
+		t.Fatalf("expected no corrupt files on the first verification, got %v (%v)", corrupt, localDB.Skipped)
+	}
+
+	//same size, different content
+	if err := ioutil.WriteFile(update, []byte("DATA"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	//truncated
+	if err := ioutil.WriteFile(dlc, []byte("da"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	corrupt, err = manager.VerifyIntegrity(localDB, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corrupt != 2 || len(localDB.Skipped) != 2 {
+		t.Fatalf("expected 2 corrupt files, got %v (%v)", corrupt, localDB.Skipped)
+	}
+	for file, skipped := range localDB.Skipped {
+		path := filepath.Join(file.BaseFolder, file.FileName)
+		if (path != update && path != dlc) || skipped.ReasonCode != REASON_CORRUPT {
+			t.Errorf("unexpected skipped file %v - %v", file.FileName, skipped.ReasonText)
+		}
+	}
+
+	//the hash of a corrupt file is not overridden
+	delete(localDB.Skipped, localDB.TitlesMap["0100000000010000"].Updates[65536].ExtendedInfo)
+	corrupt, err = manager.VerifyIntegrity(localDB, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corrupt != 2 {
+		t.Errorf("expected the corrupt files to be reported again, got %v", corrupt)
+	}
+}
@@ -8,38 +8,258 @@ import (
 	"github.com/giwty/switch-library-manager/switchfs"
 	"go.uber.org/zap"
 	"os"
+	"path"
 	"path/filepath"
-	"regexp"
+	"sort"
 	"strconv"
 	"strings"
-)
-
-var (
-	versionRegex = regexp.MustCompile(`\[[vV]?(?P<version>[0-9]{1,10})]`)
-	titleIdRegex = regexp.MustCompile(`\[(?P<titleId>[A-Z,a-z0-9]{16})]`)
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
 const (
 	DB_TABLE_FILE_SCAN_METADATA = "deep-scan"
 	DB_TABLE_LOCAL_LIBRARY      = "local-library"
+	DB_TABLE_TITLE_NAMES        = "title-names"
+	DB_TABLE_FILE_HASHES        = "file-hashes"
+	DB_TABLE_CONTENT_HASHES     = "content-hashes"
+	DB_TABLE_FILE_SCAN_FAILURES = "scan-failures"
+)
 
-	REASON_UNSUPPORTED_TYPE = iota
+const (
+	//the reason codes are stored with the cached libraries, they keep their original values (starting at 2) and
+	//new codes are only added last
+	REASON_UNSUPPORTED_TYPE = iota + 2
 	REASON_DUPLICATE
 	REASON_OLD_UPDATE
 	REASON_UNRECOGNISED
 	REASON_MALFORMED_FILE
+	REASON_CORRUPT
+	REASON_EMPTY_FILE
+	REASON_PERMISSION
+	REASON_EXCLUDED
+	REASON_TIMEOUT
+	REASON_KEY_ERROR
 )
 
 type LocalSwitchDBManager struct {
-	db *PersistentDB
+	db             *PersistentDB
+	baseFolder     string
+	fileNameParser *fileNameParser
+	cacheStats     *cacheStats
+	groupOptions   GroupOptions
+	//quick scans read the metadata from the file names and .cnmt.xml files only (see settings.SCAN_DEPTH_QUICK)
+	quickScan bool
+	//caps the number of files read concurrently (0 = use the scan_options concurrency)
+	maxConcurrency int
+	//limits the concurrent disk reads of the manager (see settings.ScanOptions.IOConcurrency), shared by all its
+	//scans and reads
+	ioLimiter *switchfs.IOLimiter
+	//the configured split naming schemes followed by the default ones (see settings.ScanOptions.SplitPatterns)
+	splitSchemes switchfs.SplitSchemes
+	//reads the NSP/XCI files content, nil reads the local files (see SetFileSource)
+	fileSource FileSource
+	//the contents dropped by their title id (see settings.ScanExclusions)
+	exclusions titleExclusions
+	//max time to read the metadata of a file (0 = no limit) and number of retries of the failed reads (see readWithTimeout)
+	readTimeout time.Duration
+	readRetries int
+	//read the files which failed to be read by a previous scan again, instead of skipping them (see cachedFailure)
+	recheckFailed bool
+	//optional, invoked once for every file whose metadata failed to parse (with the underlying error),
+	//before the file is skipped. it is called concurrently from the scan workers
+	OnParseError func(file ExtendedFileInfo, err error)
+	//optional, invoked by Watch once the library was updated - OnTitle with each title whose files were added,
+	//changed or removed, OnSkip with each added or changed file which is skipped. called from the watcher goroutine
+	OnTitle func(title *SwitchGameFiles)
+	OnSkip  func(file ExtendedFileInfo, skip SkippedFile)
+}
+
+// cacheStats counts the files served from the metadata cache vs. parsed (updated atomically by the scan workers)
+type cacheStats struct {
+	hits       int64
+	misses     int64
+	parseNanos int64
 }
 
 func NewLocalSwitchDBManager(baseFolder string) (*LocalSwitchDBManager, error) {
 	db, err := NewPersistentDB(baseFolder)
 	if err != nil {
+		if !isReadOnlyError(err) {
+			return nil, err
+		}
+		//base folder is not writable, keep going without caching the scan results
+		zap.S().Warnf("unable to create the local DB in %v, scan results will not be cached [reason: %v]", baseFolder, err)
+		db = nil
+	}
+	ldb, err := newLocalSwitchDBManager(baseFolder, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return ldb, nil
+}
+
+// NewLocalSwitchDBManagerReadOnly opens the local DB of the base folder read-only (e.g. for a viewer process next to
+// the scanning one), the cached scan results are used but nothing is written - the write operations
+// (e.g. ClearScanData) return ErrReadOnly. the DB must exist
+func NewLocalSwitchDBManagerReadOnly(baseFolder string) (*LocalSwitchDBManager, error) {
+	db, err := NewPersistentDBReadOnly(baseFolder)
+	if err != nil {
+		return nil, err
+	}
+	ldb, err := newLocalSwitchDBManager(baseFolder, db)
+	if err != nil {
+		db.Close()
 		return nil, err
 	}
-	return &LocalSwitchDBManager{db: db}, nil
+	return ldb, nil
+}
+
+// ReadOnly returns true when the local DB was opened read-only (see NewLocalSwitchDBManagerReadOnly)
+func (ldb *LocalSwitchDBManager) ReadOnly() bool {
+	return ldb.db.ReadOnly()
+}
+
+// NewLocalSwitchDBManagerWithCache creates a manager storing the scan results in the given cache
+// (e.g. an InMemoryCache for ephemeral runs), a nil cache disables caching
+func NewLocalSwitchDBManagerWithCache(baseFolder string, cache MetadataCache) (*LocalSwitchDBManager, error) {
+	var db *PersistentDB
+	if cache != nil {
+		var err error
+		db, err = NewPersistentDBWithCache(cache)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newLocalSwitchDBManager(baseFolder, db)
+}
+
+func newLocalSwitchDBManager(baseFolder string, db *PersistentDB) (*LocalSwitchDBManager, error) {
+	options := settings.ReadSettings(baseFolder).ScanOptions
+	parser, err := newFileNameParser(options.VersionPattern, options.TitleIdPattern)
+	if err != nil {
+		return nil, err
+	}
+	schemes, err := splitSchemes(options.SplitPatterns)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalSwitchDBManager{db: db, baseFolder: baseFolder, fileNameParser: parser, cacheStats: &cacheStats{}, splitSchemes: schemes,
+		exclusions: newTitleExclusions(options.ScanExclusions), readTimeout: options.GetReadTimeout(), readRetries: options.ReadRetries, recheckFailed: options.RecheckFailedFiles, quickScan: options.GetScanDepth() == settings.SCAN_DEPTH_QUICK, groupOptions: GroupOptions{KeepOldUpdates: options.KeepOldUpdates,
+			PreferLargerFiles: options.GetDuplicatePreference() == settings.DUPLICATE_PREFER_SIZE, SplitSchemes: schemes}, ioLimiter: newIOLimiter(options, 0)}, nil
+}
+
+// SetScanDepth overrides the configured scan depth (settings.SCAN_DEPTH_QUICK or settings.SCAN_DEPTH_FULL),
+// e.g. to run a quick first-pass inventory
+func (ldb *LocalSwitchDBManager) SetScanDepth(depth string) {
+	ldb.quickScan = depth == settings.SCAN_DEPTH_QUICK
+}
+
+// SetKeepOldUpdates overrides the configured keep_old_updates setting - keep the superseded updates with their title
+// without reporting them as skipped (REASON_OLD_UPDATE), see GroupOptions.KeepOldUpdates
+func (ldb *LocalSwitchDBManager) SetKeepOldUpdates(keep bool) {
+	ldb.groupOptions.KeepOldUpdates = keep
+}
+
+// SetParserConfig replaces the file name patterns configured in the scan_options,
+// an error is returned (and the patterns are left unchanged) when a pattern is invalid
+func (ldb *LocalSwitchDBManager) SetParserConfig(config ParserConfig) error {
+	parser, err := newFileNameParser(config.VersionPattern, config.TitleIdPattern)
+	if err != nil {
+		return err
+	}
+	ldb.fileNameParser = parser
+	return nil
+}
+
+// SetMaxConcurrency caps the number of files read and parsed concurrently, on top of the configured
+// scan_options concurrency (e.g. to avoid saturating a NAS disk). 0 removes the cap.
+// It should not be called while files are being read
+func (ldb *LocalSwitchDBManager) SetMaxConcurrency(max int) {
+	ldb.maxConcurrency = max
+	ldb.ioLimiter = newIOLimiter(settings.ReadSettings(ldb.baseFolder).ScanOptions, max)
+}
+
+// newIOLimiter limits the concurrent disk reads to the configured IO concurrency, capped by max (0 = no cap)
+func newIOLimiter(options settings.ScanOptions, max int) *switchfs.IOLimiter {
+	ioConcurrency := options.GetIOConcurrency()
+	if max > 0 && ioConcurrency > max {
+		ioConcurrency = max
+	}
+	return switchfs.NewIOLimiter(ioConcurrency)
+}
+
+// SplitSchemes returns the split naming schemes used by the manager - the configured ones followed by the default ones
+func (ldb *LocalSwitchDBManager) SplitSchemes() switchfs.SplitSchemes {
+	return ldb.splitSchemes
+}
+
+// splitSchemes returns the configured split naming schemes followed by the default ones
+func splitSchemes(patterns []settings.SplitPattern) (switchfs.SplitSchemes, error) {
+	var schemes switchfs.SplitSchemes
+	for i, pattern := range patterns {
+		scheme, err := switchfs.NewSplitScheme(fmt.Sprintf("custom%v", i+1), pattern.Pattern, pattern.FirstPart)
+		if err != nil {
+			return nil, err
+		}
+		schemes = append(schemes, scheme)
+	}
+	return append(schemes, switchfs.DefaultSplitSchemes()...), nil
+}
+
+func isReadOnlyError(err error) bool {
+	return os.IsPermission(err) || errors.Is(err, syscall.EROFS)
+}
+
+func isPermissionError(err error) bool {
+	return errors.Is(err, os.ErrPermission)
+}
+
+// unreadableSkip reports a file which couldn't be opened (permission denied) or read in time (see readWithTimeout),
+// nil for other errors. such a file is not identified by its name either as it couldn't be used anyway
+func unreadableSkip(err error) *SkippedFile {
+	if isPermissionError(err) {
+		return &SkippedFile{ReasonCode: REASON_PERMISSION, ReasonText: fmt.Sprintf("permission denied reading the file [reason: %v]", err)}
+	}
+	if errors.Is(err, errReadTimeout) {
+		return &SkippedFile{ReasonCode: REASON_TIMEOUT, ReasonText: err.Error()}
+	}
+	return nil
+}
+
+// readFailureSkip returns the reason a file whose metadata failed to be read is skipped - REASON_KEY_ERROR when the keys
+// are the cause (the file itself is fine), REASON_MALFORMED_FILE otherwise
+func readFailureSkip(err error, reasonText string) *SkippedFile {
+	if switchfs.IsKeyError(err) {
+		return &SkippedFile{ReasonCode: REASON_KEY_ERROR, ReasonText: reasonText}
+	}
+	return &SkippedFile{ReasonCode: REASON_MALFORMED_FILE, ReasonText: reasonText}
+}
+
+// readKeysError returns the keys problem found while reading the files (the first one by file), nil when none
+func readKeysError(skipped map[ExtendedFileInfo]SkippedFile) error {
+	var first *ExtendedFileInfo
+	for file, skip := range skipped {
+		if skip.ReasonCode != REASON_KEY_ERROR {
+			continue
+		}
+		if first == nil || fileKey(file) < fileKey(*first) {
+			file := file
+			first = &file
+		}
+	}
+	if first == nil {
+		return nil
+	}
+	return errors.New(skipped[*first].ReasonText)
+}
+
+// CacheEnabled returns false when the manager runs without a DB (e.g. read-only base folder)
+func (ldb *LocalSwitchDBManager) CacheEnabled() bool {
+	return ldb.db != nil
 }
 
 func (ldb *LocalSwitchDBManager) Close() {
@@ -51,11 +271,61 @@ type ExtendedFileInfo struct {
 	BaseFolder string
 	Size       int64
 	IsDir      bool
+	ModTime    time.Time
+	//the scan folder the file was found in
+	Root string
+	//only set for files stored inside a zip archive (FileName) - the entry path inside the archive
+	ArchiveEntry string
+}
+
+// ContentName returns the name of the file holding the content - the archive entry name for files stored
+// in an archive, the file name otherwise
+func (f ExtendedFileInfo) ContentName() string {
+	if f.ArchiveEntry != "" {
+		return path.Base(f.ArchiveEntry)
+	}
+	return f.FileName
+}
+
+// ReRoot returns the file info moved to a new root folder (e.g. when importing a library exported with relative paths)
+func (f ExtendedFileInfo) ReRoot(root string) ExtendedFileInfo {
+	relative := f.BaseFolder
+	if f.Root != "" {
+		if rel, err := filepath.Rel(f.Root, f.BaseFolder); err == nil {
+			relative = rel
+		}
+	}
+	f.BaseFolder = filepath.Join(root, relative) + string(os.PathSeparator)
+	f.Root = root
+	return f
 }
 
 type SwitchFileInfo struct {
 	ExtendedInfo ExtendedFileInfo
 	Metadata     *switchfs.ContentMetaAttributes
+	//only set for split files
+	Split *fileio.SplitFileInfo
+	//the file format (nsp/nsz/xci/xcz)
+	Format string
+	//identifies the file the content was read from, contents read from the same (multi-content) file share it
+	SourceFileId string
+	//the title name and the languages read from the NACP (base/update), empty when the control data isn't available
+	TitleName string
+	Languages []string
+}
+
+// Unverified returns true when the metadata was not read from the file content with keys, but derived from the file
+// name or a .cnmt.xml file (see switchfs.MetadataSource_FileName), it may be wrong
+func (f SwitchFileInfo) Unverified() bool {
+	return f.Metadata != nil && f.Metadata.Source != ""
+}
+
+// NumParts returns the number of parts the file is split into (1 for regular files)
+func (f SwitchFileInfo) NumParts() int {
+	if f.Split != nil && f.Split.NumParts > 1 {
+		return f.Split.NumParts
+	}
+	return 1
 }
 
 type SwitchGameFiles struct {
@@ -66,6 +336,8 @@ type SwitchGameFiles struct {
 	MultiContent bool
 	LatestUpdate int
 	IsSplit      bool
+	//files which duplicate an existing base/update/DLC (also reported as skipped)
+	Duplicates []SwitchFileInfo
 }
 
 type SkippedFile struct {
@@ -78,6 +350,72 @@ type LocalSwitchFilesDB struct {
 	TitlesMap map[string]*SwitchGameFiles
 	Skipped   map[ExtendedFileInfo]SkippedFile
 	NumFiles  int
+	//render the file paths in reports relative to their scan folder (see FilePath)
+	RelativePaths bool
+	//number of files whose metadata was served from the cache / parsed during the scan
+	CacheHits   int
+	CacheMisses int
+	//estimated time saved by the cache (cache hits * average parse time)
+	CacheTimeSaved time.Duration
+	//the library was built by a quick scan (file names and .cnmt.xml only), the grouping is approximate
+	LowConfidence bool
+	//set when the keys file is present but invalid, the files were identified by their name only
+	KeysError error
+	//the paths which could not be read during the scan (e.g. permission denied), the library may be incomplete
+	ScanErrors []ScanError
+}
+
+// ScanError is a file or folder which could not be read while listing the scan folders
+type ScanError struct {
+	Path string
+	Err  error
+}
+
+func (e ScanError) Error() string {
+	return e.Path + " - " + e.Err.Error()
+}
+
+// FilePath returns the path of the file as it should appear in reports and exports - absolute,
+// or relative to its scan folder when RelativePaths is set (and the scan folder is known)
+func (l *LocalSwitchFilesDB) FilePath(file ExtendedFileInfo) string {
+	path := filepath.Join(file.BaseFolder, file.FileName)
+	if file.ArchiveEntry != "" {
+		path = filepath.Join(path, filepath.FromSlash(file.ArchiveEntry))
+	}
+	if !l.RelativePaths || file.Root == "" {
+		return path
+	}
+	if rel, err := filepath.Rel(file.Root, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+// GetByTitleId returns the title the given title id (base, update or DLC, e.g. "[0100000000011001]") belongs to
+func (l *LocalSwitchFilesDB) GetByTitleId(titleId string) (*SwitchGameFiles, bool) {
+	titleId = strings.ToLower(strings.Trim(strings.TrimSpace(titleId), "[]"))
+	//title id prefix (without the last 4 digits)
+	if len(titleId) == 12 {
+		titleId += "0000"
+	}
+	if switchFile, ok := l.TitlesMap[groupingKey(titleId)]; ok {
+		return switchFile, true
+	}
+	//files grouped under another key (e.g. a title id parsed from a quirky file name)
+	for _, switchFile := range l.TitlesMap {
+		if switchFile.BaseExist && switchFile.File.Metadata != nil && strings.ToLower(switchFile.File.Metadata.TitleId) == titleId {
+			return switchFile, true
+		}
+		for _, update := range switchFile.Updates {
+			if update.Metadata != nil && strings.ToLower(update.Metadata.TitleId) == titleId {
+				return switchFile, true
+			}
+		}
+		if _, ok := switchFile.Dlc[titleId]; ok {
+			return switchFile, true
+		}
+	}
+	return nil, false
 }
 
 func (ldb *LocalSwitchDBManager) CreateLocalSwitchFilesDB(folders []string,
@@ -87,86 +425,577 @@ func (ldb *LocalSwitchDBManager) CreateLocalSwitchFilesDB(folders []string,
 	skipped := map[ExtendedFileInfo]SkippedFile{}
 	files := []ExtendedFileInfo{}
 
-	if !ignoreCache {
+	//a quick scan is never served from (or stored in) the library cache, which holds full scans only
+	if !ignoreCache && !ldb.quickScan {
 		ldb.db.GetEntry(DB_TABLE_LOCAL_LIBRARY, "files", &files)
 		ldb.db.GetEntry(DB_TABLE_LOCAL_LIBRARY, "skipped", &skipped)
 		ldb.db.GetEntry(DB_TABLE_LOCAL_LIBRARY, "titles", &titles)
+		//libraries cached by older versions are keyed by the title id prefix
+		ReconcileGroups(&LocalSwitchFilesDB{TitlesMap: titles, Skipped: skipped}, ldb.groupOptions)
+		//the library may have been cached with another keep_old_updates setting
+		for _, title := range titles {
+			markOldUpdates(title, skipped, ldb.groupOptions)
+		}
 	}
 
+	atomic.StoreInt64(&ldb.cacheStats.hits, 0)
+	atomic.StoreInt64(&ldb.cacheStats.misses, 0)
+	atomic.StoreInt64(&ldb.cacheStats.parseNanos, 0)
+	fromCache := len(titles) != 0
+	keysError := checkKeys()
+	var scanErrors []ScanError
+
+	options := settings.ReadSettings(ldb.baseFolder).ScanOptions
+	progress = NewThrottledProgress(progress, options.GetProgressInterval())
+
 	if len(titles) == 0 {
 
-		for i, folder := range folders {
-			err := scanFolder(folder, recursive, &files, progress)
-			if progress != nil {
-				progress.UpdateProgress(i+1, len(folders)+1, "scanning files in "+folder)
-			}
-			if err != nil {
-				continue
-			}
+		var err error
+		scanErrors, err = scanFolders(folders, recursive, options, &files, progress)
+		if err != nil {
+			return nil, err
 		}
 
+		files = uniqueFiles(files)
 		ldb.processLocalFiles(files, progress, titles, skipped)
+		ldb.saveScanMeta(folders, len(files), titles, skipped)
 
-		ldb.db.AddEntry(DB_TABLE_LOCAL_LIBRARY, "files", files)
-		ldb.db.AddEntry(DB_TABLE_LOCAL_LIBRARY, "skipped", skipped)
-		ldb.db.AddEntry(DB_TABLE_LOCAL_LIBRARY, "titles", titles)
+		if !ldb.quickScan {
+			if progress != nil {
+				progress.UpdateProgress(len(files), len(files), "caching")
+			}
+			ldb.db.AddEntry(DB_TABLE_LOCAL_LIBRARY, "files", files)
+			ldb.db.AddEntry(DB_TABLE_LOCAL_LIBRARY, "skipped", skipped)
+			ldb.db.AddEntry(DB_TABLE_LOCAL_LIBRARY, "titles", titles)
+			ldb.updateTitleNames(titles)
+		}
 	}
 
 	if progress != nil {
 		progress.UpdateProgress(len(files), len(files), "Complete")
 	}
 
-	return &LocalSwitchFilesDB{TitlesMap: titles, Skipped: skipped, NumFiles: len(files)}, nil
+	result := &LocalSwitchFilesDB{TitlesMap: titles, Skipped: skipped, NumFiles: len(files), LowConfidence: ldb.quickScan,
+		KeysError: keysError, ScanErrors: scanErrors}
+	if result.KeysError == nil {
+		result.KeysError = readKeysError(skipped)
+	}
+	if fromCache {
+		//the whole library was loaded from the cache
+		result.CacheHits = len(files)
+	} else {
+		result.CacheHits = int(atomic.LoadInt64(&ldb.cacheStats.hits))
+		result.CacheMisses = int(atomic.LoadInt64(&ldb.cacheStats.misses))
+		if result.CacheMisses != 0 {
+			averageParseTime := atomic.LoadInt64(&ldb.cacheStats.parseNanos) / int64(result.CacheMisses)
+			result.CacheTimeSaved = time.Duration(averageParseTime * int64(result.CacheHits))
+		}
+	}
+	return result, nil
 }
 
-func scanFolder(folder string, recursive bool, files *[]ExtendedFileInfo, progress ProgressUpdater) error {
-	filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
-		if path == folder {
-			return nil
+// UpdateLocalSwitchFilesDB scans additional folders and merges their files into a library returned by
+// CreateLocalSwitchFilesDB, files already part of the library are not scanned again.
+// duplicate and old files are resolved across the combined library (e.g. a newer update in the new folders
+// marks the update already in the library as old). the library cache is left untouched, as it holds the
+// configured scan folders only
+func (ldb *LocalSwitchDBManager) UpdateLocalSwitchFilesDB(localDB *LocalSwitchFilesDB, folders []string,
+	progress ProgressUpdater, recursive bool) (*LocalSwitchFilesDB, error) {
+
+	options := settings.ReadSettings(ldb.baseFolder).ScanOptions
+	progress = NewThrottledProgress(progress, options.GetProgressInterval())
+	localDB.KeysError = checkKeys()
+
+	files := libraryFiles(localDB)
+	known := make(map[ExtendedFileInfo]struct{}, len(files))
+	for _, file := range files {
+		known[file] = struct{}{}
+	}
+	//known files come first, so uniqueFiles drops the new files reachable through an already scanned path
+	sort.Slice(files, func(i, j int) bool {
+		return filepath.Join(files[i].BaseFolder, files[i].FileName) < filepath.Join(files[j].BaseFolder, files[j].FileName)
+	})
+	scanErrors, err := scanFolders(folders, recursive, options, &files, progress)
+	localDB.ScanErrors = append(localDB.ScanErrors, scanErrors...)
+	if err != nil {
+		return nil, err
+	}
+	var newFiles []ExtendedFileInfo
+	for _, file := range uniqueFiles(files) {
+		if _, ok := known[file]; !ok {
+			newFiles = append(newFiles, file)
+		}
+	}
+
+	titles := map[string]*SwitchGameFiles{}
+	skipped := map[ExtendedFileInfo]SkippedFile{}
+	ldb.processLocalFiles(newFiles, progress, titles, skipped)
+	if !ldb.quickScan {
+		ldb.updateTitleNames(titles)
+	}
+
+	for file, skip := range skipped {
+		localDB.Skipped[file] = skip
+	}
+	keys := make([]string, 0, len(titles))
+	for key := range titles {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if existing, ok := localDB.TitlesMap[key]; ok {
+			mergeTitles(existing, titles[key], localDB.Skipped, ldb.groupOptions)
+		} else {
+			localDB.TitlesMap[key] = titles[key]
+		}
+	}
+	localDB.NumFiles += len(newFiles)
+	if localDB.KeysError == nil {
+		localDB.KeysError = readKeysError(localDB.Skipped)
+	}
+	localDB.LowConfidence = localDB.LowConfidence || ldb.quickScan
+
+	if progress != nil {
+		progress.UpdateProgress(len(newFiles), len(newFiles), "Complete")
+	}
+	return localDB, nil
+}
+
+// checkKeys returns the keys file error, reported once per scan (the files are then identified by their name)
+func checkKeys() error {
+	_, err := settings.SwitchKeys()
+	if err != nil {
+		zap.S().Warnf("%v - deep scan is disabled, files are identified by their name", err)
+	}
+	return err
+}
+
+// scanFolders lists the files below the folders (see scanFolder) with the scan options
+func scanFolders(folders []string, recursive bool, options settings.ScanOptions, files *[]ExtendedFileInfo,
+	progress ProgressUpdater) ([]ScanError, error) {
+	var scanErrors []ScanError
+	limits := scanLimits{maxDepth: options.GetMaxDepth(), maxFiles: options.GetMaxFiles(), folderDepth: options.GetMaxFolderDepth()}
+	filter, err := newScanFilter(options.ScanFilter, options.ScanExclusions)
+	if err != nil {
+		zap.S().Errorf("%v", err)
+		return nil, err
+	}
+	folders = normalizeFolders(folders, recursive, limits.folderDepth)
+	for i, folder := range folders {
+		folderErrors, err := scanFolder(folder, recursive, options.FollowSymlinks, options.ScanZipArchives, filter, files, progress, limits)
+		scanErrors = append(scanErrors, folderErrors...)
+		if progress != nil {
+			progress.UpdateProgress(i+1, len(folders)+1, "scanning files in "+folder)
 		}
 		if err != nil {
-			zap.S().Error("Error while scanning folders", err)
-			return nil
+			zap.S().Errorf("%v", err)
+			return scanErrors, err
 		}
+	}
+	return scanErrors, nil
+}
 
-		if info.IsDir() {
-			return nil
+// normalizeFolders cleans the folders to absolute paths and drops the duplicates, keeping the first occurrence.
+// with recursive, the folders already walked as a sub-folder of another folder (within the folder depth) are dropped too
+func normalizeFolders(folders []string, recursive bool, folderDepth int) []string {
+	seen := map[string]struct{}{}
+	unique := make([]string, 0, len(folders))
+	for _, folder := range folders {
+		folder = filepath.Clean(folder)
+		if abs, err := filepath.Abs(folder); err == nil {
+			folder = abs
+		}
+		if _, ok := seen[folder]; ok {
+			continue
 		}
+		seen[folder] = struct{}{}
+		unique = append(unique, folder)
+	}
+	if !recursive {
+		return unique
+	}
+	result := make([]string, 0, len(unique))
+	for _, folder := range unique {
+		covered := false
+		for _, parent := range unique {
+			if depth := subFolderDepth(parent, folder); depth > 0 && (folderDepth < 0 || depth <= folderDepth) {
+				zap.S().Infof("skipping folder [%v] - already scanned as part of [%v]", folder, parent)
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			result = append(result, folder)
+		}
+	}
+	return result
+}
+
+// subFolderDepth returns how many levels the folder is below the parent, 0 when it is not a sub-folder
+func subFolderDepth(parent string, folder string) int {
+	rel, err := filepath.Rel(parent, folder)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+		return 0
+	}
+	return len(strings.Split(rel, string(os.PathSeparator)))
+}
 
-		//skip mac hidden files
-		if info.Name()[0:1] == "." {
+// scanLimits protects against scanning a wrong folder (e.g. the root folder) for too long
+type scanLimits struct {
+	maxDepth int
+	maxFiles int
+	//sub-folders deeper than this are not walked (0 = the scan folder only, -1 = unlimited), unlike maxDepth
+	//the scan goes on
+	folderDepth int
+}
+
+// scanFolder lists the files below the folder. with followSymlinks, symlinked files are listed with the details
+// of their target and symlinked folders are walked, each real folder is walked once to protect against symlink loops.
+// with scanArchives, the supported files inside zip archives are listed instead of the archives (see archiveEntries).
+// the files and folders rejected by the filter are left out. paths which can't be read are returned (the walk goes on), the error aborts the scan (see scanLimits)
+func scanFolder(folder string, recursive bool, followSymlinks bool, scanArchives bool, filter scanFilter, files *[]ExtendedFileInfo,
+	progress ProgressUpdater, limits scanLimits) ([]ScanError, error) {
+	var scanErrors []ScanError
+	visited := map[string]struct{}{}
+	if realPath, err := filepath.EvalSymlinks(folder); err == nil {
+		visited[realPath] = struct{}{}
+	}
+	//returns filepath.SkipDir for the sub-folders beyond the folder depth
+	checkDepth := func(path string) error {
+		rel, err := filepath.Rel(folder, path)
+		if err != nil {
 			return nil
 		}
-		base := path[0 : len(path)-len(info.Name())]
-		if strings.TrimSuffix(base, string(os.PathSeparator)) != strings.TrimSuffix(folder, string(os.PathSeparator)) &&
-			!recursive {
-			return nil
+		depth := len(strings.Split(rel, string(os.PathSeparator)))
+		if limits.folderDepth >= 0 && depth > limits.folderDepth {
+			return filepath.SkipDir
 		}
-		if progress != nil {
-			progress.UpdateProgress(-1, -1, "scanning "+info.Name())
+		if depth > limits.maxDepth {
+			return fmt.Errorf("scan aborted - folder [%v] is more than %v levels deep below [%v], "+
+				"please make sure the scan folder is correct (or increase scan_options.max_depth)", path, limits.maxDepth, folder)
 		}
-		*files = append(*files, ExtendedFileInfo{FileName: info.Name(), BaseFolder: base, Size: info.Size(), IsDir: info.IsDir()})
+		return nil
+	}
 
+	var walk func(root string) error
+	walk = func(root string) error {
+		return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				zap.S().Error("Error while scanning folders", err)
+				scanErrors = append(scanErrors, ScanError{Path: path, Err: err})
+				return nil
+			}
+			if path == root {
+				return nil
+			}
+
+			if followSymlinks && info.Mode()&os.ModeSymlink != 0 {
+				target, err := os.Stat(path)
+				if err != nil {
+					zap.S().Warnf("skipping broken symlink [%v] - %v", path, err)
+					scanErrors = append(scanErrors, ScanError{Path: path, Err: err})
+					return nil
+				}
+				if target.IsDir() {
+					if !recursive {
+						return nil
+					}
+					if err := checkDepth(path); err == filepath.SkipDir {
+						//not walked as a folder, SkipDir would skip the remaining files of the parent folder
+						return nil
+					} else if err != nil {
+						return err
+					}
+					realPath, err := filepath.EvalSymlinks(path)
+					if err != nil {
+						zap.S().Warnf("skipping symlink [%v] - %v", path, err)
+						scanErrors = append(scanErrors, ScanError{Path: path, Err: err})
+						return nil
+					}
+					if _, ok := visited[realPath]; ok {
+						zap.S().Infof("skipping symlink [%v] - [%v] is already scanned", path, realPath)
+						return nil
+					}
+					visited[realPath] = struct{}{}
+					//the trailing separator makes Walk follow the symlink
+					return walk(path + string(os.PathSeparator))
+				}
+				info = target
+			}
+
+			if filter.skip(path, info) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir() {
+				if !recursive {
+					return filepath.SkipDir
+				}
+				return checkDepth(path)
+			}
+
+			file := newExtendedFileInfo(filepath.Clean(folder), path, info)
+			if strings.TrimSuffix(file.BaseFolder, string(os.PathSeparator)) != strings.TrimSuffix(folder, string(os.PathSeparator)) &&
+				!recursive {
+				return nil
+			}
+			if progress != nil {
+				progress.UpdateProgress(-1, -1, "scanning "+info.Name())
+			}
+			if len(*files) >= limits.maxFiles {
+				return fmt.Errorf("scan aborted - more than %v files found, "+
+					"please make sure the scan folder [%v] is correct (or increase scan_options.max_files)", limits.maxFiles, folder)
+			}
+			if scanArchives {
+				if entries := archiveEntries(file); len(entries) != 0 {
+					*files = append(*files, entries...)
+					return nil
+				}
+			}
+			*files = append(*files, file)
+
+			return nil
+		})
+	}
+	err := walk(folder)
+	return scanErrors, err
+}
+
+// archiveEntries lists the NSP/NSZ files stored in a zip archive, nil is returned for other files
+// (and archives without such files, which are then reported as unsupported)
+func archiveEntries(file ExtendedFileInfo) []ExtendedFileInfo {
+	if strings.ToLower(filepath.Ext(file.FileName)) != ".zip" {
 		return nil
-	})
-	return nil
+	}
+	entries, err := switchfs.ZipEntries(filepath.Join(file.BaseFolder, file.FileName))
+	if err != nil {
+		zap.S().Warnf("[file:%v] failed to read the zip archive [reason: %v]", file.FileName, err)
+		return nil
+	}
+	var result []ExtendedFileInfo
+	for _, entry := range entries {
+		if switchfs.ClassifyFileName(path.Base(entry.Name)).IsNsp() {
+			archiveFile := file
+			archiveFile.ArchiveEntry = entry.Name
+			archiveFile.Size = entry.Size
+			result = append(result, archiveFile)
+		}
+	}
+	return result
+}
+
+// uniqueFiles removes files reachable more than once (e.g. overlapping scan folders or symlinks),
+// keeping the first occurrence
+func uniqueFiles(files []ExtendedFileInfo) []ExtendedFileInfo {
+	seen := map[string]struct{}{}
+	result := make([]ExtendedFileInfo, 0, len(files))
+	for _, file := range files {
+		path := uniqueKey(file)
+		if _, ok := seen[path]; ok {
+			zap.S().Debugf("skipping [%v] - already scanned", filepath.Join(file.BaseFolder, file.FileName))
+			continue
+		}
+		seen[path] = struct{}{}
+		result = append(result, file)
+	}
+	return result
+}
+
+// uniqueKey identifies the file by its real absolute path (and archive entry), see uniqueFiles
+func uniqueKey(file ExtendedFileInfo) string {
+	path := filepath.Join(file.BaseFolder, file.FileName)
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if file.ArchiveEntry != "" {
+		path += "|" + file.ArchiveEntry
+	}
+	return path
+}
+
+func newExtendedFileInfo(root string, path string, info os.FileInfo) ExtendedFileInfo {
+	base := path[0 : len(path)-len(info.Name())]
+	return ExtendedFileInfo{FileName: info.Name(), BaseFolder: base, Size: info.Size(), IsDir: info.IsDir(),
+		ModTime: info.ModTime(), Root: root}
 }
 
 func (ldb *LocalSwitchDBManager) ClearScanData() error {
+	if err := ldb.db.ClearTable(DB_TABLE_FILE_SCAN_FAILURES); err != nil {
+		return err
+	}
 	return ldb.db.ClearTable(DB_TABLE_FILE_SCAN_METADATA)
 }
 
+// PruneMissing removes the cached metadata (and read failures) of files below the given folders (all files when empty) that no longer
+// exist or were modified since they were cached, returning the number of removed entries.
+// folders that don't exist (e.g. an unmounted drive) are ignored, so their cache is kept
+func (ldb *LocalSwitchDBManager) PruneMissing(folders []string) (int, error) {
+	var roots []string
+	for _, folder := range folders {
+		if _, err := os.Stat(folder); err != nil {
+			zap.S().Infof("skipping cache pruning of [%v] - %v", folder, err)
+			continue
+		}
+		if abs, err := filepath.Abs(folder); err == nil {
+			folder = abs
+		}
+		roots = append(roots, filepath.Clean(folder))
+	}
+	if len(folders) != 0 && len(roots) == 0 {
+		return 0, nil
+	}
+	stale := func(key string) bool {
+		if key == "app_version" {
+			return false
+		}
+		//keys are "path|name|size|modification time[|archive entry]" (see fileCacheKey)
+		parts := strings.SplitN(key, "|", 5)
+		filePath := parts[0]
+		if len(roots) != 0 && !isBelowAny(filePath, roots) {
+			return false
+		}
+		info, err := os.Stat(filePath)
+		if err != nil {
+			return os.IsNotExist(err)
+		}
+		current := ExtendedFileInfo{FileName: info.Name(), Size: info.Size(), ModTime: info.ModTime()}
+		if len(parts) == 5 {
+			//archive entry - the size is the entry size, the archive modification time tells whether it changed
+			current.ArchiveEntry = parts[4]
+			current.Size, _ = strconv.ParseInt(parts[2], 10, 64)
+		}
+		return fileCacheKey(current, filePath) != key
+	}
+	//the failures are keyed as the metadata (see cachedFailure)
+	failures, err := ldb.db.DeleteEntries(DB_TABLE_FILE_SCAN_FAILURES, stale)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := ldb.db.DeleteEntries(DB_TABLE_FILE_SCAN_METADATA, stale)
+	return deleted + failures, err
+}
+
+// MoveCacheEntry moves the cached metadata of a file which was renamed/moved to newPath (keeping its size and
+// modification time), so it is not parsed again by the next scan
+func (ldb *LocalSwitchDBManager) MoveCacheEntry(file ExtendedFileInfo, newPath string) error {
+	oldKey := fileCacheKey(file, filepath.Join(file.BaseFolder, file.FileName))
+	var metadata map[string]*switchfs.ContentMetaAttributes
+	if err := ldb.db.GetEntry(DB_TABLE_FILE_SCAN_METADATA, oldKey, &metadata); err != nil || metadata == nil {
+		return err
+	}
+	moved := file
+	moved.FileName = filepath.Base(newPath)
+	moved.BaseFolder = filepath.Dir(newPath) + string(os.PathSeparator)
+	if err := ldb.db.AddEntry(DB_TABLE_FILE_SCAN_METADATA, fileCacheKey(moved, newPath), metadata); err != nil {
+		return err
+	}
+	return ldb.db.DeleteEntry(DB_TABLE_FILE_SCAN_METADATA, oldKey)
+}
+
+func isBelowAny(filePath string, roots []string) bool {
+	if abs, err := filepath.Abs(filePath); err == nil {
+		filePath = abs
+	}
+	for _, root := range roots {
+		if rel, err := filepath.Rel(root, filePath); err == nil && rel != ".." &&
+			!strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+			return true
+		}
+	}
+	return false
+}
+
+type scanTask struct {
+	file     ExtendedFileInfo
+	filePath string
+	fileType switchfs.FileType
+}
+
+type scanResult struct {
+	contentMap map[string]*switchfs.ContentMetaAttributes
+	split      *fileio.SplitFileInfo
+	skip       *SkippedFile
+	err        error
+}
+
+// GroupOptions controls how the parsed files are grouped into titles (see Group)
+type GroupOptions struct {
+	//keep superseded updates in the title Updates only (LatestUpdate marks the active one),
+	//instead of also reporting them as skipped (REASON_OLD_UPDATE)
+	KeepOldUpdates bool
+	//of two files holding the same content (same title id and version), keep the largest one instead of
+	//the one with the smallest path (see preferredFile)
+	PreferLargerFiles bool
+	//the naming schemes telling the split files (nil - the default schemes)
+	SplitSchemes switchfs.SplitSchemes
+}
+
+// preferredFile returns true when the file a is kept over the file b holding the same content - the file with the
+// lexicographically smaller path, or the larger file (then the smaller path) with PreferLargerFiles.
+// the outcome doesn't depend on the order the files were found in
+func preferredFile(a SwitchFileInfo, b SwitchFileInfo, opts GroupOptions) bool {
+	if opts.PreferLargerFiles {
+		if sizeA, sizeB := groupedFileSize(a), groupedFileSize(b); sizeA != sizeB {
+			return sizeA > sizeB
+		}
+	}
+	return fileKey(a.ExtendedInfo) < fileKey(b.ExtendedInfo)
+}
+
+// groupedFileSize returns the file size, all the parts are counted for split files
+func groupedFileSize(file SwitchFileInfo) int64 {
+	if file.Split != nil && file.Split.TotalSize != 0 {
+		return file.Split.TotalSize
+	}
+	return file.ExtendedInfo.Size
+}
+
+// processLocalFiles reads the files metadata and groups the files into titles
 func (ldb *LocalSwitchDBManager) processLocalFiles(files []ExtendedFileInfo,
 	progress ProgressUpdater,
 	titles map[string]*SwitchGameFiles,
 	skipped map[ExtendedFileInfo]SkippedFile) {
-	ind := 0
-	total := len(files)
-	for _, file := range files {
-		ind += 1
-		if progress != nil {
-			progress.UpdateProgress(ind, total, "process:"+file.FileName)
+
+	switchFiles, gatherSkipped := ldb.GatherFiles(files, progress)
+	for file, skip := range gatherSkipped {
+		skipped[file] = skip
+	}
+	grouped := Group(switchFiles, ldb.groupOptions)
+	for key, title := range grouped.TitlesMap {
+		titles[key] = title
+	}
+	for file, skip := range grouped.Skipped {
+		skipped[file] = skip
+	}
+}
+
+// GatherFiles reads the metadata of the given files, returning an entry per content found in each file
+// (a multi-content file yields several entries) and the files that were skipped while reading.
+// files that were read with issues (e.g. malformed) are both returned and reported as skipped
+func (ldb *LocalSwitchDBManager) GatherFiles(files []ExtendedFileInfo,
+	progress ProgressUpdater) ([]SwitchFileInfo, map[ExtendedFileInfo]SkippedFile) {
+	return ldb.gatherFiles(files, progress, nil)
+}
+
+// gatherFiles implements GatherFiles, onFile (optional) is called from a single goroutine as soon as each file
+// is resolved, with its contents and the reason it was skipped (nil when not skipped)
+func (ldb *LocalSwitchDBManager) gatherFiles(files []ExtendedFileInfo, progress ProgressUpdater,
+	onFile func(file ExtendedFileInfo, switchFiles []SwitchFileInfo, skip *SkippedFile)) ([]SwitchFileInfo, map[ExtendedFileInfo]SkippedFile) {
+
+	skipped := map[ExtendedFileInfo]SkippedFile{}
+	skip := func(file ExtendedFileInfo, reason SkippedFile) {
+		skipped[file] = reason
+		if onFile != nil {
+			onFile(file, nil, &reason)
 		}
+	}
+	var tasks []scanTask
+	for _, file := range files {
 
 		//scan sub-folders if flag is present
 		filePath := filepath.Join(file.BaseFolder, file.FileName)
@@ -174,197 +1003,531 @@ func (ldb *LocalSwitchDBManager) processLocalFiles(files []ExtendedFileInfo,
 			continue
 		}
 
-		fileName := strings.ToLower(file.FileName)
-		isSplit := false
+		//too short to hold an extension (e.g. "a", or an empty name reported by some file systems)
+		if len(file.ContentName()) < 2 {
+			skip(file, SkippedFile{ReasonCode: REASON_UNSUPPORTED_TYPE, ReasonText: "file type is not supported"})
+			continue
+		}
 
-		if partNum, err := strconv.Atoi(fileName[len(fileName)-2:]); err == nil {
-			if partNum == 0 {
-				isSplit = true
-			} else {
-				continue
-			}
+		fileType := switchfs.ClassifyFileName(file.ContentName())
+		if file.ArchiveEntry == "" {
+			fileType = ldb.splitSchemes.ClassifyFile(filePath)
+		}
 
+		//only the first part of a split file is scanned, it represents the whole file
+		if fileType == switchfs.FileType_SplitPart && !ldb.splitSchemes.IsFirstSplitPart(filePath) {
+			continue
 		}
 
-		//only handle NSZ and NSP files
+		if fileType == switchfs.FileType_Unsupported {
+			skip(file, SkippedFile{ReasonCode: REASON_UNSUPPORTED_TYPE, ReasonText: "file type is not supported"})
+			continue
+		}
 
-		if !isSplit &&
-			!strings.HasSuffix(fileName, "xci") &&
-			!strings.HasSuffix(fileName, "nsp") &&
-			!strings.HasSuffix(fileName, "nsz") &&
-			!strings.HasSuffix(fileName, "xcz") {
-			skipped[file] = SkippedFile{ReasonCode: REASON_UNSUPPORTED_TYPE, ReasonText: "file type is not supported"}
+		//typically a failed download, it is not read (the file name alone would identify it)
+		if file.Size == 0 {
+			skip(file, SkippedFile{ReasonCode: REASON_EMPTY_FILE, ReasonText: "file is empty (0 bytes)"})
 			continue
 		}
+		tasks = append(tasks, scanTask{file: file, filePath: filePath, fileType: fileType})
+	}
 
-		contentMap, err := ldb.getGameMetadata(file, filePath, skipped)
+	var onDone func(i int, result scanResult)
+	if onFile != nil {
+		onDone = func(i int, result scanResult) {
+			switchFiles, skip := resultFiles(tasks[i].file, result)
+			switchFiles, skip = ldb.excludeTitles(tasks[i].file, switchFiles, skip)
+			onFile(tasks[i].file, switchFiles, skip)
+		}
+	}
+	results := ldb.readFilesMetadata(tasks, progress, onDone)
 
-		if err != nil {
-			if _, ok := skipped[file]; !ok {
-				skipped[file] = SkippedFile{ReasonText: "unable to determine title-Id / version - " + err.Error(), ReasonCode: REASON_UNRECOGNISED}
-			}
-			continue
+	//keep the files order, so the outcome doesn't depend on the workers scheduling
+	var switchFiles []SwitchFileInfo
+	for i, task := range tasks {
+		fileSwitchFiles, skip := resultFiles(task.file, results[i])
+		fileSwitchFiles, skip = ldb.excludeTitles(task.file, fileSwitchFiles, skip)
+		if skip != nil {
+			skipped[task.file] = *skip
 		}
+		switchFiles = append(switchFiles, fileSwitchFiles...)
+	}
+	return switchFiles, skipped
+}
 
-		for _, metadata := range contentMap {
+// resultFiles returns an entry per content read from the file, ordered by title id, and the reason the file is
+// skipped (nil when not skipped). a file read with issues (e.g. malformed) has both
+func resultFiles(file ExtendedFileInfo, result scanResult) ([]SwitchFileInfo, *SkippedFile) {
+	skip := result.skip
+	if result.err != nil {
+		if skip == nil {
+			skip = &SkippedFile{ReasonText: "unable to determine title-Id / version - " + result.err.Error(), ReasonCode: REASON_UNRECOGNISED}
+		}
+		return nil, skip
+	}
 
-			idPrefix := metadata.TitleId[0 : len(metadata.TitleId)-4]
+	if err := validateContentMap(result.contentMap); err != nil {
+		return nil, &SkippedFile{ReasonCode: REASON_UNRECOGNISED, ReasonText: err.Error()}
+	}
 
-			multiContent := len(contentMap) > 1
-			switchTitle := &SwitchGameFiles{
-				MultiContent: multiContent,
-				Updates:      map[int]SwitchFileInfo{},
-				Dlc:          map[string]SwitchFileInfo{},
-				BaseExist:    false,
-				IsSplit:      isSplit,
-				LatestUpdate: 0,
-			}
-			if t, ok := titles[idPrefix]; ok {
-				switchTitle = t
-			}
-			titles[idPrefix] = switchTitle
+	titleIds := make([]string, 0, len(result.contentMap))
+	for titleId := range result.contentMap {
+		titleIds = append(titleIds, titleId)
+	}
+	sort.Strings(titleIds)
+	var switchFiles []SwitchFileInfo
+	for _, titleId := range titleIds {
+		metadata := result.contentMap[titleId]
+		switchFiles = append(switchFiles, SwitchFileInfo{ExtendedInfo: file, Metadata: metadata,
+			Split: result.split, Format: getFileFormat(file.ContentName(), result.split), SourceFileId: fileKey(file),
+			TitleName: nacpName(metadata), Languages: nacpLanguages(metadata)})
+	}
+	return switchFiles, skip
+}
 
-			//process Updates
-			if strings.HasSuffix(metadata.TitleId, "800") {
-				metadata.Type = "Update"
+// Group groups the parsed files into titles (base, updates and DLC). of several files holding the same content,
+// the preferred one is kept (see preferredFile) and the others are reported as duplicates, whatever the files order.
+// Group doesn't access the disk, and the returned skipped files only contain the grouping decisions
+// (duplicate and old files)
+func Group(files []SwitchFileInfo, opts GroupOptions) *LocalSwitchFilesDB {
+	titles := map[string]*SwitchGameFiles{}
+	skipped := map[ExtendedFileInfo]SkippedFile{}
 
-				if update, ok := switchTitle.Updates[metadata.Version]; ok {
-					skipped[file] = SkippedFile{ReasonCode: REASON_DUPLICATE, ReasonText: "duplicate update file (" + update.ExtendedInfo.FileName + ")"}
-					zap.S().Warnf("-->Duplicate update file found [%v] and [%v]", update.ExtendedInfo.FileName, file.FileName)
-					continue
-				}
-				switchTitle.Updates[metadata.Version] = SwitchFileInfo{ExtendedInfo: file, Metadata: metadata}
-				if metadata.Version > switchTitle.LatestUpdate {
-					if switchTitle.LatestUpdate != 0 {
-						skipped[switchTitle.Updates[switchTitle.LatestUpdate].ExtendedInfo] = SkippedFile{ReasonCode: REASON_OLD_UPDATE, ReasonText: "old update file, newer update exist locally"}
-					}
-					switchTitle.LatestUpdate = metadata.Version
-				} else {
-					skipped[file] = SkippedFile{ReasonCode: REASON_OLD_UPDATE, ReasonText: "old update file, newer update exist locally"}
+	//the preferred files come first, the contents of a multi-content file keep their order
+	files = append([]SwitchFileInfo(nil), files...)
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].ExtendedInfo == files[j].ExtendedInfo {
+			return false
+		}
+		return preferredFile(files[i], files[j], opts)
+	})
+
+	contentsPerFile := map[ExtendedFileInfo]int{}
+	for _, switchFileInfo := range files {
+		contentsPerFile[switchFileInfo.ExtendedInfo]++
+	}
+
+	//a multi-content file (e.g. base + update bundle) also holds current content, so it is never reported as old
+	markOld := func(file ExtendedFileInfo, reasonText string) {
+		if contentsPerFile[file] > 1 {
+			return
+		}
+		skipped[file] = SkippedFile{ReasonCode: REASON_OLD_UPDATE, ReasonText: reasonText}
+	}
+
+	for _, switchFileInfo := range files {
+		file := switchFileInfo.ExtendedInfo
+		isSplit := switchFileInfo.Split != nil || isSplitFile(opts.SplitSchemes, file.FileName)
+		//grouping sets the content type, work on a copy to leave the input untouched
+		metadataCopy := *switchFileInfo.Metadata
+		metadata := &metadataCopy
+		switchFileInfo.Metadata = metadata
+
+		key := groupingKey(metadata.TitleId)
+
+		multiContent := contentsPerFile[file] > 1
+		switchTitle := &SwitchGameFiles{
+			MultiContent: multiContent,
+			Updates:      map[int]SwitchFileInfo{},
+			Dlc:          map[string]SwitchFileInfo{},
+			BaseExist:    false,
+			IsSplit:      isSplit,
+			LatestUpdate: 0,
+		}
+		if t, ok := titles[key]; ok {
+			switchTitle = t
+		}
+		titles[key] = switchTitle
+
+		contentType, err := ClassifyContent(metadata.TitleId, metadata)
+		if err != nil {
+			//not a base or an update, treat it as a DLC
+			zap.S().Warnf("[file:%v] %v", file.FileName, err)
+			contentType = TITLE_TYPE_DLC.String()
+		}
+
+		//process Updates
+		if contentType == TITLE_TYPE_UPDATE.String() {
+			metadata.Type = contentType
+
+			if update, ok := switchTitle.Updates[metadata.Version]; ok {
+				skipped[file] = SkippedFile{ReasonCode: REASON_DUPLICATE, ReasonText: "duplicate update file (" + update.ExtendedInfo.FileName + ")"}
+				zap.S().Warnf("-->Duplicate update file found [%v] and [%v]", update.ExtendedInfo.FileName, file.FileName)
+				switchTitle.Duplicates = append(switchTitle.Duplicates, switchFileInfo)
+				continue
+			}
+			switchTitle.Updates[metadata.Version] = switchFileInfo
+			//superseded updates stay in Updates either way, KeepOldUpdates only drops the skipped entries
+			if metadata.Version > switchTitle.LatestUpdate {
+				if switchTitle.LatestUpdate != 0 && !opts.KeepOldUpdates {
+					markOld(switchTitle.Updates[switchTitle.LatestUpdate].ExtendedInfo, "old update file, newer update exist locally")
 				}
+				switchTitle.LatestUpdate = metadata.Version
+			} else if !opts.KeepOldUpdates {
+				markOld(file, "old update file, newer update exist locally")
+			}
+			continue
+		}
+
+		//process base
+		if contentType == TITLE_TYPE_BASE.String() {
+			metadata.Type = contentType
+			if switchTitle.BaseExist {
+				skipped[file] = SkippedFile{ReasonCode: REASON_DUPLICATE, ReasonText: "duplicate base file (" + switchTitle.File.ExtendedInfo.FileName + ")"}
+				zap.S().Warnf("-->Duplicate base file found [%v] and [%v]", file.FileName, switchTitle.File.ExtendedInfo.FileName)
+				switchTitle.Duplicates = append(switchTitle.Duplicates, switchFileInfo)
 				continue
 			}
+			switchTitle.File = switchFileInfo
+			switchTitle.BaseExist = true
+			//the title may have been created by a (loose) update, reflect the base file
+			switchTitle.MultiContent = switchTitle.MultiContent || multiContent
 
-			//process base
-			if strings.HasSuffix(metadata.TitleId, "000") {
-				metadata.Type = "Base"
-				if switchTitle.BaseExist {
-					skipped[file] = SkippedFile{ReasonCode: REASON_DUPLICATE, ReasonText: "duplicate base file (" + switchTitle.File.ExtendedInfo.FileName + ")"}
-					zap.S().Warnf("-->Duplicate base file found [%v] and [%v]", file.FileName, switchTitle.File.ExtendedInfo.FileName)
-					continue
-				}
-				switchTitle.File = SwitchFileInfo{ExtendedInfo: file, Metadata: metadata}
-				switchTitle.BaseExist = true
+			continue
+		}
 
+		if dlc, ok := switchTitle.Dlc[metadata.TitleId]; ok {
+			if metadata.Version < dlc.Metadata.Version {
+				markOld(file, "old DLC file, newer version exist locally")
+				zap.S().Warnf("-->Old DLC file found [%v] and [%v]", file.FileName, dlc.ExtendedInfo.FileName)
+				continue
+			} else if metadata.Version == dlc.Metadata.Version {
+				skipped[file] = SkippedFile{ReasonCode: REASON_DUPLICATE, ReasonText: "duplicate DLC file (" + dlc.ExtendedInfo.FileName + ")"}
+				zap.S().Warnf("-->Duplicate DLC file found [%v] and [%v]", file.FileName, dlc.ExtendedInfo.FileName)
+				switchTitle.Duplicates = append(switchTitle.Duplicates, switchFileInfo)
 				continue
 			}
+		}
+		//not an update, and not main TitleAttributes, so treat it as a DLC
+		metadata.Type = "DLC"
+		switchTitle.Dlc[metadata.TitleId] = switchFileInfo
+	}
 
-			if dlc, ok := switchTitle.Dlc[metadata.TitleId]; ok {
-				if metadata.Version < dlc.Metadata.Version {
-					skipped[file] = SkippedFile{ReasonCode: REASON_OLD_UPDATE, ReasonText: "old DLC file, newer version exist locally"}
-					zap.S().Warnf("-->Old DLC file found [%v] and [%v]", file.FileName, dlc.ExtendedInfo.FileName)
-					continue
-				} else if metadata.Version == dlc.Metadata.Version {
-					skipped[file] = SkippedFile{ReasonCode: REASON_DUPLICATE, ReasonText: "duplicate DLC file (" + dlc.ExtendedInfo.FileName + ")"}
-					zap.S().Warnf("-->Duplicate DLC file found [%v] and [%v]", file.FileName, dlc.ExtendedInfo.FileName)
-					continue
+	result := &LocalSwitchFilesDB{TitlesMap: titles, Skipped: skipped, NumFiles: len(contentsPerFile)}
+	ReconcileGroups(result, opts)
+	return result
+}
+
+// isSplitFile returns true for the first part of a split file (e.g. "00", ".part1")
+func isSplitFile(schemes switchfs.SplitSchemes, fileName string) bool {
+	part, ok := schemes.ParsePart(fileName)
+	return ok && part.IsFirst()
+}
+
+// readFilesMetadata reads the metadata of all the files using a pool of workers (CPU concurrency),
+// while the actual disk reads are limited separately (IO concurrency). onDone (optional) is called from
+// a single goroutine with the result of each task, as soon as it is read
+func (ldb *LocalSwitchDBManager) readFilesMetadata(tasks []scanTask, progress ProgressUpdater,
+	onDone func(i int, result scanResult)) []scanResult {
+	cpuConcurrency := settings.ReadSettings(ldb.baseFolder).ScanOptions.GetCPUConcurrency()
+	if ldb.maxConcurrency > 0 && cpuConcurrency > ldb.maxConcurrency {
+		cpuConcurrency = ldb.maxConcurrency
+	}
+
+	results := make([]scanResult, len(tasks))
+	taskQueue := make(chan int)
+	done := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < cpuConcurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range taskQueue {
+				task := tasks[i]
+				result := scanResult{}
+				result.contentMap, result.skip, result.err = ldb.getGameMetadata(task.file, task.filePath, task.fileType)
+				if result.err == nil && task.fileType == switchfs.FileType_SplitPart {
+					split, err := fileio.GetSplitFileInfo(ldb.splitSchemes, task.filePath)
+					if err != nil {
+						zap.S().Warnf("[file:%v] failed to read split file parts [reason: %v]", task.file.FileName, err)
+					} else if len(split.Warnings) != 0 {
+						zap.S().Warnf("[file:%v] inconsistent split file parts %v", task.file.FileName, split.Warnings)
+					}
+					result.split = split
 				}
+				results[i] = result
+				done <- i
 			}
-			//not an update, and not main TitleAttributes, so treat it as a DLC
-			metadata.Type = "DLC"
-			switchTitle.Dlc[metadata.TitleId] = SwitchFileInfo{ExtendedInfo: file, Metadata: metadata}
+		}()
+	}
+	go func() {
+		for i := range tasks {
+			taskQueue <- i
+		}
+		close(taskQueue)
+		wg.Wait()
+		close(done)
+	}()
+
+	//progress is reported from a single goroutine to keep it monotonic
+	bytesProgress, _ := progress.(ProgressUpdaterV2)
+	var sizes []int64
+	var totalBytes, doneBytes int64
+	if bytesProgress != nil {
+		sizes = make([]int64, len(tasks))
+		for i, task := range tasks {
+			sizes[i] = ldb.taskSize(task)
+			totalBytes += sizes[i]
+		}
+	}
+	start := time.Now()
+	ind := 0
+	for i := range done {
+		ind += 1
+		if onDone != nil {
+			onDone(i, results[i])
+		}
+		if bytesProgress != nil {
+			doneBytes += sizes[i]
+			eta := -1
+			if doneBytes > 0 {
+				elapsed := time.Since(start)
+				eta = int((time.Duration(float64(elapsed) * float64(totalBytes-doneBytes) / float64(doneBytes))).Seconds())
+			}
+			bytesProgress.UpdateBytes(doneBytes, totalBytes, eta)
+		}
+		if progress != nil {
+			progress.UpdateProgress(ind, len(tasks), "process:"+tasks[i].file.FileName)
 		}
 	}
+	return results
+}
 
+// taskSize returns the size of the file to read, all the parts are counted for split files
+func (ldb *LocalSwitchDBManager) taskSize(task scanTask) int64 {
+	if task.fileType != switchfs.FileType_SplitPart {
+		return task.file.Size
+	}
+	parts, _, err := ldb.splitSchemes.FileParts(task.filePath)
+	if err != nil {
+		return task.file.Size
+	}
+	size := int64(0)
+	for _, part := range parts {
+		if info, err := os.Stat(part); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
 }
 
 func (ldb *LocalSwitchDBManager) getGameMetadata(file ExtendedFileInfo,
-	filePath string,
-	skipped map[ExtendedFileInfo]SkippedFile) (map[string]*switchfs.ContentMetaAttributes, error) {
+	filePath string, fileType switchfs.FileType) (map[string]*switchfs.ContentMetaAttributes, *SkippedFile, error) {
 
 	var metadata map[string]*switchfs.ContentMetaAttributes = nil
+	var skip *SkippedFile = nil
+	cached := false
+	start := time.Now()
+	defer func() {
+		if cached {
+			atomic.AddInt64(&ldb.cacheStats.hits, 1)
+		} else {
+			atomic.AddInt64(&ldb.cacheStats.misses, 1)
+			atomic.AddInt64(&ldb.cacheStats.parseNanos, int64(time.Since(start)))
+		}
+	}()
+	//report the first parse error only, a file failing the deep parse may also fail the fallbacks
+	parseErrorReported := false
+	reportParseError := func(err error) {
+		if ldb.OnParseError != nil && !parseErrorReported {
+			parseErrorReported = true
+			ldb.OnParseError(file, err)
+		}
+	}
+	//invalid keys are reported once per scan (see checkKeys), files are then identified by their name
 	keys, _ := settings.SwitchKeys()
 	var err error
-	fileKey := filePath + "|" + file.FileName + "|" + strconv.Itoa(int(file.Size))
-	if keys != nil && keys.GetKey("header_key") != "" {
+	fileKey := fileCacheKey(file, filePath)
+	deepScan := !ldb.quickScan && keys != nil && keys.GetKey("header_key") != ""
+	//the cache only holds metadata read with keys, it is used whether the keys are (still) available or not
+	if !ldb.quickScan {
 		err = ldb.db.GetEntry(DB_TABLE_FILE_SCAN_METADATA, fileKey, &metadata)
 
 		if err != nil {
 			zap.S().Warnf("%v", err)
 		}
 
+		//unchanged file (same path, size and modification time), the file itself is not read
 		if metadata != nil {
-			return metadata, nil
+			cached = true
+			return metadata, nil, nil
 		}
-
-		fileName := strings.ToLower(file.FileName)
-		if strings.HasSuffix(fileName, "nsp") ||
-			strings.HasSuffix(fileName, "nsz") {
-			metadata, err = switchfs.ReadNspMetadata(filePath)
+	}
+	//the files which failed the deep read before are not read again, they go straight to the fallbacks
+	if deepScan {
+		skip = ldb.cachedFailure(fileKey)
+		cached = skip != nil
+	}
+	if deepScan && !cached {
+		if file.ArchiveEntry != "" {
+			metadata, err = ldb.readWithTimeout(func() (map[string]*switchfs.ContentMetaAttributes, error) {
+				return readArchiveEntryMetadata(filePath, file.ArchiveEntry, ldb.ioLimiter)
+			})
 			if err != nil {
-				skipped[file] = SkippedFile{ReasonCode: REASON_MALFORMED_FILE, ReasonText: fmt.Sprintf("failed to read NSP [reason: %v]", err)}
-				zap.S().Errorf("[file:%v] failed to read NSP [reason: %v]\n", file.FileName, err)
+				reportParseError(err)
+				if unreadable := unreadableSkip(err); unreadable != nil {
+					return nil, unreadable, err
+				}
+				skip = readFailureSkip(err, fmt.Sprintf("failed to read %v in archive [reason: %v]", strings.ToUpper(fileType.String()), err))
+				zap.S().Errorf("[file:%v] failed to read %v in archive [reason: %v]\n", file.ContentName(), strings.ToUpper(fileType.String()), err)
 			}
-		} else if strings.HasSuffix(fileName, "xci") ||
-			strings.HasSuffix(fileName, "xcz") {
-			metadata, err = switchfs.ReadXciMetadata(filePath)
+		} else if fileType.IsNsp() {
+			metadata, err = ldb.readWithTimeout(func() (map[string]*switchfs.ContentMetaAttributes, error) {
+				return ldb.readMetadata(filePath, switchfs.ReadNspMetadataFrom)
+			})
 			if err != nil {
-				skipped[file] = SkippedFile{ReasonCode: REASON_MALFORMED_FILE, ReasonText: fmt.Sprintf("failed to read NSP [reason: %v]", err)}
-				zap.S().Errorf("[file:%v] failed to read file [reason: %v]\n", file.FileName, err)
+				reportParseError(err)
+				if unreadable := unreadableSkip(err); unreadable != nil {
+					return nil, unreadable, err
+				}
+				skip = readFailureSkip(err, fmt.Sprintf("failed to read %v [reason: %v]", strings.ToUpper(fileType.String()), err))
+				zap.S().Errorf("[file:%v] failed to read %v [reason: %v]\n", file.FileName, strings.ToUpper(fileType.String()), err)
 			}
-		} else if strings.HasSuffix(fileName, "00") {
-			metadata, err = fileio.ReadSplitFileMetadata(filePath)
+		} else if fileType.IsXci() {
+			metadata, err = ldb.readWithTimeout(func() (map[string]*switchfs.ContentMetaAttributes, error) {
+				return ldb.readMetadata(filePath, switchfs.ReadXciMetadataFrom)
+			})
 			if err != nil {
-				skipped[file] = SkippedFile{ReasonCode: REASON_MALFORMED_FILE, ReasonText: fmt.Sprintf("failed to read split files [reason: %v]", err)}
-				zap.S().Errorf("[file:%v] failed to read NSP [reason: %v]\n", file.FileName, err)
+				reportParseError(err)
+				if unreadable := unreadableSkip(err); unreadable != nil {
+					return nil, unreadable, err
+				}
+				skip = readFailureSkip(err, fmt.Sprintf("failed to read %v [reason: %v]", strings.ToUpper(fileType.String()), err))
+				zap.S().Errorf("[file:%v] failed to read %v [reason: %v]\n", file.FileName, strings.ToUpper(fileType.String()), err)
+			}
+		} else if fileType == switchfs.FileType_SplitPart {
+			metadata, err = ldb.readWithTimeout(func() (map[string]*switchfs.ContentMetaAttributes, error) {
+				splitMetadata, err := fileio.ReadSplitFileMetadata(ldb.splitSchemes, filePath, ldb.ioLimiter)
+				if err != nil {
+					return nil, err
+				}
+				return splitMetadata.Metadata, nil
+			})
+			if err != nil {
+				reportParseError(err)
+				if unreadable := unreadableSkip(err); unreadable != nil {
+					return nil, unreadable, err
+				}
+				skip = readFailureSkip(err, fmt.Sprintf("failed to read split file [reason: %v]", err))
+				zap.S().Errorf("[file:%v] failed to read split file [reason: %v]\n", file.FileName, err)
 			}
 		}
+		ldb.cacheFailure(fileKey, skip)
 	}
 
 	if metadata != nil {
-		err = ldb.db.AddEntry(DB_TABLE_FILE_SCAN_METADATA, fileKey, metadata)
+		if !ldb.db.ReadOnly() {
+			err = ldb.db.AddEntry(DB_TABLE_FILE_SCAN_METADATA, fileKey, metadata)
 
-		if err != nil {
-			zap.S().Warnf("%v", err)
+			if err != nil {
+				zap.S().Warnf("%v", err)
+			}
+		}
+		return metadata, skip, nil
+	}
+
+	//without keys, a NSP may still hold a plain .cnmt.xml (not cached, it isn't verified)
+	if !ldb.quickScan && !deepScan && file.ArchiveEntry == "" && fileType.IsNsp() {
+		xmlMetadata, xmlErr := ldb.readWithTimeout(func() (map[string]*switchfs.ContentMetaAttributes, error) {
+			return ldb.readMetadata(filePath, switchfs.ReadNspCnmtXmlFrom)
+		})
+		if xmlErr == nil {
+			return xmlMetadata, skip, nil
 		}
-		return metadata, nil
+		if unreadable := unreadableSkip(xmlErr); unreadable != nil {
+			return nil, unreadable, xmlErr
+		}
+		zap.S().Debugf("[file:%v] no cnmt.xml in the NSP [reason: %v]", file.FileName, xmlErr)
 	}
 
-	//fallback to parse data from filename
+	//fallback to a .cnmt.xml sidecar (not for archive entries, the sidecar would be found for each entry)
+	if sidecar := findCnmtXmlSidecar(filePath); sidecar != "" && file.ArchiveEntry == "" {
+		cnmt, xmlErr := switchfs.ReadCnmtXmlFile(sidecar)
+		if xmlErr == nil {
+			return map[string]*switchfs.ContentMetaAttributes{cnmt.TitleId: cnmt}, skip, nil
+		}
+		reportParseError(xmlErr)
+		zap.S().Warnf("[file:%v] failed to read cnmt.xml sidecar [reason: %v]\n", file.FileName, xmlErr)
+	}
 
-	//parse title id
-	titleId, _ := parseTitleIdFromFileName(file.FileName)
-	version, _ := parseVersionFromFileName(file.FileName)
+	//fallback to parse data from filename, the parts stored in a folder of their own are named after the folder
+	name := file.ContentName()
+	if part, ok := ldb.splitSchemes.ParsePart(name); ok && fileType == switchfs.FileType_SplitPart && part.InFolder() {
+		name = filepath.Base(filepath.Clean(file.BaseFolder))
+	}
 
-	if titleId == nil || version == nil {
-		return nil, errors.New("unable to determine titileId / version")
+	//parse title id
+	titleId, err := ldb.fileNameParser.parseTitleId(name)
+	if err != nil {
+		reportParseError(err)
+		return nil, skip, err
+	}
+	version, err := ldb.fileNameParser.parseVersion(name)
+	if err != nil {
+		reportParseError(err)
+		return nil, skip, err
 	}
 	metadata = map[string]*switchfs.ContentMetaAttributes{}
-	metadata[*titleId] = &switchfs.ContentMetaAttributes{TitleId: *titleId, Version: *version}
+	metadata[*titleId] = &switchfs.ContentMetaAttributes{TitleId: *titleId, Version: *version, Source: switchfs.MetadataSource_FileName}
 
-	return metadata, nil
+	return metadata, skip, nil
 }
 
-func parseVersionFromFileName(fileName string) (*int, error) {
-	res := versionRegex.FindStringSubmatch(fileName)
-	if len(res) != 2 {
-		return nil, errors.New("failed to parse name - no version id found")
-	}
-	ver, err := strconv.Atoi(res[1])
+// readArchiveEntryMetadata reads the metadata of a NSP/NSZ stored in a zip archive, without extracting it
+func readArchiveEntryMetadata(archivePath string, entry string, limiter *switchfs.IOLimiter) (map[string]*switchfs.ContentMetaAttributes, error) {
+	reader, size, err := switchfs.OpenZipEntry(archivePath, entry)
 	if err != nil {
-		return nil, errors.New("failed to parse name - no version id found")
+		return nil, err
 	}
-	return &ver, nil
+	defer reader.Close()
+	return switchfs.ReadNspMetadataFrom(limiter.Limit(reader), size)
 }
 
-func parseTitleIdFromFileName(fileName string) (*string, error) {
-	res := titleIdRegex.FindStringSubmatch(fileName)
+// fileCacheKey returns the metadata cache key of the file, a file modified in place gets a new key
+func fileCacheKey(file ExtendedFileInfo, filePath string) string {
+	key := filePath + "|" + file.FileName + "|" + strconv.Itoa(int(file.Size)) + "|" + strconv.FormatInt(file.ModTime.UnixNano(), 10)
+	if file.ArchiveEntry != "" {
+		key += "|" + file.ArchiveEntry
+	}
+	return key
+}
 
-	if len(res) != 2 {
-		return nil, errors.New("failed to parse name - no title id found")
+// findCnmtXmlSidecar looks for a .cnmt.xml next to the file (or inside a folder with the same name as the file)
+func findCnmtXmlSidecar(filePath string) string {
+	basePath := strings.TrimSuffix(filePath, filepath.Ext(filePath))
+	for _, candidate := range []string{basePath + ".cnmt.xml", filePath + ".cnmt.xml"} {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	if info, err := os.Stat(basePath); err == nil && info.IsDir() {
+		matches, _ := filepath.Glob(filepath.Join(basePath, "*.cnmt.xml"))
+		if len(matches) > 0 {
+			sort.Strings(matches)
+			return matches[0]
+		}
+	}
+	return ""
+}
+
+func getFileFormat(fileName string, split *fileio.SplitFileInfo) string {
+	if split != nil {
+		return split.Format
 	}
-	titleId := strings.ToLower(res[1])
-	return &titleId, nil
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if len(ext) > 1 {
+		return ext[1:]
+	}
+	return ""
+}
+
+func validateContentMap(contentMap map[string]*switchfs.ContentMetaAttributes) error {
+	for _, metadata := range contentMap {
+		if err := validateTitleId(metadata.TitleId); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func ParseTitleNameFromFileName(fileName string) string {
@@ -0,0 +1,728 @@
+package db
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCreateLocalSwitchFilesDBOverlappingFolders(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+
+	fileNames := []string{
+		"Super Mario Odyssey [0100000000010000][v0].nsp",
+		"Super Mario Odyssey [0100000000010800][v65536].nsp",
+	}
+	for _, fileName := range fileNames {
+		if err := ioutil.WriteFile(filepath.Join(gamesFolder, fileName), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	//the same folder twice (and once more through a relative path)
+	relative, err := filepath.Rel(mustGetwd(t), gamesFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	localDB, err := manager.CreateLocalSwitchFilesDB([]string{gamesFolder, gamesFolder, relative}, nil, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if localDB.NumFiles != len(fileNames) {
+		t.Errorf("expected %v files, got %v", len(fileNames), localDB.NumFiles)
+	}
+	title, ok := localDB.TitlesMap["0100000000010000"]
+	if !ok || !title.BaseExist || len(title.Updates) != 1 {
+		t.Fatalf("expected a title with a base and an update, got %+v", title)
+	}
+	if len(title.Duplicates) != 0 {
+		t.Errorf("expected no duplicates, got %v", title.Duplicates)
+	}
+	for file, skipped := range localDB.Skipped {
+		t.Errorf("unexpected skipped file %v - %v", file.FileName, skipped.ReasonText)
+	}
+}
+
+func TestNormalizeFolders(t *testing.T) {
+	games := filepath.Join(mustGetwd(t), "games")
+	sub := filepath.Join(games, "sub")
+	deep := filepath.Join(games, "a", "b")
+	other := filepath.Join(mustGetwd(t), "games2")
+	folders := []string{sub, games + string(os.PathSeparator), "games", other, deep, filepath.Join(games, ".", "sub")}
+
+	if got, expected := normalizeFolders(folders, true, -1), []string{games, other}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("recursive - expected %v, got %v", expected, got)
+	}
+	//sub-folders beyond the folder depth are not walked as part of the parent
+	if got, expected := normalizeFolders(folders, true, 1), []string{games, other, deep}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("folder depth 1 - expected %v, got %v", expected, got)
+	}
+	if got, expected := normalizeFolders(folders, false, -1), []string{sub, games, other, deep}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("not recursive - expected %v, got %v", expected, got)
+	}
+}
+
+func TestSplitFolderParts(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+
+	//NAND backup style parts numbered from 1, named after their folder
+	partsFolder := filepath.Join(gamesFolder, "Zelda [0100000000020000][v0]")
+	if err := os.Mkdir(partsFolder, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"01", "02", "03"} {
+		data := make([]byte, 0x400)
+		if i == 0 {
+			//PFS0 header with no files
+			copy(data, "PFS0")
+		}
+		if err := ioutil.WriteFile(filepath.Join(partsFolder, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+	localDB, err := manager.CreateLocalSwitchFilesDB([]string{gamesFolder}, nil, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(localDB.Skipped) != 0 {
+		t.Errorf("expected no skipped parts, got %v", localDB.Skipped)
+	}
+	title, ok := localDB.TitlesMap["0100000000020000"]
+	if !ok || !title.BaseExist || !title.IsSplit {
+		t.Fatalf("expected a split base file, got %+v", localDB.TitlesMap)
+	}
+	if split := title.File.Split; split == nil || split.NumParts != 3 || split.TotalSize != 3*0x400 {
+		t.Errorf("expected 3 parts of 0x400 bytes, got %+v", split)
+	}
+	if title.File.ExtendedInfo.FileName != "01" {
+		t.Errorf("expected the first part to represent the file, got %v", title.File.ExtendedInfo.FileName)
+	}
+}
+
+func TestOnParseError(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+
+	fileNames := []string{
+		"Super Mario Odyssey [0100000000010000][v0].nsp",
+		"Untagged game.nsp",
+	}
+	for _, fileName := range fileNames {
+		if err := ioutil.WriteFile(filepath.Join(gamesFolder, fileName), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+	var lock sync.Mutex
+	failures := map[string]int{}
+	manager.OnParseError = func(file ExtendedFileInfo, err error) {
+		lock.Lock()
+		defer lock.Unlock()
+		if err == nil {
+			t.Errorf("expected an error for %v", file.FileName)
+		}
+		failures[file.FileName]++
+	}
+
+	_, err = manager.CreateLocalSwitchFilesDB([]string{gamesFolder}, nil, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) != 1 || failures["Untagged game.nsp"] != 1 {
+		t.Errorf("expected a single parse error for the untagged file, got %v", failures)
+	}
+}
+
+func TestUpdateLocalSwitchFilesDB(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+	updatesFolder, err := ioutil.TempDir("", "slm-updates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(updatesFolder)
+
+	files := map[string]string{
+		"Super Mario Odyssey [0100000000010000][v0].nsp":      gamesFolder,
+		"Super Mario Odyssey [0100000000010800][v65536].nsp":  gamesFolder,
+		"Super Mario Odyssey [0100000000010800][v131072].nsp": updatesFolder,
+		"Zelda [0100000000020800][v65536].nsp":                updatesFolder,
+	}
+	for fileName, folder := range files {
+		if err := ioutil.WriteFile(filepath.Join(folder, fileName), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	localDB, err := manager.CreateLocalSwitchFilesDB([]string{gamesFolder}, nil, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the already scanned folder is not scanned twice
+	localDB, err = manager.UpdateLocalSwitchFilesDB(localDB, []string{updatesFolder, gamesFolder}, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if localDB.NumFiles != len(files) {
+		t.Errorf("expected %v files, got %v", len(files), localDB.NumFiles)
+	}
+	title, ok := localDB.TitlesMap["0100000000010000"]
+	if !ok || !title.BaseExist || len(title.Updates) != 2 || title.LatestUpdate != 131072 {
+		t.Fatalf("expected a title with a base and 2 updates (latest v131072), got %+v", title)
+	}
+	if len(title.Duplicates) != 0 {
+		t.Errorf("expected no duplicates, got %v", title.Duplicates)
+	}
+	if _, ok := localDB.TitlesMap["0100000000020000"]; !ok {
+		t.Errorf("expected the title of the new folder to be added")
+	}
+	if len(localDB.Skipped) != 1 {
+		t.Fatalf("expected a single skipped file, got %v", localDB.Skipped)
+	}
+	for file, skipped := range localDB.Skipped {
+		if file.FileName != "Super Mario Odyssey [0100000000010800][v65536].nsp" || skipped.ReasonCode != REASON_OLD_UPDATE {
+			t.Errorf("expected the previous update to be skipped as old, got %v - %v", file.FileName, skipped.ReasonText)
+		}
+	}
+
+	//the result matches a full scan of both folders
+	fullDB, err := manager.CreateLocalSwitchFilesDB([]string{gamesFolder, updatesFolder}, nil, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fullDB.TitlesMap) != len(localDB.TitlesMap) || len(fullDB.Skipped) != len(localDB.Skipped) ||
+		fullDB.TitlesMap["0100000000010000"].LatestUpdate != title.LatestUpdate {
+		t.Errorf("expected the merged library to match a full scan, got %+v and %+v", localDB, fullDB)
+	}
+}
+
+func TestFileCacheKey(t *testing.T) {
+	file := ExtendedFileInfo{FileName: "game.nsp", BaseFolder: "/games/", Size: 100, ModTime: time.Unix(1000, 0)}
+	key := fileCacheKey(file, "/games/game.nsp")
+	if key != fileCacheKey(file, "/games/game.nsp") {
+		t.Errorf("expected a stable key")
+	}
+	modified := file
+	modified.ModTime = time.Unix(2000, 0)
+	if key == fileCacheKey(modified, "/games/game.nsp") {
+		t.Errorf("expected a file modified in place to get a new key")
+	}
+}
+
+func TestNewLocalSwitchDBManagerLocked(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	//the DB file is locked by the first manager
+	if _, err := NewLocalSwitchDBManager(baseFolder); err == nil {
+		t.Errorf("expected an error opening a locked DB")
+	}
+}
+
+func TestNewLocalSwitchDBManagerReadOnly(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+	if err := ioutil.WriteFile(filepath.Join(gamesFolder, "Zelda [0100000000020000][v0].nsp"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewLocalSwitchDBManagerReadOnly(baseFolder); err == nil {
+		t.Errorf("expected an error opening a missing DB read-only")
+	}
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.CreateLocalSwitchFilesDB([]string{gamesFolder}, nil, true, true); err != nil {
+		t.Fatal(err)
+	}
+	//the DB file is locked for writing
+	if _, err := NewLocalSwitchDBManagerReadOnly(baseFolder); err == nil {
+		t.Errorf("expected an error opening a DB locked for writing")
+	}
+	manager.Close()
+
+	//several readers can share the DB
+	reader, err := NewLocalSwitchDBManagerReadOnly(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	otherReader, err := NewLocalSwitchDBManagerReadOnly(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer otherReader.Close()
+
+	if !reader.ReadOnly() {
+		t.Errorf("expected a read-only manager")
+	}
+	meta, err := reader.LastScanInfo()
+	if err != nil || meta.NumTitles != 1 {
+		t.Errorf("expected the scan info to be readable, got %+v (%v)", meta, err)
+	}
+	localDB, err := reader.CreateLocalSwitchFilesDB([]string{gamesFolder}, nil, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(localDB.TitlesMap) != 1 {
+		t.Errorf("expected the cached library, got %v", localDB.TitlesMap)
+	}
+	//a full rescan doesn't write either
+	if _, err := reader.CreateLocalSwitchFilesDB([]string{gamesFolder}, nil, true, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := reader.ClearScanData(); err != ErrReadOnly {
+		t.Errorf("expected ErrReadOnly clearing the scan data, got %v", err)
+	}
+	if err := reader.db.AddEntry(DB_TABLE_LOCAL_LIBRARY, "files", nil); err != ErrReadOnly {
+		t.Errorf("expected ErrReadOnly adding an entry, got %v", err)
+	}
+}
+
+func TestCachedLibraryKeepOldUpdates(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+	for _, fileName := range []string{
+		"Super Mario Odyssey [0100000000010000][v0].nsp",
+		"Super Mario Odyssey [0100000000010800][v65536].nsp",
+		"Super Mario Odyssey [0100000000010800][v131072].nsp",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(gamesFolder, fileName), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	localDB, err := manager.CreateLocalSwitchFilesDB([]string{gamesFolder}, nil, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(localDB.Skipped) != 1 {
+		t.Fatalf("expected the old update to be skipped, got %v", localDB.Skipped)
+	}
+
+	//the cached library follows the current setting
+	manager.SetKeepOldUpdates(true)
+	localDB, err = manager.CreateLocalSwitchFilesDB([]string{gamesFolder}, nil, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	title := localDB.TitlesMap["0100000000010000"]
+	if len(localDB.Skipped) != 0 || len(title.Updates) != 2 || title.LatestUpdate != 131072 {
+		t.Errorf("expected both updates kept with v131072 active, got %v (skipped %v)", title.Updates, localDB.Skipped)
+	}
+
+	manager.SetKeepOldUpdates(false)
+	localDB, err = manager.CreateLocalSwitchFilesDB([]string{gamesFolder}, nil, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file, skip := range localDB.Skipped {
+		if skip.ReasonCode != REASON_OLD_UPDATE || file.FileName != "Super Mario Odyssey [0100000000010800][v65536].nsp" {
+			t.Errorf("unexpected skipped file %v - %+v", file.FileName, skip)
+		}
+	}
+	if len(localDB.Skipped) != 1 {
+		t.Errorf("expected the old update to be skipped again, got %v", localDB.Skipped)
+	}
+}
+
+func TestPruneMissing(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+
+	filePath := filepath.Join(gamesFolder, "game.nsp")
+	if err := ioutil.WriteFile(filePath, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := newExtendedFileInfo(gamesFolder, filePath, info)
+	modified := current
+	modified.ModTime = current.ModTime.Add(-time.Hour)
+	deleted := ExtendedFileInfo{FileName: "deleted.nsp", Size: 10}
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+	keys := []string{fileCacheKey(current, filePath), fileCacheKey(modified, filePath),
+		fileCacheKey(deleted, filepath.Join(gamesFolder, "deleted.nsp")),
+		fileCacheKey(deleted, filepath.Join(baseFolder, "other", "deleted.nsp"))}
+	for _, key := range keys {
+		if err := manager.db.AddEntry(DB_TABLE_FILE_SCAN_METADATA, key, "metadata"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//only the entries below the games folder are pruned
+	removed, err := manager.PruneMissing([]string{gamesFolder})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("expected 2 removed entries, got %v", removed)
+	}
+	var remaining []string
+	manager.db.ForEachEntry(DB_TABLE_FILE_SCAN_METADATA, func(key string, decode func(interface{}) error) error {
+		remaining = append(remaining, key)
+		return nil
+	})
+	if len(remaining) != 2 || remaining[0] != keys[0] && remaining[1] != keys[0] {
+		t.Errorf("expected the current and the unrelated entries to remain, got %v", remaining)
+	}
+}
+
+func TestScanFolderFollowSymlinks(t *testing.T) {
+	root, err := ioutil.TempDir("", "slm-symlinks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	//storage/game.nsp, library/games -> storage, library/games/loop -> library, library/file.nsp -> storage/game.nsp
+	storage := filepath.Join(root, "storage")
+	library := filepath.Join(root, "library")
+	for _, folder := range []string{storage, library} {
+		if err := os.Mkdir(folder, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(storage, "game.nsp"), []byte("game data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		filepath.Join(library, "games"):    storage,
+		filepath.Join(storage, "loop"):     library,
+		filepath.Join(library, "file.nsp"): filepath.Join(storage, "game.nsp"),
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("symlinks are not supported - %v", err)
+		}
+	}
+
+	var files []ExtendedFileInfo
+	_, err = scanFolder(library, true, true, false, scanFilter{skipDotFiles: true}, &files, nil, scanLimits{maxDepth: 64, maxFiles: 100, folderDepth: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected the linked file and the file of the linked folder, got %+v", files)
+	}
+	for _, file := range files {
+		if file.Size != int64(len("game data")) {
+			t.Errorf("expected %v to have the size of its target, got %v", file.FileName, file.Size)
+		}
+	}
+
+	files = nil
+	_, err = scanFolder(library, true, false, false, scanFilter{skipDotFiles: true}, &files, nil, scanLimits{maxDepth: 64, maxFiles: 100, folderDepth: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		if file.FileName == "game.nsp" {
+			t.Errorf("expected the linked folder not to be walked without following symlinks")
+		}
+	}
+}
+
+func TestProcessLocalFilesShortNames(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	files := []ExtendedFileInfo{
+		{FileName: "a", BaseFolder: baseFolder + string(os.PathSeparator), Size: 1},
+		{FileName: "", BaseFolder: baseFolder + string(os.PathSeparator), Size: 1},
+	}
+	titles := map[string]*SwitchGameFiles{}
+	skipped := map[ExtendedFileInfo]SkippedFile{}
+	manager.processLocalFiles(files, nil, titles, skipped)
+	if len(titles) != 0 {
+		t.Errorf("expected no titles, got %v", titles)
+	}
+	for _, file := range files {
+		if skip, ok := skipped[file]; !ok || skip.ReasonCode != REASON_UNSUPPORTED_TYPE {
+			t.Errorf("expected [%v] to be skipped as unsupported, got %+v", file.FileName, skip)
+		}
+	}
+}
+
+func TestScanFolderErrors(t *testing.T) {
+	root, err := ioutil.TempDir("", "slm-errors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	if err := ioutil.WriteFile(filepath.Join(root, "game.nsp"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var files []ExtendedFileInfo
+	scanErrors, err := scanFolder(filepath.Join(root, "missing"), true, false, false, scanFilter{skipDotFiles: true}, &files, nil, scanLimits{maxDepth: 64, maxFiles: 100, folderDepth: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scanErrors) != 1 || scanErrors[0].Path != filepath.Join(root, "missing") {
+		t.Errorf("expected the missing folder to be reported, got %v", scanErrors)
+	}
+
+	locked := filepath.Join(root, "locked")
+	if err := os.Mkdir(locked, 0000); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(locked, 0755)
+	if _, err := ioutil.ReadDir(locked); err == nil {
+		t.Skip("unable to create an unreadable folder (running as root?)")
+	}
+	files = nil
+	scanErrors, err = scanFolder(root, true, false, false, scanFilter{skipDotFiles: true}, &files, nil, scanLimits{maxDepth: 64, maxFiles: 100, folderDepth: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scanErrors) != 1 || scanErrors[0].Path != locked {
+		t.Errorf("expected the unreadable folder to be reported, got %v", scanErrors)
+	}
+	if len(files) != 1 {
+		t.Errorf("expected the scan to go on past the unreadable folder, got %+v", files)
+	}
+}
+
+func TestScanZipArchives(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+
+	archives := map[string][]string{
+		"Super Mario Odyssey.zip": {"Super Mario Odyssey [0100000000010000][v0].nsp",
+			"updates/Super Mario Odyssey [0100000000010800][v65536].nsp", "readme.txt"},
+		"docs.zip": {"readme.txt"},
+	}
+	for name, entries := range archives {
+		archive, err := os.Create(filepath.Join(gamesFolder, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		writer := zip.NewWriter(archive)
+		for _, entry := range entries {
+			w, err := writer.Create(entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write([]byte("data"))
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+		archive.Close()
+	}
+
+	var files []ExtendedFileInfo
+	_, err = scanFolder(gamesFolder, true, false, true, scanFilter{skipDotFiles: true}, &files, nil, scanLimits{maxDepth: 64, maxFiles: 100, folderDepth: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the NSP entries of the first archive, the second archive is listed as is
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %+v", files)
+	}
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	titles := map[string]*SwitchGameFiles{}
+	skipped := map[ExtendedFileInfo]SkippedFile{}
+	manager.processLocalFiles(uniqueFiles(files), nil, titles, skipped)
+	title, ok := titles["0100000000010000"]
+	if !ok || !title.BaseExist || len(title.Updates) != 1 {
+		t.Fatalf("expected a title with a base and an update read from the archive, got %+v", title)
+	}
+	if len(skipped) != 1 {
+		t.Fatalf("expected the archive without NSP files to be skipped, got %v", skipped)
+	}
+	for file, skip := range skipped {
+		if file.FileName != "docs.zip" || skip.ReasonCode != REASON_UNSUPPORTED_TYPE {
+			t.Errorf("unexpected skipped file %v - %v", file.FileName, skip.ReasonText)
+		}
+	}
+
+	localDB := &LocalSwitchFilesDB{TitlesMap: titles, Skipped: skipped}
+	update := title.Updates[65536].ExtendedInfo
+	expectedPath := filepath.Join(gamesFolder, "Super Mario Odyssey.zip", "updates", "Super Mario Odyssey [0100000000010800][v65536].nsp")
+	if localDB.FilePath(update) != expectedPath {
+		t.Errorf("expected the entry path %v, got %v", expectedPath, localDB.FilePath(update))
+	}
+	archivePath := filepath.Join(gamesFolder, "Super Mario Odyssey.zip")
+	if fileCacheKey(title.File.ExtendedInfo, archivePath) == fileCacheKey(update, archivePath) {
+		t.Errorf("expected the archive entries to have distinct cache keys")
+	}
+}
+
+func mustGetwd(t *testing.T) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wd
+}
+
+func TestScanFolderDepth(t *testing.T) {
+	root, err := ioutil.TempDir("", "slm-depth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	for _, name := range []string{"top.nsp", "a/one.nsp", "a/b/two.nsp", "a/b/c/three.nsp"} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for folderDepth, expected := range map[int]int{0: 1, 1: 2, 2: 3, -1: 4} {
+		var files []ExtendedFileInfo
+		_, err := scanFolder(root, true, false, false, scanFilter{}, &files, nil, scanLimits{maxDepth: 64, maxFiles: 100, folderDepth: folderDepth})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != expected {
+			t.Errorf("expected %v files with folder depth %v, got %v", expected, folderDepth, files)
+		}
+	}
+
+	//the abort limit still applies within the folder depth
+	var files []ExtendedFileInfo
+	if _, err := scanFolder(root, true, false, false, scanFilter{}, &files, nil, scanLimits{maxDepth: 1, maxFiles: 100, folderDepth: -1}); err == nil {
+		t.Errorf("expected the scan to be aborted beyond the max depth")
+	}
+}
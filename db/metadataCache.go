@@ -0,0 +1,79 @@
+package db
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MetadataCache is the key/value store behind the scan caches (see PersistentDB). Keys are made of the table name
+// and the entry key ("<table>/<key>"), values are opaque.
+// BoltCache stores the entries in a file, InMemoryCache keeps them for the lifetime of the process
+type MetadataCache interface {
+	//returns false when the key doesn't exist
+	Get(key string) ([]byte, bool)
+	Put(key string, val []byte) error
+	Delete(key string) error
+	//calls fn for each entry whose key starts with the prefix, the cache must not be modified from fn
+	ForEach(prefix string, fn func(key string, val []byte) error) error
+}
+
+// batchCache is implemented by caches able to apply several operations at once (e.g. in a single transaction)
+type batchCache interface {
+	Batch(fn func(cache MetadataCache) error) error
+}
+
+// InMemoryCache is a MetadataCache for tests and ephemeral runs, safe for concurrent use
+type InMemoryCache struct {
+	lock    sync.RWMutex
+	entries map[string][]byte
+}
+
+func NewInMemoryCache() *InMemoryCache {
+	return &InMemoryCache{entries: map[string][]byte{}}
+}
+
+func (c *InMemoryCache) Get(key string) ([]byte, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	val, ok := c.entries[key]
+	return val, ok
+}
+
+func (c *InMemoryCache) Put(key string, val []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries[key] = append([]byte{}, val...)
+	return nil
+}
+
+func (c *InMemoryCache) Delete(key string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.entries, key)
+	return nil
+}
+
+// ForEach iterates the entries in key order
+func (c *InMemoryCache) ForEach(prefix string, fn func(key string, val []byte) error) error {
+	c.lock.RLock()
+	keys := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = c.entries[key]
+	}
+	c.lock.RUnlock()
+
+	for i, key := range keys {
+		if err := fn(key, values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
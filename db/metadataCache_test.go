@@ -0,0 +1,143 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func testPersistentDB(t *testing.T, db *PersistentDB) {
+	if err := db.AddEntry("table1", "/games/a.nsp", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddEntries("table1", map[string]interface{}{"/games/b.nsp": 2, "/games/c.nsp": 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddEntry("table2", "/games/a.nsp", 4); err != nil {
+		t.Fatal(err)
+	}
+
+	var value int
+	if err := db.GetEntry("table2", "/games/a.nsp", &value); err != nil || value != 4 {
+		t.Errorf("expected 4, got %v (%v)", value, err)
+	}
+	value = 0
+	if err := db.GetEntry("table2", "/games/missing.nsp", &value); err != nil || value != 0 {
+		t.Errorf("expected a missing entry to be ignored, got %v (%v)", value, err)
+	}
+
+	entries := func(table string) map[string]int {
+		result := map[string]int{}
+		err := db.ForEachEntry(table, func(key string, decode func(interface{}) error) error {
+			var value int
+			if err := decode(&value); err != nil {
+				return err
+			}
+			result[key] = value
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	if got := entries("table1"); !reflect.DeepEqual(got, map[string]int{"/games/a.nsp": 1, "/games/b.nsp": 2, "/games/c.nsp": 3}) {
+		t.Errorf("unexpected table1 entries %v", got)
+	}
+
+	deleted, err := db.DeleteEntries("table1", func(key string) bool {
+		return key != "/games/b.nsp"
+	})
+	if err != nil || deleted != 2 {
+		t.Errorf("expected 2 deleted entries, got %v (%v)", deleted, err)
+	}
+	if got := entries("table1"); !reflect.DeepEqual(got, map[string]int{"/games/b.nsp": 2}) {
+		t.Errorf("unexpected table1 entries after delete %v", got)
+	}
+
+	if err := db.ClearTable("table2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := entries("table2"); len(got) != 0 {
+		t.Errorf("expected table2 to be cleared, got %v", got)
+	}
+	if got := entries("table1"); len(got) != 1 {
+		t.Errorf("expected table1 to be left untouched, got %v", got)
+	}
+}
+
+func TestPersistentDBInMemory(t *testing.T) {
+	db, err := NewPersistentDBWithCache(NewInMemoryCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testPersistentDB(t, db)
+}
+
+func TestPersistentDBBolt(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-bolt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	db, err := NewPersistentDB(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	testPersistentDB(t, db)
+}
+
+func TestInMemoryCacheForEach(t *testing.T) {
+	cache := NewInMemoryCache()
+	for _, key := range []string{"b/2", "a/1", "b/1", "c"} {
+		if err := cache.Put(key, []byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var keys []string
+	err := cache.ForEach("b/", func(key string, val []byte) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sort.StringsAreSorted(keys) || !reflect.DeepEqual(keys, []string{"b/1", "b/2"}) {
+		t.Errorf("unexpected keys %v", keys)
+	}
+}
+
+func TestLocalSwitchDBManagerInMemoryCache(t *testing.T) {
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+	if err := ioutil.WriteFile(filepath.Join(gamesFolder, "Game [0100000000010000][v0].nsp"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	//the base folder is not used for the cache
+	manager, err := NewLocalSwitchDBManagerWithCache(gamesFolder, NewInMemoryCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+	if _, err := os.Stat(filepath.Join(gamesFolder, "slm.db")); !os.IsNotExist(err) {
+		t.Errorf("expected no DB file to be created")
+	}
+	if _, err := manager.CreateLocalSwitchFilesDB([]string{gamesFolder}, nil, true, false); err != nil {
+		t.Fatal(err)
+	}
+	localDB, err := manager.CreateLocalSwitchFilesDB([]string{gamesFolder}, nil, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if localDB.CacheHits != 1 {
+		t.Errorf("expected the library to be served from the cache, got %+v", localDB)
+	}
+}
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"strconv"
+	"strings"
+)
+
+const (
+	//version of the stored tables format, independent of the application version - bump it (and register
+	//a migration) whenever the keys or the values stored in the tables change
+	DB_SCHEMA_VERSION = 3
+	//DBs created before the schema version was recorded
+	legacySchemaVersion = 1
+)
+
+// migrations upgrade the tables from the given schema version to the next one, they run in order
+// in a single transaction along with the new schema version
+var migrations = map[int]func(cache MetadataCache) error{
+	1: dropUntimedCacheKeys,
+	2: dropCachedLibrary,
+}
+
+// legacyTables are the tables written before the schema version was recorded
+var legacyTables = []string{DB_TABLE_FILE_SCAN_METADATA, DB_TABLE_LOCAL_LIBRARY}
+
+// migrate brings the tables to DB_SCHEMA_VERSION. the cached metadata is dropped as a last resort,
+// when there is no migration path from the stored version (e.g. a DB written by a newer release) or a migration fails
+func (pd *PersistentDB) migrate() error {
+	versionKey := tableKey(DB_INTERNAL_TABLENAME, "schema_version")
+	version := DB_SCHEMA_VERSION
+	if value, ok := pd.cache.Get(versionKey); ok {
+		stored, err := strconv.Atoi(string(value))
+		if err != nil {
+			zap.S().Warnf("invalid DB schema version [%v]", string(value))
+			stored = -1
+		}
+		version = stored
+	} else if hasLegacyData(pd.cache) {
+		version = legacySchemaVersion
+	}
+	if version == DB_SCHEMA_VERSION {
+		if _, ok := pd.cache.Get(versionKey); ok {
+			return nil
+		}
+		return pd.cache.Put(versionKey, []byte(strconv.Itoa(DB_SCHEMA_VERSION)))
+	}
+
+	err := pd.batch(func(cache MetadataCache) error {
+		for v := version; v < DB_SCHEMA_VERSION; v++ {
+			migration, ok := migrations[v]
+			if !ok {
+				return fmt.Errorf("no migration from schema version %v", v)
+			}
+			if err := migration(cache); err != nil {
+				return fmt.Errorf("schema migration from version %v failed - %v", v, err)
+			}
+		}
+		if version > DB_SCHEMA_VERSION {
+			return fmt.Errorf("schema version %v is newer than %v", version, DB_SCHEMA_VERSION)
+		}
+		return cache.Put(versionKey, []byte(strconv.Itoa(DB_SCHEMA_VERSION)))
+	})
+	if err == nil {
+		zap.S().Infof("local DB migrated from schema version %v to %v", version, DB_SCHEMA_VERSION)
+		return nil
+	}
+
+	zap.S().Warnf("%v - clearing the cached metadata", err)
+	return pd.batch(func(cache MetadataCache) error {
+		if _, err := deleteKeys(cache, tableKey(DB_TABLE_FILE_SCAN_METADATA, ""), func(key string) bool {
+			return true
+		}); err != nil {
+			return err
+		}
+		return cache.Put(versionKey, []byte(strconv.Itoa(DB_SCHEMA_VERSION)))
+	})
+}
+
+// deleteKeys deletes the entries whose key starts with the prefix and matches the predicate (called with the key
+// without the prefix), returning the number of deleted entries
+func deleteKeys(cache MetadataCache, prefix string, shouldDelete func(key string) bool) (int, error) {
+	//the cache can't be modified while iterating
+	var keys []string
+	err := cache.ForEach(prefix, func(key string, val []byte) error {
+		if shouldDelete(strings.TrimPrefix(key, prefix)) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, key := range keys {
+		if err := cache.Delete(key); err != nil {
+			return 0, err
+		}
+	}
+	return len(keys), nil
+}
+
+// dropUntimedCacheKeys drops the metadata cached before the modification time was part of the key
+// ("path|name|size"), these entries are never read anymore (see fileCacheKey)
+func dropUntimedCacheKeys(cache MetadataCache) error {
+	_, err := deleteKeys(cache, tableKey(DB_TABLE_FILE_SCAN_METADATA, ""), func(key string) bool {
+		return key != "app_version" && strings.Count(key, "|") < 3
+	})
+	return err
+}
+
+// hasLegacyData returns true when a DB without a schema version holds data - its app_version can't tell, as the
+// releases of that time failed to store it
+func hasLegacyData(cache MetadataCache) bool {
+	if _, ok := cache.Get(tableKey(DB_INTERNAL_TABLENAME, "app_version")); ok {
+		return true
+	}
+	errFound := errors.New("found")
+	for _, table := range legacyTables {
+		err := cache.ForEach(tableKey(table, ""), func(key string, val []byte) error {
+			return errFound
+		})
+		if err == errFound {
+			return true
+		}
+	}
+	return false
+}
+
+// dropCachedLibrary drops the cached library, encoded with other skipped file reason codes and another layout of
+// the title updates - the next scan is served from the cached metadata instead
+func dropCachedLibrary(cache MetadataCache) error {
+	_, err := deleteKeys(cache, tableKey(DB_TABLE_LOCAL_LIBRARY, ""), func(key string) bool {
+		return key == "files" || key == "skipped" || key == "titles"
+	})
+	return err
+}
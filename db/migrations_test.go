@@ -0,0 +1,105 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestMigrateLegacySchema(t *testing.T) {
+	cache := NewInMemoryCache()
+	cache.Put(tableKey(DB_INTERNAL_TABLENAME, "app_version"), []byte("1.3.0"))
+	cache.Put(tableKey(DB_TABLE_FILE_SCAN_METADATA, "/games/a.nsp|a.nsp|100"), []byte("old"))
+	cache.Put(tableKey(DB_TABLE_FILE_SCAN_METADATA, "/games/b.nsp|b.nsp|100|1600000000"), []byte("current"))
+
+	if _, err := NewPersistentDBWithCache(cache); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Get(tableKey(DB_TABLE_FILE_SCAN_METADATA, "/games/a.nsp|a.nsp|100")); ok {
+		t.Errorf("expected the entry without modification time to be dropped")
+	}
+	if _, ok := cache.Get(tableKey(DB_TABLE_FILE_SCAN_METADATA, "/games/b.nsp|b.nsp|100|1600000000")); !ok {
+		t.Errorf("expected the current entry to be kept")
+	}
+	if version, _ := cache.Get(tableKey(DB_INTERNAL_TABLENAME, "schema_version")); string(version) != strconv.Itoa(DB_SCHEMA_VERSION) {
+		t.Errorf("expected schema version %v, got %v", DB_SCHEMA_VERSION, string(version))
+	}
+}
+
+func TestMigrateLegacySchemaWithoutAppVersion(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+
+	//the releases before the schema version failed to store their app_version
+	cache, err := OpenBoltCache(filepath.Join(baseFolder, "slm.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Put(tableKey(DB_TABLE_FILE_SCAN_METADATA, "/games/a.nsp|a.nsp|100"), []byte("old"))
+	cache.Put(tableKey(DB_TABLE_FILE_SCAN_METADATA, "/games/b.nsp|b.nsp|100|1600000000"), []byte("current"))
+	cache.Put(tableKey(DB_TABLE_LOCAL_LIBRARY, "titles"), []byte("old titles"))
+	cache.Put(tableKey(DB_TABLE_LOCAL_LIBRARY, "scan_meta"), []byte("scan"))
+	cache.Close()
+
+	db, err := NewPersistentDB(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	expected := map[string]bool{
+		tableKey(DB_TABLE_FILE_SCAN_METADATA, "/games/a.nsp|a.nsp|100"):            false,
+		tableKey(DB_TABLE_FILE_SCAN_METADATA, "/games/b.nsp|b.nsp|100|1600000000"): true,
+		//the cached library holds the old reason codes
+		tableKey(DB_TABLE_LOCAL_LIBRARY, "titles"):    false,
+		tableKey(DB_TABLE_LOCAL_LIBRARY, "scan_meta"): true,
+	}
+	for key, kept := range expected {
+		if _, ok := db.cache.Get(key); ok != kept {
+			t.Errorf("%v - expected kept %v, got %v", key, kept, ok)
+		}
+	}
+	if version, _ := db.cache.Get(tableKey(DB_INTERNAL_TABLENAME, "schema_version")); string(version) != strconv.Itoa(DB_SCHEMA_VERSION) {
+		t.Errorf("expected schema version %v, got %v", DB_SCHEMA_VERSION, string(version))
+	}
+}
+
+func TestMigrateWithoutPath(t *testing.T) {
+	for _, stored := range []string{"0", strconv.Itoa(DB_SCHEMA_VERSION + 1), "invalid"} {
+		cache := NewInMemoryCache()
+		cache.Put(tableKey(DB_INTERNAL_TABLENAME, "schema_version"), []byte(stored))
+		cache.Put(tableKey(DB_TABLE_FILE_SCAN_METADATA, "/games/b.nsp|b.nsp|100|1600000000"), []byte("current"))
+		cache.Put(tableKey(DB_TABLE_TITLE_NAMES, "0100000000010000"), []byte("name"))
+
+		if _, err := NewPersistentDBWithCache(cache); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := cache.Get(tableKey(DB_TABLE_FILE_SCAN_METADATA, "/games/b.nsp|b.nsp|100|1600000000")); ok {
+			t.Errorf("[%v] expected the cached metadata to be cleared", stored)
+		}
+		if _, ok := cache.Get(tableKey(DB_TABLE_TITLE_NAMES, "0100000000010000")); !ok {
+			t.Errorf("[%v] expected the other tables to be kept", stored)
+		}
+		if version, _ := cache.Get(tableKey(DB_INTERNAL_TABLENAME, "schema_version")); string(version) != strconv.Itoa(DB_SCHEMA_VERSION) {
+			t.Errorf("[%v] expected schema version %v, got %v", stored, DB_SCHEMA_VERSION, string(version))
+		}
+	}
+}
+
+func TestMigrateCurrentSchema(t *testing.T) {
+	cache := NewInMemoryCache()
+	if _, err := NewPersistentDBWithCache(cache); err != nil {
+		t.Fatal(err)
+	}
+	cache.Put(tableKey(DB_TABLE_FILE_SCAN_METADATA, "/games/b.nsp|b.nsp|100|1600000000"), []byte("current"))
+	if _, err := NewPersistentDBWithCache(cache); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Get(tableKey(DB_TABLE_FILE_SCAN_METADATA, "/games/b.nsp|b.nsp|100|1600000000")); !ok {
+		t.Errorf("expected the cached metadata to be kept on reopen")
+	}
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"sort"
+	"strings"
+)
+
+type MissingDlc struct {
+	Title   *SwitchGameFiles
+	TitleId string
+	//sorted title ids of the DLC not found locally
+	Missing []string
+	//number of DLC existing for the title
+	Total int
+}
+
+// MissingDLC returns, for each title with a base, the DLC not found locally. dlc maps the title id (or its prefix)
+// to all the DLC title ids existing for the title, as provided by an external titles database.
+// the result is sorted by title id
+func (l *LocalSwitchFilesDB) MissingDLC(dlc map[string][]string) []MissingDlc {
+	existing := map[string]map[string]struct{}{}
+	for titleId, dlcIds := range dlc {
+		//title id prefix (without the last 4 digits)
+		if len(titleId) == 12 {
+			titleId += "0000"
+		}
+		key := groupingKey(titleId)
+		if existing[key] == nil {
+			existing[key] = map[string]struct{}{}
+		}
+		for _, dlcId := range dlcIds {
+			existing[key][strings.ToLower(dlcId)] = struct{}{}
+		}
+	}
+
+	var result []MissingDlc
+	for key, switchFile := range l.TitlesMap {
+		if !switchFile.BaseExist {
+			continue
+		}
+		local := map[string]struct{}{}
+		for dlcId := range switchFile.Dlc {
+			local[strings.ToLower(dlcId)] = struct{}{}
+		}
+		var missing []string
+		for dlcId := range existing[key] {
+			if _, ok := local[dlcId]; !ok {
+				missing = append(missing, dlcId)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		sort.Strings(missing)
+		result = append(result, MissingDlc{Title: switchFile, TitleId: switchFile.TitleId(), Missing: missing,
+			Total: len(existing[key])})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].TitleId < result[j].TitleId
+	})
+	return result
+}
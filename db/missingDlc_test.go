@@ -0,0 +1,29 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMissingDLC(t *testing.T) {
+	localDB := Group([]SwitchFileInfo{
+		testSwitchFile("base1.nsp", "0100000000010000", 0),
+		testSwitchFile("dlc1.nsp", "0100000000011001", 0),
+		testSwitchFile("base2.nsp", "0100000000020000", 0),
+		testSwitchFile("dlc2.nsp", "0100000000021001", 0),
+		testSwitchFile("dlc3.nsp", "0100000000031001", 0),
+	}, GroupOptions{})
+
+	missing := localDB.MissingDLC(map[string][]string{
+		"0100000000010000": {"0100000000011001", "0100000000011003", "0100000000011002"},
+		"010000000002":     {"0100000000021001"},
+		"0100000000030000": {"0100000000031001", "0100000000031002"},
+	})
+	if len(missing) != 1 {
+		t.Fatalf("expected a single title missing DLC (titles without a base are ignored), got %+v", missing)
+	}
+	if missing[0].TitleId != "0100000000010000" || missing[0].Total != 3 ||
+		!reflect.DeepEqual(missing[0].Missing, []string{"0100000000011002", "0100000000011003"}) {
+		t.Errorf("unexpected missing DLC %+v", missing[0])
+	}
+}
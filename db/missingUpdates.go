@@ -0,0 +1,56 @@
+package db
+
+import "sort"
+
+// number of the version increments between two consecutive updates (v65536, v131072, ...)
+const updateVersionStep = 0x10000
+
+type MissingUpdate struct {
+	Title            *SwitchGameFiles
+	TitleId          string
+	LocalVersion     int
+	AvailableVersion int
+	//number of updates released since the local version
+	VersionsBehind int
+}
+
+// MissingUpdates returns the titles (having a base or an update) whose latest local update is older than the
+// available version. available maps the title id (or its prefix) to the latest known update version, as provided
+// by an external titles database. the result is sorted by the number of versions behind (descending), then title id
+func (l *LocalSwitchFilesDB) MissingUpdates(available map[string]int) []MissingUpdate {
+	latest := map[string]int{}
+	for titleId, version := range available {
+		//title id prefix (without the last 4 digits)
+		if len(titleId) == 12 {
+			titleId += "0000"
+		}
+		key := groupingKey(titleId)
+		if version > latest[key] {
+			latest[key] = version
+		}
+	}
+
+	var result []MissingUpdate
+	for key, switchFile := range l.TitlesMap {
+		if !switchFile.BaseExist && len(switchFile.Updates) == 0 {
+			continue
+		}
+		availableVersion, ok := latest[key]
+		if !ok || switchFile.LatestUpdate >= availableVersion {
+			continue
+		}
+		behind := (availableVersion - switchFile.LatestUpdate) / updateVersionStep
+		if behind == 0 {
+			behind = 1
+		}
+		result = append(result, MissingUpdate{Title: switchFile, TitleId: switchFile.TitleId(),
+			LocalVersion: switchFile.LatestUpdate, AvailableVersion: availableVersion, VersionsBehind: behind})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].VersionsBehind != result[j].VersionsBehind {
+			return result[i].VersionsBehind > result[j].VersionsBehind
+		}
+		return result[i].TitleId < result[j].TitleId
+	})
+	return result
+}
@@ -0,0 +1,30 @@
+package db
+
+import "testing"
+
+func TestMissingUpdates(t *testing.T) {
+	localDB := Group([]SwitchFileInfo{
+		testSwitchFile("base1.nsp", "0100000000010000", 0),
+		testSwitchFile("update1.nsp", "0100000000010800", 65536),
+		testSwitchFile("base2.nsp", "0100000000020000", 0),
+		testSwitchFile("base3.nsp", "0100000000030000", 0),
+		testSwitchFile("update3.nsp", "0100000000030800", 131072),
+		testSwitchFile("dlc4.nsp", "0100000000041001", 0),
+	}, GroupOptions{})
+
+	missing := localDB.MissingUpdates(map[string]int{
+		"0100000000010000": 196608,
+		"010000000002":     65536,
+		"0100000000030800": 131072,
+		"0100000000040000": 65536,
+	})
+	if len(missing) != 2 {
+		t.Fatalf("expected 2 titles missing updates, got %+v", missing)
+	}
+	if missing[0].TitleId != "0100000000010000" || missing[0].VersionsBehind != 2 || missing[0].LocalVersion != 65536 {
+		t.Errorf("unexpected first missing update %+v", missing[0])
+	}
+	if missing[1].TitleId != "0100000000020000" || missing[1].VersionsBehind != 1 || missing[1].AvailableVersion != 65536 {
+		t.Errorf("unexpected second missing update %+v", missing[1])
+	}
+}
@@ -0,0 +1,59 @@
+package db
+
+import "sort"
+
+type MultiContentEntry struct {
+	File ExtendedFileInfo
+	//the contents held by the file, ordered by title id
+	Contents []BundledContent
+}
+
+type BundledContent struct {
+	TitleId string
+	Type    TitleType
+	Version int
+}
+
+// MultiContentFiles returns the files holding several contents (e.g. a XCI holding a base, its update and DLC),
+// ordered by path, with the contents found in each file - such a file is listed under each title it holds content of.
+// the duplicate contents are included, the old DLC files are not
+func (l *LocalSwitchFilesDB) MultiContentFiles() []MultiContentEntry {
+	contents := map[ExtendedFileInfo]map[string]BundledContent{}
+	add := func(file SwitchFileInfo) {
+		if file.Metadata == nil {
+			return
+		}
+		titleId, titleType, err := ValidateTitleId(file.Metadata.TitleId)
+		if err != nil {
+			titleId = file.Metadata.TitleId
+		}
+		if contents[file.ExtendedInfo] == nil {
+			contents[file.ExtendedInfo] = map[string]BundledContent{}
+		}
+		contents[file.ExtendedInfo][titleId] = BundledContent{TitleId: titleId, Type: titleType, Version: file.Metadata.Version}
+	}
+	for _, title := range l.TitlesMap {
+		for _, file := range title.files() {
+			add(file)
+		}
+	}
+
+	var result []MultiContentEntry
+	for file, fileContents := range contents {
+		if len(fileContents) < 2 {
+			continue
+		}
+		entry := MultiContentEntry{File: file}
+		for _, content := range fileContents {
+			entry.Contents = append(entry.Contents, content)
+		}
+		sort.Slice(entry.Contents, func(i, j int) bool {
+			return entry.Contents[i].TitleId < entry.Contents[j].TitleId
+		})
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return fileKey(result[i].File) < fileKey(result[j].File)
+	})
+	return result
+}
@@ -0,0 +1,37 @@
+package db
+
+import "testing"
+
+func TestMultiContentFiles(t *testing.T) {
+	files := []SwitchFileInfo{
+		testSwitchFile("bundle.xci", "0100000000010000", 0),
+		testSwitchFile("bundle.xci", "0100000000010800", 65536),
+		testSwitchFile("bundle.xci", "0100000000011001", 0),
+		testSwitchFile("dlc.nsp", "0100000000011002", 0),
+		testSwitchFile("a bundle.nsp", "0100000000020800", 65536),
+		testSwitchFile("a bundle.nsp", "0100000000030800", 65536),
+	}
+	localDB := Group(files, GroupOptions{})
+
+	entries := localDB.MultiContentFiles()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 multi-content files, got %+v", entries)
+	}
+	if entries[0].File.FileName != "a bundle.nsp" || len(entries[0].Contents) != 2 ||
+		entries[0].Contents[1].TitleId != "0100000000030800" || entries[0].Contents[1].Type != TITLE_TYPE_UPDATE {
+		t.Errorf("unexpected entry %+v", entries[0])
+	}
+	expected := []BundledContent{
+		{TitleId: "0100000000010000", Type: TITLE_TYPE_BASE, Version: 0},
+		{TitleId: "0100000000010800", Type: TITLE_TYPE_UPDATE, Version: 65536},
+		{TitleId: "0100000000011001", Type: TITLE_TYPE_DLC, Version: 0},
+	}
+	if entries[1].File.FileName != "bundle.xci" || len(entries[1].Contents) != len(expected) {
+		t.Fatalf("unexpected entry %+v", entries[1])
+	}
+	for i := range expected {
+		if entries[1].Contents[i] != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], entries[1].Contents[i])
+		}
+	}
+}
@@ -3,119 +3,211 @@ package db
 import (
 	"bytes"
 	"encoding/gob"
+	"errors"
 	"fmt"
-	"github.com/boltdb/bolt"
 	"github.com/giwty/switch-library-manager/settings"
 	"go.uber.org/zap"
-	"log"
+	"io"
 	"path/filepath"
+	"strconv"
+	"strings"
 )
 
 const (
 	DB_INTERNAL_TABLENAME = "internal-metadata"
 )
 
+// ErrReadOnly is returned by the write operations of a DB opened read-only (see NewPersistentDBReadOnly)
+var ErrReadOnly = errors.New("the local DB is opened read-only")
+
+// PersistentDB stores gob encoded values in tables, on top of a MetadataCache
 type PersistentDB struct {
-	db *bolt.DB
+	cache    MetadataCache
+	readOnly bool
 }
 
+// NewPersistentDB opens (or creates) the slm.db file in the base folder
 func NewPersistentDB(baseFolder string) (*PersistentDB, error) {
-	// Open the my.db data file in your current directory.
-	// It will be created if it doesn't exist.
-	db, err := bolt.Open(filepath.Join(baseFolder, "slm.db"), 0600, &bolt.Options{Timeout: 1 * 60})
+	cache, err := OpenBoltCache(filepath.Join(baseFolder, "slm.db"))
+	if err != nil {
+		return nil, err
+	}
+	db, err := NewPersistentDBWithCache(cache)
 	if err != nil {
-		log.Fatal(err)
+		cache.Close()
 		return nil, err
 	}
+	return db, nil
+}
 
+// NewPersistentDBReadOnly opens the existing slm.db file in the base folder for reading, the write operations
+// return ErrReadOnly. the DB is not migrated, a DB of another schema version is rejected
+func NewPersistentDBReadOnly(baseFolder string) (*PersistentDB, error) {
+	cache, err := OpenBoltCacheReadOnly(filepath.Join(baseFolder, "slm.db"))
+	if err != nil {
+		return nil, err
+	}
+	version := legacySchemaVersion
+	if value, ok := cache.Get(tableKey(DB_INTERNAL_TABLENAME, "schema_version")); ok {
+		version, _ = strconv.Atoi(string(value))
+	}
+	if version != DB_SCHEMA_VERSION {
+		cache.Close()
+		return nil, fmt.Errorf("the local DB schema version is %v (expected %v), open it once for writing to migrate it",
+			version, DB_SCHEMA_VERSION)
+	}
+	return &PersistentDB{cache: cache, readOnly: true}, nil
+}
+
+// NewPersistentDBWithCache stores the tables in the given cache
+func NewPersistentDBWithCache(cache MetadataCache) (*PersistentDB, error) {
+	db := &PersistentDB{cache: cache}
+	if err := db.migrate(); err != nil {
+		zap.S().Warnf("failed to migrate the DB schema - %v", err)
+		return nil, fmt.Errorf("failed to initialize the local DB - %v", err)
+	}
 	//set DB version
-	err = db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(DB_INTERNAL_TABLENAME))
-		if b == nil {
-			b, err := tx.CreateBucket([]byte(DB_INTERNAL_TABLENAME))
-			if b == nil || err != nil {
-				return fmt.Errorf("create bucket: %s", err)
-			}
-			err = b.Put([]byte("app_version"), []byte(settings.SLM_VERSION))
-			if err != nil {
-				zap.S().Warnf("failed to save app_version - %v", err)
-				return err
-			}
+	versionKey := tableKey(DB_INTERNAL_TABLENAME, "app_version")
+	if _, ok := cache.Get(versionKey); !ok {
+		err := cache.Put(versionKey, []byte(settings.SLM_VERSION))
+		if err != nil {
+			zap.S().Warnf("failed to save app_version - %v", err)
+			return nil, fmt.Errorf("failed to initialize the local DB - %v", err)
 		}
-		return nil
-	})
+	}
+	return db, nil
+}
+
+func tableKey(tableName string, key string) string {
+	return tableName + "/" + key
+}
+
+// batch runs fn in a single transaction when supported by the cache
+func (pd *PersistentDB) batch(fn func(cache MetadataCache) error) error {
+	if batcher, ok := pd.cache.(batchCache); ok {
+		return batcher.Batch(fn)
+	}
+	return fn(pd.cache)
+}
+
+//all the operations below are no-ops on a nil PersistentDB, which allows
+//running in a cache-less mode when the DB file cannot be opened
 
-	return &PersistentDB{db: db}, nil
+// ReadOnly returns true when the DB was opened read-only
+func (pd *PersistentDB) ReadOnly() bool {
+	return pd != nil && pd.readOnly
 }
 
 func (pd *PersistentDB) Close() {
-	pd.db.Close()
+	if pd == nil {
+		return
+	}
+	if closer, ok := pd.cache.(io.Closer); ok {
+		closer.Close()
+	}
 }
 
 func (pd *PersistentDB) ClearTable(tableName string) error {
-	err := pd.db.Update(func(tx *bolt.Tx) error {
-		err := tx.DeleteBucket([]byte(tableName))
-		return err
+	_, err := pd.DeleteEntries(tableName, func(key string) bool {
+		return true
 	})
 	return err
 }
 
 func (pd *PersistentDB) AddEntry(tableName string, key string, value interface{}) error {
-	var err error
-	err = pd.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(tableName))
-		if b == nil {
-			b, err = tx.CreateBucket([]byte(tableName))
-			if b == nil || err != nil {
-				return fmt.Errorf("create bucket: %s", err)
-			}
-		}
-		var bytesBuff bytes.Buffer
-		encoder := gob.NewEncoder(&bytesBuff)
-		err := encoder.Encode(value)
-		if err != nil {
-			return err
-		}
-		err = b.Put([]byte(key), bytesBuff.Bytes())
+	if pd == nil {
+		return nil
+	}
+	if pd.readOnly {
+		return ErrReadOnly
+	}
+	var bytesBuff bytes.Buffer
+	encoder := gob.NewEncoder(&bytesBuff)
+	err := encoder.Encode(value)
+	if err != nil {
 		return err
-	})
-	return err
+	}
+	return pd.cache.Put(tableKey(tableName, key), bytesBuff.Bytes())
 }
 
-func (pd *PersistentDB) GetEntry(tableName string, key string, value interface{}) error {
-	err := pd.db.View(func(tx *bolt.Tx) error {
-
-		b := tx.Bucket([]byte(tableName))
-		if b == nil {
-			return nil
-		}
-		v := b.Get([]byte(key))
-		if v == nil {
-			return nil
-		}
-		d := gob.NewDecoder(bytes.NewReader(v))
-
-		// Decoding the serialized data
-		err := d.Decode(value)
-		if err != nil {
-			return err
+// AddEntries adds (or replaces) all the entries in a single transaction
+func (pd *PersistentDB) AddEntries(tableName string, entries map[string]interface{}) error {
+	if pd == nil || len(entries) == 0 {
+		return nil
+	}
+	if pd.readOnly {
+		return ErrReadOnly
+	}
+	return pd.batch(func(cache MetadataCache) error {
+		for key, value := range entries {
+			var bytesBuff bytes.Buffer
+			err := gob.NewEncoder(&bytesBuff).Encode(value)
+			if err != nil {
+				return err
+			}
+			err = cache.Put(tableKey(tableName, key), bytesBuff.Bytes())
+			if err != nil {
+				return err
+			}
 		}
 		return nil
 	})
-	return err
 }
 
-/*func (pd *PersistentDB) GetEntries() (map[string]*switchfs.ContentMetaAttributes, error) {
-	pd.db.View(func(tx *bolt.Tx) error {
-		// Assume bucket exists and has keys
-		b := tx.Bucket([]byte(METADATA_TABLENAME))
+// ForEachEntry calls fn for each entry of the table, decode decodes the entry value into the given target
+func (pd *PersistentDB) ForEachEntry(tableName string, fn func(key string, decode func(value interface{}) error) error) error {
+	if pd == nil {
+		return nil
+	}
+	prefix := tableKey(tableName, "")
+	return pd.cache.ForEach(prefix, func(key string, val []byte) error {
+		return fn(strings.TrimPrefix(key, prefix), func(value interface{}) error {
+			return gob.NewDecoder(bytes.NewReader(val)).Decode(value)
+		})
+	})
+}
 
-		c := b.Cursor()
+func (pd *PersistentDB) DeleteEntry(tableName string, key string) error {
+	if pd == nil {
+		return nil
+	}
+	if pd.readOnly {
+		return ErrReadOnly
+	}
+	return pd.cache.Delete(tableKey(tableName, key))
+}
 
-		for k, v := c.First(); k != nil; k, v = c.Next() {
-			fmt.Printf("key=%s, value=%s\n", k, v)
-		}
+// DeleteEntries deletes the entries of the table matching the given predicate in a single transaction,
+// returning the number of deleted entries
+func (pd *PersistentDB) DeleteEntries(tableName string, shouldDelete func(key string) bool) (int, error) {
+	if pd == nil {
+		return 0, nil
+	}
+	if pd.readOnly {
+		return 0, ErrReadOnly
+	}
+	deleted := 0
+	err := pd.batch(func(cache MetadataCache) error {
+		var err error
+		deleted, err = deleteKeys(cache, tableKey(tableName, ""), shouldDelete)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
 
+func (pd *PersistentDB) GetEntry(tableName string, key string, value interface{}) error {
+	if pd == nil {
 		return nil
-	})
-}*/
+	}
+	v, ok := pd.cache.Get(tableKey(tableName, key))
+	if !ok {
+		return nil
+	}
+	d := gob.NewDecoder(bytes.NewReader(v))
+
+	// Decoding the serialized data
+	return d.Decode(value)
+}
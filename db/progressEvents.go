@@ -0,0 +1,148 @@
+package db
+
+import (
+	"strings"
+	"sync"
+)
+
+const (
+	PHASE_SCANNING   = "scanning"
+	PHASE_PROCESSING = "processing"
+	PHASE_CACHING    = "caching"
+	PHASE_COMPLETE   = "complete"
+)
+
+// ProgressEvent is a machine-readable scan progress update (see ProgressEvents).
+// Current and Total are -1 while the number of files isn't known yet (e.g. while the folders are walked)
+type ProgressEvent struct {
+	Phase       string `json:"phase"`
+	Current     int    `json:"current"`
+	Total       int    `json:"total"`
+	CurrentFile string `json:"current_file,omitempty"`
+	//set on the last event when the scan failed
+	Error string `json:"error,omitempty"`
+}
+
+// ProgressEvents is a ProgressUpdater forwarding the updates as ProgressEvent values over a channel.
+// the updates are queued, so a slow reader never holds up the scan. the channel is closed by Close,
+// once all the queued events are read
+type ProgressEvents struct {
+	sync.Mutex
+	queue    []ProgressEvent
+	closed   bool
+	complete bool
+	signal   chan struct{}
+	events   chan ProgressEvent
+}
+
+// NewProgressEvents returns a ProgressEvents ready to be passed as the scan progress
+func NewProgressEvents() *ProgressEvents {
+	p := &ProgressEvents{signal: make(chan struct{}, 1), events: make(chan ProgressEvent)}
+	go p.forward()
+	return p
+}
+
+// Events returns the channel the events are sent to
+func (p *ProgressEvents) Events() <-chan ProgressEvent {
+	return p.events
+}
+
+func (p *ProgressEvents) UpdateProgress(curr int, total int, message string) {
+	p.push(progressEvent(curr, total, message))
+}
+
+// Close ends the events, the error (if any) is sent with a last complete event.
+// a complete event is sent as well when the scan didn't report its completion
+func (p *ProgressEvents) Close(err error) {
+	p.Lock()
+	if p.closed {
+		p.Unlock()
+		return
+	}
+	if err != nil || !p.complete {
+		event := ProgressEvent{Phase: PHASE_COMPLETE, Current: -1, Total: -1}
+		if err != nil {
+			event.Error = err.Error()
+		}
+		p.queue = append(p.queue, event)
+	}
+	p.closed = true
+	p.Unlock()
+	p.notify()
+}
+
+func (p *ProgressEvents) push(event ProgressEvent) {
+	p.Lock()
+	if p.closed {
+		p.Unlock()
+		return
+	}
+	p.complete = p.complete || event.Phase == PHASE_COMPLETE
+	p.queue = append(p.queue, event)
+	p.Unlock()
+	p.notify()
+}
+
+func (p *ProgressEvents) notify() {
+	select {
+	case p.signal <- struct{}{}:
+	default:
+	}
+}
+
+// forward sends the queued events until the events are closed and the queue is drained
+func (p *ProgressEvents) forward() {
+	defer close(p.events)
+	for {
+		p.Lock()
+		queue := p.queue
+		p.queue = nil
+		closed := p.closed
+		p.Unlock()
+		for _, event := range queue {
+			p.events <- event
+		}
+		if closed && len(queue) == 0 {
+			return
+		}
+		if len(queue) == 0 {
+			<-p.signal
+		}
+	}
+}
+
+// progressEvent maps the progress messages of the scan to an event
+func progressEvent(curr int, total int, message string) ProgressEvent {
+	event := ProgressEvent{Current: curr, Total: total}
+	switch {
+	case strings.HasPrefix(message, "process:"):
+		event.Phase = PHASE_PROCESSING
+		event.CurrentFile = strings.TrimPrefix(message, "process:")
+	case strings.HasPrefix(message, "scanning files in "):
+		event.Phase = PHASE_SCANNING
+		event.CurrentFile = strings.TrimPrefix(message, "scanning files in ")
+	case strings.HasPrefix(message, "scanning "):
+		event.Phase = PHASE_SCANNING
+		event.CurrentFile = strings.TrimPrefix(message, "scanning ")
+	case message == "caching":
+		event.Phase = PHASE_CACHING
+	case message == "Complete":
+		event.Phase = PHASE_COMPLETE
+	default:
+		event.Phase = PHASE_PROCESSING
+		event.CurrentFile = message
+	}
+	return event
+}
+
+// CreateLocalSwitchFilesDBWithEvents runs CreateLocalSwitchFilesDB with the progress reported as events.
+// the scan runs to completion before returning, the channel then holds all the events of the scan and is closed
+// after the last one. to follow a scan as it runs (e.g. to stream it to a web UI), pass a ProgressEvents to
+// CreateLocalSwitchFilesDB from another goroutine instead
+func (ldb *LocalSwitchDBManager) CreateLocalSwitchFilesDBWithEvents(folders []string, recursive bool,
+	ignoreCache bool) (*LocalSwitchFilesDB, <-chan ProgressEvent, error) {
+	events := NewProgressEvents()
+	localDB, err := ldb.CreateLocalSwitchFilesDB(folders, events, recursive, ignoreCache)
+	events.Close(err)
+	return localDB, events.Events(), err
+}
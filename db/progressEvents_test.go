@@ -0,0 +1,88 @@
+package db
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateLocalSwitchFilesDBWithEvents(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+	for _, fileName := range []string{
+		"Super Mario Odyssey [0100000000010000][v0].nsp",
+		"Super Mario Odyssey [0100000000010800][v65536].nsp",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(gamesFolder, fileName), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	localDB, events, err := manager.CreateLocalSwitchFilesDBWithEvents([]string{gamesFolder}, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if localDB.NumFiles != 2 {
+		t.Errorf("expected 2 files, got %v", localDB.NumFiles)
+	}
+	var received []ProgressEvent
+	for event := range events {
+		received = append(received, event)
+	}
+	if len(received) == 0 {
+		t.Fatal("expected progress events")
+	}
+	if last := received[len(received)-1]; last.Phase != PHASE_COMPLETE || last.Error != "" {
+		t.Errorf("expected a complete event last, got %+v", last)
+	}
+	phases := map[string]bool{}
+	for _, event := range received {
+		phases[event.Phase] = true
+	}
+	for _, phase := range []string{PHASE_SCANNING, PHASE_PROCESSING, PHASE_CACHING} {
+		if !phases[phase] {
+			t.Errorf("expected a %v event, got %+v", phase, received)
+		}
+	}
+}
+
+func TestProgressEventsClose(t *testing.T) {
+	events := NewProgressEvents()
+	events.UpdateProgress(1, 2, "process:a.nsp")
+	events.Close(errors.New("scan failed"))
+	//updates after close are dropped
+	events.UpdateProgress(2, 2, "Complete")
+
+	var received []ProgressEvent
+	for event := range events.Events() {
+		received = append(received, event)
+	}
+	expected := []ProgressEvent{
+		{Phase: PHASE_PROCESSING, Current: 1, Total: 2, CurrentFile: "a.nsp"},
+		{Phase: PHASE_COMPLETE, Current: -1, Total: -1, Error: "scan failed"},
+	}
+	if len(received) != len(expected) {
+		t.Fatalf("expected %+v, got %+v", expected, received)
+	}
+	for i := range expected {
+		if received[i] != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], received[i])
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// forEachFile calls fn once for each base, update and DLC of the library, ordered by title id
+// then base, updates (by version) and DLC (by title id), see SwitchGameFiles.contentFiles
+func (l *LocalSwitchFilesDB) forEachFile(fn func(file SwitchFileInfo)) {
+	keys := make([]string, 0, len(l.TitlesMap))
+	for key := range l.TitlesMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, file := range l.TitlesMap[key].contentFiles() {
+			fn(file)
+		}
+	}
+}
+
+// FilesByExtension returns the base, update and DLC files keyed by their lower case extension without the dot
+// (e.g. "nsp"), split files are keyed by their container format (e.g. "xci") rather than the part extension.
+// a file holding several contents is listed once per content
+func (l *LocalSwitchFilesDB) FilesByExtension() map[string][]SwitchFileInfo {
+	result := map[string][]SwitchFileInfo{}
+	l.forEachFile(func(file SwitchFileInfo) {
+		extension := strings.TrimPrefix(strings.ToLower(filepath.Ext(file.ExtendedInfo.FileName)), ".")
+		if file.Split != nil && file.Format != "" {
+			extension = file.Format
+		}
+		result[extension] = append(result[extension], file)
+	})
+	return result
+}
+
+// FilesByType returns the base, update and DLC files keyed by their metadata type - "Base", "Update" or "DLC"
+// (set when grouping, see Group), files without metadata are left out
+func (l *LocalSwitchFilesDB) FilesByType() map[string][]SwitchFileInfo {
+	result := map[string][]SwitchFileInfo{}
+	l.forEachFile(func(file SwitchFileInfo) {
+		if file.Metadata == nil {
+			return
+		}
+		result[file.Metadata.Type] = append(result[file.Metadata.Type], file)
+	})
+	return result
+}
+
+// UnverifiedFiles returns the base, update and DLC files identified by their name or a .cnmt.xml file rather than
+// their content (see SwitchFileInfo.Unverified), e.g. scanned without keys
+func (l *LocalSwitchFilesDB) UnverifiedFiles() []SwitchFileInfo {
+	var result []SwitchFileInfo
+	l.forEachFile(func(file SwitchFileInfo) {
+		if file.Unverified() {
+			result = append(result, file)
+		}
+	})
+	return result
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"github.com/giwty/switch-library-manager/fileio"
+	"testing"
+)
+
+func TestFilesByExtensionAndType(t *testing.T) {
+	split := testSwitchFile("00", "0100000000030000", 0)
+	split.Format = "xci"
+	split.Split = &fileio.SplitFileInfo{NumParts: 2, TotalSize: 2, Format: "xci"}
+	files := []SwitchFileInfo{
+		testSwitchFile("base.NSP", "0100000000010000", 0),
+		testSwitchFile("update1.nsz", "0100000000010800", 65536),
+		testSwitchFile("update2.nsp", "0100000000010800", 131072),
+		testSwitchFile("dlc.nsp", "0100000000011001", 0),
+		testSwitchFile("base2.xci", "0100000000020000", 0),
+		split,
+	}
+	localDB := Group(files, GroupOptions{KeepOldUpdates: true})
+
+	byExtension := localDB.FilesByExtension()
+	if len(byExtension["nsp"]) != 3 || len(byExtension["nsz"]) != 1 || len(byExtension["xci"]) != 2 || len(byExtension) != 3 {
+		t.Errorf("unexpected files by extension %v", byExtension)
+	}
+
+	byType := localDB.FilesByType()
+	if len(byType["Base"]) != 3 || len(byType["Update"]) != 2 || len(byType["DLC"]) != 1 || len(byType) != 3 {
+		t.Errorf("unexpected files by type %v", byType)
+	}
+	//ordered by title id then version
+	if byType["Update"][0].ExtendedInfo.FileName != "update1.nsz" || byType["Base"][2].ExtendedInfo.FileName != "00" {
+		t.Errorf("unexpected files order %v", byType)
+	}
+
+	//a file without metadata has no type
+	dlc := localDB.TitlesMap["0100000000010000"].Dlc["0100000000011001"]
+	dlc.Metadata = nil
+	localDB.TitlesMap["0100000000010000"].Dlc["0100000000011001"] = dlc
+	if byType := localDB.FilesByType(); len(byType["DLC"]) != 0 || len(byType) != 2 {
+		t.Errorf("expected the file without metadata to be left out, got %v", byType)
+	}
+}
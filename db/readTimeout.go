@@ -0,0 +1,71 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"github.com/avast/retry-go"
+	"github.com/giwty/switch-library-manager/switchfs"
+	"go.uber.org/zap"
+	"os"
+	"time"
+)
+
+// errReadTimeout is wrapped by the errors of the reads which didn't complete in time (see readWithTimeout)
+var errReadTimeout = errors.New("read timed out")
+
+// retryDelay is the delay before the first retry of a failed read, doubled for each retry
+var retryDelay = 500 * time.Millisecond
+
+// readWithTimeout runs the read with the configured per file timeout, so a file on a stalled mount can't freeze the
+// whole scan. the reads failing with an I/O error or timing out are retried (readRetries times) with an increasing
+// delay. a read which timed out is abandoned - its goroutine goes on until the read returns, and its result is dropped
+func (ldb *LocalSwitchDBManager) readWithTimeout(read func() (map[string]*switchfs.ContentMetaAttributes, error)) (map[string]*switchfs.ContentMetaAttributes, error) {
+	var metadata map[string]*switchfs.ContentMetaAttributes
+	attempt := func() error {
+		var err error
+		metadata, err = runWithTimeout(read, ldb.readTimeout)
+		return err
+	}
+	if ldb.readRetries <= 0 {
+		return metadata, attempt()
+	}
+	err := retry.Do(attempt, retry.Attempts(uint(ldb.readRetries)+1), retry.Delay(retryDelay),
+		retry.DelayType(retry.BackOffDelay), retry.LastErrorOnly(true), retry.RetryIf(isTransientError),
+		retry.OnRetry(func(n uint, err error) {
+			zap.S().Warnf("retrying a failed read (attempt %v) - %v", n+2, err)
+		}))
+	return metadata, err
+}
+
+func runWithTimeout(read func() (map[string]*switchfs.ContentMetaAttributes, error),
+	timeout time.Duration) (map[string]*switchfs.ContentMetaAttributes, error) {
+	if timeout <= 0 {
+		return read()
+	}
+	type result struct {
+		metadata map[string]*switchfs.ContentMetaAttributes
+		err      error
+	}
+	//buffered, an abandoned read doesn't block when it eventually returns
+	done := make(chan result, 1)
+	go func() {
+		metadata, err := read()
+		done <- result{metadata: metadata, err: err}
+	}()
+	select {
+	case r := <-done:
+		return r.metadata, r.err
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("%w - no response after %v", errReadTimeout, timeout)
+	}
+}
+
+// isTransientError returns true for the errors worth retrying - timeouts and I/O errors, but not missing files,
+// permission errors or files which can't be parsed
+func isTransientError(err error) bool {
+	if errors.Is(err, errReadTimeout) {
+		return true
+	}
+	var pathError *os.PathError
+	return errors.As(err, &pathError) && !os.IsNotExist(pathError) && !os.IsPermission(pathError)
+}
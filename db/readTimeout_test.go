@@ -0,0 +1,108 @@
+package db
+
+import (
+	"github.com/giwty/switch-library-manager/settings"
+	"github.com/giwty/switch-library-manager/switchfs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// stalledFileSource blocks the reads of the stalled files until released, and fails the first reads of the
+// flaky files with an I/O error
+type stalledFileSource struct {
+	memoryFileSource
+	stalled map[string]bool
+	release chan struct{}
+	flaky   map[string]int
+}
+
+func (s *stalledFileSource) Open(filePath string) (switchfs.ReadAtCloser, int64, error) {
+	if s.stalled[filePath] {
+		<-s.release
+	}
+	s.Lock()
+	if s.flaky[filePath] > 0 {
+		s.flaky[filePath]--
+		s.Unlock()
+		return nil, 0, &os.PathError{Op: "read", Path: filePath, Err: syscall.EIO}
+	}
+	s.Unlock()
+	return s.memoryFileSource.Open(filePath)
+}
+
+func TestReadTimeout(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	if err := ioutil.WriteFile(filepath.Join(baseFolder, "prod.keys"), []byte(testProdKeys), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := settings.InitSwitchKeys(baseFolder); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		settings.ReadSettings(baseFolder).Prodkeys = ""
+		os.Remove(filepath.Join(baseFolder, "prod.keys"))
+		settings.InitSwitchKeys(baseFolder)
+	}()
+
+	manager, err := NewLocalSwitchDBManagerWithCache(baseFolder, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stalledPath := filepath.Join("/remote", "Zelda [0100000000020000][v0].nsp")
+	flakyPath := filepath.Join("/remote", "Super Mario Odyssey [0100000000010000][v0].nsp")
+	source := &stalledFileSource{
+		memoryFileSource: memoryFileSource{files: map[string][]byte{flakyPath: []byte("not a PFS0 header")}},
+		stalled:          map[string]bool{stalledPath: true},
+		release:          make(chan struct{}),
+		flaky:            map[string]int{flakyPath: 1},
+	}
+	defer close(source.release)
+	manager.SetFileSource(source)
+	manager.readTimeout = 50 * time.Millisecond
+	manager.readRetries = 1
+	defer func(delay time.Duration) {
+		retryDelay = delay
+	}(retryDelay)
+	retryDelay = time.Millisecond
+
+	stalled := ExtendedFileInfo{FileName: filepath.Base(stalledPath), BaseFolder: "/remote", Size: 10}
+	flaky := ExtendedFileInfo{FileName: filepath.Base(flakyPath), BaseFolder: "/remote", Size: 17}
+	var switchFiles []SwitchFileInfo
+	var skipped map[ExtendedFileInfo]SkippedFile
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		switchFiles, skipped = manager.GatherFiles([]ExtendedFileInfo{stalled, flaky}, nil)
+	}()
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the scan is stalled")
+	}
+
+	if skipped[stalled].ReasonCode != REASON_TIMEOUT {
+		t.Errorf("expected the stalled file to time out, got %+v", skipped[stalled])
+	}
+	//the I/O error is retried, the file is then read (and identified by its name as it can't be parsed)
+	if skipped[flaky].ReasonCode != REASON_MALFORMED_FILE || len(switchFiles) != 1 {
+		t.Errorf("expected the flaky file to be read after a retry, got %v (skipped %+v)", switchFiles, skipped[flaky])
+	}
+	if source.flaky[flakyPath] != 0 {
+		t.Errorf("expected the flaky file to be retried")
+	}
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"go.uber.org/zap"
+	"sort"
+)
+
+// ReconcileGroups merges title groups belonging to the same application, which happens when the files of a title
+// were keyed inconsistently (e.g. a title id parsed from a quirky file name, or a library grouped by an older version).
+// groups are re-keyed by their base title id, base/update/DLC collisions are resolved the same way as in Group.
+// it returns the number of merged groups
+func ReconcileGroups(localDB *LocalSwitchFilesDB, opts GroupOptions) int {
+	keys := make([]string, 0, len(localDB.TitlesMap))
+	for key := range localDB.TitlesMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	merged := 0
+	for _, key := range keys {
+		title := localDB.TitlesMap[key]
+		baseId := title.TitleId()
+		if baseId == "" || baseId == key {
+			continue
+		}
+		delete(localDB.TitlesMap, key)
+		target, ok := localDB.TitlesMap[baseId]
+		if !ok {
+			localDB.TitlesMap[baseId] = title
+			continue
+		}
+		zap.S().Infof("merging title group [%v] into [%v]", key, baseId)
+		mergeTitles(target, title, localDB.Skipped, opts)
+		merged++
+	}
+	return merged
+}
+
+func mergeTitles(target *SwitchGameFiles, source *SwitchGameFiles, skipped map[ExtendedFileInfo]SkippedFile,
+	opts GroupOptions) {
+	if source.BaseExist {
+		if target.BaseExist {
+			kept, duplicate := target.File, source.File
+			if preferredFile(source.File, target.File, opts) {
+				kept, duplicate = source.File, target.File
+				target.File = kept
+				target.IsSplit = source.IsSplit
+				unmarkDuplicate(skipped, kept.ExtendedInfo)
+			}
+			skipped[duplicate.ExtendedInfo] = SkippedFile{ReasonCode: REASON_DUPLICATE,
+				ReasonText: "duplicate base file (" + kept.ExtendedInfo.FileName + ")"}
+			target.Duplicates = append(target.Duplicates, duplicate)
+		} else {
+			target.File = source.File
+			target.BaseExist = true
+			target.MultiContent = target.MultiContent || source.MultiContent
+			target.IsSplit = source.IsSplit
+		}
+	}
+
+	for version, update := range source.Updates {
+		if existing, ok := target.Updates[version]; ok {
+			kept, duplicate := existing, update
+			if preferredFile(update, existing, opts) {
+				kept, duplicate = update, existing
+				target.Updates[version] = kept
+				unmarkDuplicate(skipped, kept.ExtendedInfo)
+			}
+			skipped[duplicate.ExtendedInfo] = SkippedFile{ReasonCode: REASON_DUPLICATE,
+				ReasonText: "duplicate update file (" + kept.ExtendedInfo.FileName + ")"}
+			target.Duplicates = append(target.Duplicates, duplicate)
+			continue
+		}
+		target.Updates[version] = update
+	}
+	if source.LatestUpdate > target.LatestUpdate {
+		target.LatestUpdate = source.LatestUpdate
+	}
+	if !opts.KeepOldUpdates {
+		markOldUpdates(target, skipped, opts)
+	}
+
+	for id, dlc := range source.Dlc {
+		existing, ok := target.Dlc[id]
+		if !ok {
+			target.Dlc[id] = dlc
+			continue
+		}
+		switch {
+		case dlc.Metadata.Version == existing.Metadata.Version:
+			kept, duplicate := existing, dlc
+			if preferredFile(dlc, existing, opts) {
+				kept, duplicate = dlc, existing
+				target.Dlc[id] = kept
+				unmarkDuplicate(skipped, kept.ExtendedInfo)
+			}
+			skipped[duplicate.ExtendedInfo] = SkippedFile{ReasonCode: REASON_DUPLICATE, ReasonText: "duplicate DLC file (" + kept.ExtendedInfo.FileName + ")"}
+			target.Duplicates = append(target.Duplicates, duplicate)
+		case dlc.Metadata.Version < existing.Metadata.Version:
+			skipped[dlc.ExtendedInfo] = SkippedFile{ReasonCode: REASON_OLD_UPDATE, ReasonText: "old DLC file, newer version exist locally"}
+		default:
+			skipped[existing.ExtendedInfo] = SkippedFile{ReasonCode: REASON_OLD_UPDATE, ReasonText: "old DLC file, newer version exist locally"}
+			target.Dlc[id] = dlc
+		}
+	}
+
+	target.Duplicates = append(target.Duplicates, source.Duplicates...)
+}
+
+// markOldUpdates reports the superseded updates of the title as skipped (REASON_OLD_UPDATE), or drops these
+// entries with KeepOldUpdates. the old DLC files are left as they are
+func markOldUpdates(title *SwitchGameFiles, skipped map[ExtendedFileInfo]SkippedFile, opts GroupOptions) {
+	for version, update := range title.Updates {
+		//the file holding the base (multi-content) is never reported as old
+		if version == title.LatestUpdate || (title.BaseExist && update.ExtendedInfo == title.File.ExtendedInfo) {
+			continue
+		}
+		skip, ok := skipped[update.ExtendedInfo]
+		if opts.KeepOldUpdates {
+			if ok && skip.ReasonCode == REASON_OLD_UPDATE {
+				delete(skipped, update.ExtendedInfo)
+			}
+		} else if !ok {
+			skipped[update.ExtendedInfo] = SkippedFile{ReasonCode: REASON_OLD_UPDATE, ReasonText: "old update file, newer update exist locally"}
+		}
+	}
+}
+
+// unmarkDuplicate drops the duplicate flag of a file which is kept after all
+func unmarkDuplicate(skipped map[ExtendedFileInfo]SkippedFile, file ExtendedFileInfo) {
+	if skip, ok := skipped[file]; ok && skip.ReasonCode == REASON_DUPLICATE {
+		delete(skipped, file)
+	}
+}
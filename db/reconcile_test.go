@@ -0,0 +1,49 @@
+package db
+
+import "testing"
+
+func TestGroupFileNameFallbackUpdate(t *testing.T) {
+	base := testSwitchFile("Game [01007EF00011E000][v0].nsp", "01007ef00011e000", 0)
+	base.Metadata.ApplicationId = "01007ef00011e000"
+	//parsed from the file name - upper cased id, no application id
+	update := testSwitchFile("Game [01007EF00011E800][v65536].nsp", "01007EF00011E800", 65536)
+	update.Metadata.Source = "file_name"
+
+	localDB := Group([]SwitchFileInfo{update, base}, GroupOptions{})
+	if len(localDB.TitlesMap) != 1 {
+		t.Fatalf("expected a single title, got %v", len(localDB.TitlesMap))
+	}
+	title := localDB.TitlesMap["01007ef00011e000"]
+	if title == nil || !title.BaseExist || title.LatestUpdate != 65536 {
+		t.Errorf("expected the base and the update in one title, got %+v", title)
+	}
+}
+
+func TestReconcileGroups(t *testing.T) {
+	base := testSwitchFile("base.nsp", "0100000000010000", 0)
+	oldUpdate := testSwitchFile("update1.nsp", "0100000000010800", 65536)
+	update := testSwitchFile("update2.nsp", "0100000000010800", 131072)
+	dlc := testSwitchFile("dlc.nsp", "0100000000011001", 0)
+
+	//the same title split in two groups (keyed by the id prefix and by the base id)
+	split := Group([]SwitchFileInfo{oldUpdate, dlc}, GroupOptions{})
+	localDB := Group([]SwitchFileInfo{base, update}, GroupOptions{})
+	localDB.TitlesMap["010000000001"] = split.TitlesMap["0100000000010000"]
+
+	if merged := ReconcileGroups(localDB, GroupOptions{}); merged != 1 {
+		t.Errorf("expected 1 merge, got %v", merged)
+	}
+	if len(localDB.TitlesMap) != 1 {
+		t.Fatalf("expected a single title, got %v", len(localDB.TitlesMap))
+	}
+	title := localDB.TitlesMap["0100000000010000"]
+	if !title.BaseExist || len(title.Updates) != 2 || title.LatestUpdate != 131072 || len(title.Dlc) != 1 {
+		t.Errorf("expected the groups to be merged, got %+v", title)
+	}
+	if skipped, ok := localDB.Skipped[oldUpdate.ExtendedInfo]; !ok || skipped.ReasonCode != REASON_OLD_UPDATE {
+		t.Errorf("expected the older update to be skipped as old, got %+v", skipped)
+	}
+	if _, ok := localDB.Skipped[update.ExtendedInfo]; ok {
+		t.Errorf("expected the latest update not to be skipped")
+	}
+}
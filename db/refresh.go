@@ -0,0 +1,105 @@
+package db
+
+import (
+	"errors"
+	"github.com/giwty/switch-library-manager/settings"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrFileNotFound is returned by RefreshFile when the file no longer exists
+var ErrFileNotFound = errors.New("file not found")
+
+// RefreshFile reads the metadata of a single file again, ignoring (and replacing) its cached metadata, and returns the
+// file grouped as a title - to be merged into an existing library (e.g. after a corrupt file was fixed).
+// the title of the first content is returned for a file holding contents of several titles. ErrFileNotFound is returned
+// (and the cached metadata dropped) when the file no longer exists, an error is returned when the file is skipped.
+// the file root is the library folder holding the file (see ExtendedFileInfo.Root)
+func (ldb *LocalSwitchDBManager) RefreshFile(folders []string, filePath string) (*SwitchGameFiles, error) {
+	filePath = filepath.Clean(filePath)
+	if abs, err := filepath.Abs(filePath); err == nil {
+		filePath = abs
+	}
+	//the cache keys start with the path, see fileCacheKey. a previous failure is dropped too, so the file is read again
+	for _, table := range []string{DB_TABLE_FILE_SCAN_METADATA, DB_TABLE_FILE_SCAN_FAILURES} {
+		_, err := ldb.db.DeleteEntries(table, func(key string) bool {
+			return strings.HasPrefix(key, filePath+"|")
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	info, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		return nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, errors.New(filePath + " is a folder")
+	}
+
+	file := newExtendedFileInfo(rootFolder(normalizeFolders(folders, false, -1), filePath), filePath, info)
+	files := []ExtendedFileInfo{file}
+	if settings.ReadSettings(ldb.baseFolder).ScanOptions.ScanZipArchives {
+		if entries := archiveEntries(file); len(entries) != 0 {
+			files = entries
+		}
+	}
+	switchFiles, skipped := ldb.GatherFiles(files, nil)
+	if len(switchFiles) == 0 {
+		for _, skip := range skipped {
+			return nil, errors.New(skip.ReasonText)
+		}
+		return nil, errors.New("no content found in " + filePath)
+	}
+
+	grouped := Group(switchFiles, ldb.groupOptions)
+	return grouped.TitlesMap[groupingKey(switchFiles[0].Metadata.TitleId)], nil
+}
+
+// InvalidateCache drops the cached metadata (and read failures) of the given files, so the next scan reads them
+// again while the other files are still served from the cache. each path is either a file, a folder (all the
+// files below it are invalidated) or a cache key (see fileCacheKey)
+func (ldb *LocalSwitchDBManager) InvalidateCache(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	keys := map[string]struct{}{}
+	roots := make([]string, 0, len(paths))
+	for _, path := range paths {
+		keys[path] = struct{}{}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		roots = append(roots, filepath.Clean(path))
+	}
+	invalid := func(key string) bool {
+		if _, ok := keys[key]; ok {
+			return true
+		}
+		//keys are "path|name|size|modification time[|archive entry]"
+		filePath := strings.SplitN(key, "|", 2)[0]
+		return key != "app_version" && isBelowAny(filePath, roots)
+	}
+	for _, table := range []string{DB_TABLE_FILE_SCAN_METADATA, DB_TABLE_FILE_SCAN_FAILURES} {
+		if _, err := ldb.db.DeleteEntries(table, invalid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateLocalSwitchFilesDBWithRescan scans the folders like CreateLocalSwitchFilesDB, reading the files below the
+// rescan paths again (see InvalidateCache) - the other files are served from the metadata cache. the library cache
+// is not read, as it would hold the previously read metadata of the rescanned files
+func (ldb *LocalSwitchDBManager) CreateLocalSwitchFilesDBWithRescan(folders []string, rescan []string,
+	progress ProgressUpdater, recursive bool) (*LocalSwitchFilesDB, error) {
+	if err := ldb.InvalidateCache(rescan); err != nil {
+		return nil, err
+	}
+	return ldb.CreateLocalSwitchFilesDB(folders, progress, recursive, true)
+}
@@ -0,0 +1,137 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRefreshFile(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+
+	update := filepath.Join(gamesFolder, "Super Mario Odyssey [0100000000010800][v65536].nsp")
+	if err := ioutil.WriteFile(update, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	staleKey := update + "|stale"
+	if err := manager.db.AddEntry(DB_TABLE_FILE_SCAN_METADATA, staleKey, "metadata"); err != nil {
+		t.Fatal(err)
+	}
+
+	//a relative path
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	relative, err := filepath.Rel(wd, update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	title, err := manager.RefreshFile([]string{gamesFolder}, relative)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title.BaseExist || title.LatestUpdate != 65536 || title.TitleId() != "0100000000010000" {
+		t.Errorf("expected the update to be grouped under its title, got %+v", title)
+	}
+	if root := title.Updates[65536].ExtendedInfo.Root; root != gamesFolder {
+		t.Errorf("expected the root %v, got %v", gamesFolder, root)
+	}
+	var cached string
+	if err := manager.db.GetEntry(DB_TABLE_FILE_SCAN_METADATA, staleKey, &cached); err != nil || cached != "" {
+		t.Errorf("expected the cached metadata to be dropped, got %v (%v)", cached, err)
+	}
+
+	if err := os.Remove(update); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.RefreshFile([]string{gamesFolder}, update); err != ErrFileNotFound {
+		t.Errorf("expected ErrFileNotFound, got %v", err)
+	}
+
+	unsupported := filepath.Join(gamesFolder, "readme.txt")
+	if err := ioutil.WriteFile(unsupported, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.RefreshFile([]string{gamesFolder}, unsupported); err == nil {
+		t.Errorf("expected an error for an unsupported file")
+	}
+}
+
+func TestInvalidateCache(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	games := filepath.Join(baseFolder, "games")
+	keys := map[string]string{
+		"mario":   filepath.Join(games, "mario", "Super Mario Odyssey.nsp") + "|Super Mario Odyssey.nsp|10|1",
+		"zelda":   filepath.Join(games, "zelda", "Zelda.nsp") + "|Zelda.nsp|10|1",
+		"kirby":   filepath.Join(games, "Kirby.nsp") + "|Kirby.nsp|10|1",
+		"sibling": filepath.Join(baseFolder, "games2", "Metroid.nsp") + "|Metroid.nsp|10|1",
+	}
+	for _, key := range keys {
+		if err := manager.db.AddEntry(DB_TABLE_FILE_SCAN_METADATA, key, "metadata"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := manager.db.AddEntry(DB_TABLE_FILE_SCAN_FAILURES, keys["zelda"], SkippedFile{ReasonCode: REASON_MALFORMED_FILE}); err != nil {
+		t.Fatal(err)
+	}
+
+	//a folder, a file key and a path without cached entries
+	if err := manager.InvalidateCache([]string{filepath.Join(games, "zelda") + string(os.PathSeparator), keys["kirby"],
+		filepath.Join(games, "missing")}); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]bool{"mario": true, "zelda": false, "kirby": false, "sibling": true}
+	for name, key := range keys {
+		var cached string
+		if err := manager.db.GetEntry(DB_TABLE_FILE_SCAN_METADATA, key, &cached); err != nil {
+			t.Fatal(err)
+		}
+		if (cached != "") != expected[name] {
+			t.Errorf("%v - expected cached %v, got %v", name, expected[name], cached != "")
+		}
+	}
+	if manager.cachedFailure(keys["zelda"]) != nil {
+		t.Errorf("expected the cached failure to be dropped")
+	}
+
+	//a parent folder invalidates all the files below it, but not its siblings with the same prefix
+	if err := manager.InvalidateCache([]string{games}); err != nil {
+		t.Fatal(err)
+	}
+	var cached string
+	if err := manager.db.GetEntry(DB_TABLE_FILE_SCAN_METADATA, keys["mario"], &cached); err != nil || cached != "" {
+		t.Errorf("expected the mario entry to be dropped, got %v (%v)", cached, err)
+	}
+	if err := manager.db.GetEntry(DB_TABLE_FILE_SCAN_METADATA, keys["sibling"], &cached); err != nil || cached == "" {
+		t.Errorf("expected the sibling entry to be kept, got %v (%v)", cached, err)
+	}
+}
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"sort"
+	"sync"
+)
+
+// SafeLibrary guards a library (see LocalSwitchFilesDB) for concurrent use, e.g. a UI reading the library while
+// the watcher applies changes. the library must only be accessed through the wrapper once wrapped
+type SafeLibrary struct {
+	sync.RWMutex
+	library      *LocalSwitchFilesDB
+	groupOptions GroupOptions
+}
+
+// NewSafeLibrary wraps the library, opts is used to resolve the old and duplicate files added with AddFile
+func NewSafeLibrary(localDB *LocalSwitchFilesDB, opts GroupOptions) *SafeLibrary {
+	return &SafeLibrary{library: localDB, groupOptions: opts}
+}
+
+// Get returns a copy of the title holding the given title id (base, update or DLC), see GetByTitleId
+func (s *SafeLibrary) Get(titleId string) (*SwitchGameFiles, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	title, ok := s.library.GetByTitleId(titleId)
+	if !ok {
+		return nil, false
+	}
+	return copyTitle(title), true
+}
+
+// Range calls fn for each title ordered by key, until fn returns false. the library is locked for reading meanwhile -
+// fn must not modify (nor keep) the title, nor call the SafeLibrary methods which modify the library
+func (s *SafeLibrary) Range(fn func(key string, title *SwitchGameFiles) bool) {
+	s.RLock()
+	defer s.RUnlock()
+	keys := make([]string, 0, len(s.library.TitlesMap))
+	for key := range s.library.TitlesMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !fn(key, s.library.TitlesMap[key]) {
+			return
+		}
+	}
+}
+
+// AddFile merges a parsed file (see GatherFiles) into the library, duplicate and old files are resolved
+// against the title the file belongs to. a multi-content file is added once per content
+func (s *SafeLibrary) AddFile(file SwitchFileInfo) {
+	if file.Metadata == nil {
+		return
+	}
+	grouped := Group([]SwitchFileInfo{file}, s.groupOptions)
+
+	s.Lock()
+	defer s.Unlock()
+	if s.library.TitlesMap == nil {
+		s.library.TitlesMap = map[string]*SwitchGameFiles{}
+	}
+	if s.library.Skipped == nil {
+		s.library.Skipped = map[ExtendedFileInfo]SkippedFile{}
+	}
+	known := false
+	for _, existing := range libraryFiles(s.library) {
+		if existing == file.ExtendedInfo {
+			known = true
+			break
+		}
+	}
+	for key, title := range grouped.TitlesMap {
+		if existing, ok := s.library.TitlesMap[key]; ok {
+			mergeTitles(existing, title, s.library.Skipped, s.groupOptions)
+		} else {
+			s.library.TitlesMap[key] = title
+		}
+	}
+	if !known {
+		s.library.NumFiles++
+	}
+}
+
+// Snapshot returns a copy of the titles for read-only iteration without holding the lock, the titles Updates, Dlc
+// and Duplicates are copied (the files metadata is shared, and must not be modified)
+func (s *SafeLibrary) Snapshot() map[string]*SwitchGameFiles {
+	s.RLock()
+	defer s.RUnlock()
+	titles := make(map[string]*SwitchGameFiles, len(s.library.TitlesMap))
+	for key, title := range s.library.TitlesMap {
+		titles[key] = copyTitle(title)
+	}
+	return titles
+}
+
+// Update calls fn with the library locked for writing, e.g. to replace the library after a scan
+func (s *SafeLibrary) Update(fn func(localDB *LocalSwitchFilesDB)) {
+	s.Lock()
+	defer s.Unlock()
+	fn(s.library)
+}
+
+// View calls fn with the library locked for reading, fn must not modify (nor keep) the library
+func (s *SafeLibrary) View(fn func(localDB *LocalSwitchFilesDB)) {
+	s.RLock()
+	defer s.RUnlock()
+	fn(s.library)
+}
+
+func copyTitle(title *SwitchGameFiles) *SwitchGameFiles {
+	titleCopy := *title
+	titleCopy.Updates = make(map[int]SwitchFileInfo, len(title.Updates))
+	for version, update := range title.Updates {
+		titleCopy.Updates[version] = update
+	}
+	titleCopy.Dlc = make(map[string]SwitchFileInfo, len(title.Dlc))
+	for id, dlc := range title.Dlc {
+		titleCopy.Dlc[id] = dlc
+	}
+	titleCopy.Duplicates = append([]SwitchFileInfo(nil), title.Duplicates...)
+	return &titleCopy
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestSafeLibrary(t *testing.T) {
+	localDB := Group([]SwitchFileInfo{testSwitchFile("base.nsp", "0100000000010000", 0)}, GroupOptions{})
+	library := NewSafeLibrary(localDB, GroupOptions{})
+
+	wg := sync.WaitGroup{}
+	for w := 0; w < 4; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 1; i <= 50; i++ {
+				library.AddFile(testSwitchFile(fmt.Sprintf("update%v-%v.nsp", w, i), "0100000000010800", (w*50+i)*65536))
+				library.AddFile(testSwitchFile(fmt.Sprintf("dlc%v-%v.nsp", w, i), fmt.Sprintf("01000000000%v%04x", 1+w, 0x1000+i), 0))
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if title, ok := library.Get("0100000000010000"); ok {
+					for range title.Updates {
+					}
+				}
+				library.Range(func(key string, title *SwitchGameFiles) bool {
+					return len(title.Dlc) >= 0
+				})
+				for _, title := range library.Snapshot() {
+					for range title.Dlc {
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	title, ok := library.Get("0100000000010000")
+	if !ok || !title.BaseExist || len(title.Updates) != 200 || title.LatestUpdate != 200*65536 || len(title.Dlc) != 50 {
+		t.Fatalf("unexpected title %+v", title)
+	}
+	numFiles := 0
+	library.View(func(localDB *LocalSwitchFilesDB) {
+		numFiles = localDB.NumFiles
+		if len(localDB.Skipped) != 199 {
+			t.Errorf("expected 199 old updates, got %v", len(localDB.Skipped))
+		}
+	})
+	if numFiles != 401 {
+		t.Errorf("expected 401 files, got %v", numFiles)
+	}
+
+	//the copies are not affected by later changes
+	snapshot := library.Snapshot()
+	library.AddFile(testSwitchFile("dlc.nsp", "0100000000011fff", 0))
+	if len(snapshot["0100000000010000"].Dlc) != 50 {
+		t.Errorf("expected the snapshot to be left untouched")
+	}
+}
@@ -0,0 +1,45 @@
+package db
+
+import "go.uber.org/zap"
+
+// SetRecheckFailedFiles overrides the configured recheck_failed_files setting - read the files which failed to be
+// read by a previous scan again, instead of skipping them with their previous reason
+func (ldb *LocalSwitchDBManager) SetRecheckFailedFiles(recheck bool) {
+	ldb.recheckFailed = recheck
+}
+
+// cachedFailure returns the reason the deep read of the file failed during a previous scan, nil when the file
+// wasn't read before, was read successfully, or the failed files are to be read again.
+// the failures are keyed as the metadata cache (see fileCacheKey), a modified file is read again
+func (ldb *LocalSwitchDBManager) cachedFailure(fileKey string) *SkippedFile {
+	if ldb.recheckFailed {
+		return nil
+	}
+	var skip *SkippedFile
+	if err := ldb.db.GetEntry(DB_TABLE_FILE_SCAN_FAILURES, fileKey, &skip); err != nil {
+		zap.S().Warnf("%v", err)
+		return nil
+	}
+	return skip
+}
+
+// cacheFailure records the reason the deep read of the file failed (nil when it succeeded). the files which couldn't
+// be read (permission denied, timeout) or failed because of the keys are not recorded, as the next scan may be able
+// to read them
+func (ldb *LocalSwitchDBManager) cacheFailure(fileKey string, skip *SkippedFile) {
+	if ldb.db.ReadOnly() {
+		return
+	}
+	var err error
+	if skip == nil {
+		//only a forced recheck reads a file which may have failed before
+		if ldb.recheckFailed {
+			err = ldb.db.DeleteEntry(DB_TABLE_FILE_SCAN_FAILURES, fileKey)
+		}
+	} else if skip.ReasonCode != REASON_PERMISSION && skip.ReasonCode != REASON_TIMEOUT && skip.ReasonCode != REASON_KEY_ERROR {
+		err = ldb.db.AddEntry(DB_TABLE_FILE_SCAN_FAILURES, fileKey, skip)
+	}
+	if err != nil {
+		zap.S().Warnf("%v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"github.com/giwty/switch-library-manager/settings"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCachedScanFailures(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	if err := ioutil.WriteFile(filepath.Join(baseFolder, "prod.keys"), []byte(testProdKeys), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := settings.InitSwitchKeys(baseFolder); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		settings.ReadSettings(baseFolder).Prodkeys = ""
+		os.Remove(filepath.Join(baseFolder, "prod.keys"))
+		settings.InitSwitchKeys(baseFolder)
+	}()
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+	filePath := filepath.Join("/remote", "Super Mario Odyssey [0100000000010000][v0].nsp")
+	source := &memoryFileSource{files: map[string][]byte{filePath: []byte("not a PFS0 header")}}
+	manager.SetFileSource(source)
+	file := ExtendedFileInfo{FileName: filepath.Base(filePath), BaseFolder: "/remote", Size: 17}
+
+	scan := func(file ExtendedFileInfo) SkippedFile {
+		_, skipped := manager.GatherFiles([]ExtendedFileInfo{file}, nil)
+		skip, ok := skipped[file]
+		if !ok || skip.ReasonCode != REASON_MALFORMED_FILE {
+			t.Fatalf("expected the file to be skipped as malformed, got %+v", skipped)
+		}
+		return skip
+	}
+	first := scan(file)
+	//the failure is cached, the file is not read again
+	if second := scan(file); second != first {
+		t.Errorf("expected the cached failure %+v, got %+v", first, second)
+	}
+	if len(source.opened) != 1 {
+		t.Errorf("expected the file to be read once, got %v", source.opened)
+	}
+
+	//a modified file is read again
+	modified := file
+	modified.Size = 18
+	scan(modified)
+	if len(source.opened) != 2 {
+		t.Errorf("expected the modified file to be read, got %v", source.opened)
+	}
+
+	manager.SetRecheckFailedFiles(true)
+	scan(file)
+	if len(source.opened) != 3 {
+		t.Errorf("expected the failed file to be read again, got %v", source.opened)
+	}
+
+	if err := manager.ClearScanData(); err != nil {
+		t.Fatal(err)
+	}
+	manager.SetRecheckFailedFiles(false)
+	scan(file)
+	if len(source.opened) != 4 {
+		t.Errorf("expected the file to be read after clearing the scan data, got %v", source.opened)
+	}
+}
+
+func TestScanFailureReasons(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	if err := ioutil.WriteFile(filepath.Join(baseFolder, "prod.keys"), []byte(testProdKeys), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := settings.InitSwitchKeys(baseFolder); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		settings.ReadSettings(baseFolder).Prodkeys = ""
+		os.Remove(filepath.Join(baseFolder, "prod.keys"))
+		settings.InitSwitchKeys(baseFolder)
+	}()
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+	expected := map[string]string{
+		"Super Mario Odyssey [0100000000010000][v0].xci": "failed to read XCI",
+		"Zelda [0100000000020000][v0].nsz":               "failed to read NSZ",
+	}
+	source := &memoryFileSource{files: map[string][]byte{}}
+	var files []ExtendedFileInfo
+	for name := range expected {
+		source.files[filepath.Join("/remote", name)] = []byte("not a valid header")
+		files = append(files, ExtendedFileInfo{FileName: name, BaseFolder: "/remote", Size: 18})
+	}
+	manager.SetFileSource(source)
+
+	_, skipped := manager.GatherFiles(files, nil)
+	for _, file := range files {
+		if skip := skipped[file]; !strings.HasPrefix(skip.ReasonText, expected[file.FileName]) {
+			t.Errorf("%v - expected the reason %v, got %+v", file.FileName, expected[file.FileName], skip)
+		}
+	}
+}
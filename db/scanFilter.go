@@ -0,0 +1,70 @@
+package db
+
+import (
+	"fmt"
+	"github.com/giwty/switch-library-manager/settings"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// scanFilter decides which files and folders are left out of the scan (see settings.ScanFilter)
+type scanFilter struct {
+	skipDotFiles      bool
+	skipWindowsHidden bool
+	//lower case patterns, matched against the lower case names
+	excludeGlobs []string
+	//lower case patterns using "/" separators, matched against the lower case full paths
+	excludePaths []string
+}
+
+// newScanFilter validates the exclude patterns of the settings
+func newScanFilter(options settings.ScanFilter, exclusions settings.ScanExclusions) (scanFilter, error) {
+	filter := scanFilter{skipDotFiles: options.GetSkipDotFiles(), skipWindowsHidden: options.SkipWindowsHidden}
+	for _, glob := range options.ExcludeGlobs {
+		if strings.TrimSpace(glob) == "" {
+			continue
+		}
+		glob = strings.ToLower(glob)
+		if _, err := filepath.Match(glob, ""); err != nil {
+			return filter, fmt.Errorf("invalid scan_filter.exclude_globs pattern [%v] - %v", glob, err)
+		}
+		filter.excludeGlobs = append(filter.excludeGlobs, glob)
+	}
+	for _, glob := range exclusions.PathGlobs {
+		if strings.TrimSpace(glob) == "" {
+			continue
+		}
+		glob = strings.ToLower(filepath.ToSlash(filepath.Clean(glob)))
+		if _, err := path.Match(glob, ""); err != nil {
+			return filter, fmt.Errorf("invalid scan_exclusions.path_globs pattern [%v] - %v", glob, err)
+		}
+		filter.excludePaths = append(filter.excludePaths, glob)
+	}
+	return filter, nil
+}
+
+// skip returns true when the file (or folder) should be left out of the scan
+func (f scanFilter) skip(filePath string, info os.FileInfo) bool {
+	name := info.Name()
+	if f.skipDotFiles && !info.IsDir() && strings.HasPrefix(name, ".") {
+		return true
+	}
+	if f.skipWindowsHidden && isHiddenFile(filePath, info) {
+		return true
+	}
+	lowerName := strings.ToLower(name)
+	for _, glob := range f.excludeGlobs {
+		if matched, _ := filepath.Match(glob, lowerName); matched {
+			return true
+		}
+	}
+	lowerPath := strings.ToLower(filepath.ToSlash(filepath.Clean(filePath)))
+	for _, glob := range f.excludePaths {
+		if matched, _ := path.Match(glob, lowerPath); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+// +build !windows
+
+package db
+
+import "os"
+
+// isHiddenFile - files have no hidden attribute outside of Windows (dot files are handled by skipDotFiles)
+func isHiddenFile(path string, info os.FileInfo) bool {
+	return false
+}
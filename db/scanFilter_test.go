@@ -0,0 +1,74 @@
+package db
+
+import (
+	"github.com/giwty/switch-library-manager/settings"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestScanFilter(t *testing.T) {
+	root, err := ioutil.TempDir("", "slm-filter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	for _, name := range []string{"game.nsp", ".game.nsp", "Thumbs.db", "desktop.ini", "junk/update.nsp", ".hidden/dlc.nsp"} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scan := func(options settings.ScanFilter) []string {
+		filter, err := newScanFilter(options, settings.ScanExclusions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var files []ExtendedFileInfo
+		if _, err := scanFolder(root, true, false, false, filter, &files, nil, scanLimits{maxDepth: 64, maxFiles: 100, folderDepth: -1}); err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, file := range files {
+			rel, _ := filepath.Rel(root, filepath.Join(file.BaseFolder, file.FileName))
+			names = append(names, filepath.ToSlash(rel))
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	//dot files are skipped by default, dot folders are still scanned
+	names := scan(settings.ScanFilter{})
+	expected := []string{".hidden/dlc.nsp", "Thumbs.db", "desktop.ini", "game.nsp", "junk/update.nsp"}
+	if len(names) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, names)
+		}
+	}
+
+	skipDotFiles := false
+	names = scan(settings.ScanFilter{SkipDotFiles: &skipDotFiles, ExcludeGlobs: []string{"thumbs.db", "*.INI", "junk"}})
+	expected = []string{".game.nsp", ".hidden/dlc.nsp", "game.nsp"}
+	if len(names) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, names)
+		}
+	}
+
+	if _, err := newScanFilter(settings.ScanFilter{ExcludeGlobs: []string{"[a-"}}, settings.ScanExclusions{}); err == nil {
+		t.Errorf("expected an error for an invalid pattern")
+	}
+}
@@ -0,0 +1,23 @@
+package db
+
+import (
+	"os"
+	"syscall"
+)
+
+// isHiddenFile returns true for the files with the hidden or system attribute
+func isHiddenFile(path string, info os.FileInfo) bool {
+	attributes, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		pathPtr, err := syscall.UTF16PtrFromString(path)
+		if err != nil {
+			return false
+		}
+		value, err := syscall.GetFileAttributes(pathPtr)
+		if err != nil {
+			return false
+		}
+		return value&(syscall.FILE_ATTRIBUTE_HIDDEN|syscall.FILE_ATTRIBUTE_SYSTEM) != 0
+	}
+	return attributes.FileAttributes&(syscall.FILE_ATTRIBUTE_HIDDEN|syscall.FILE_ATTRIBUTE_SYSTEM) != 0
+}
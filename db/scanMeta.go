@@ -0,0 +1,53 @@
+package db
+
+import (
+	"errors"
+	"github.com/giwty/switch-library-manager/settings"
+	"go.uber.org/zap"
+	"sort"
+	"time"
+)
+
+// ErrNoScanInfo is returned by LastScanInfo when no scan was recorded (or the cache is disabled)
+var ErrNoScanInfo = errors.New("no scan recorded")
+
+// ScanMeta summarizes the last successful scan
+type ScanMeta struct {
+	Time       time.Time `json:"time"`
+	NumFiles   int       `json:"num_files"`
+	NumTitles  int       `json:"num_titles"`
+	NumSkipped int       `json:"num_skipped"`
+	Folders    []string  `json:"folders"`
+	//the application version which ran the scan (settings.SLM_VERSION)
+	AppVersion string `json:"app_version"`
+	//the files were identified by their name only (see settings.SCAN_DEPTH_QUICK)
+	QuickScan bool `json:"quick_scan"`
+}
+
+// LastScanInfo returns the summary of the last successful scan without scanning, ErrNoScanInfo is returned
+// when there is none
+func (ldb *LocalSwitchDBManager) LastScanInfo() (ScanMeta, error) {
+	var meta *ScanMeta
+	if err := ldb.db.GetEntry(DB_TABLE_LOCAL_LIBRARY, "scan_meta", &meta); err != nil {
+		return ScanMeta{}, err
+	}
+	if meta == nil {
+		return ScanMeta{}, ErrNoScanInfo
+	}
+	return *meta, nil
+}
+
+// saveScanMeta records the summary of a successful scan (see LastScanInfo)
+func (ldb *LocalSwitchDBManager) saveScanMeta(folders []string, numFiles int, titles map[string]*SwitchGameFiles,
+	skipped map[ExtendedFileInfo]SkippedFile) {
+	if ldb.db.ReadOnly() {
+		return
+	}
+	sortedFolders := append([]string(nil), folders...)
+	sort.Strings(sortedFolders)
+	meta := ScanMeta{Time: time.Now(), NumFiles: numFiles, NumTitles: len(titles), NumSkipped: len(skipped),
+		Folders: sortedFolders, AppVersion: settings.SLM_VERSION, QuickScan: ldb.quickScan}
+	if err := ldb.db.AddEntry(DB_TABLE_LOCAL_LIBRARY, "scan_meta", meta); err != nil {
+		zap.S().Warnf("failed to save the scan summary - %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"github.com/giwty/switch-library-manager/settings"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLastScanInfo(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+
+	for _, fileName := range []string{
+		"Super Mario Odyssey [0100000000010000][v0].nsp",
+		"Super Mario Odyssey [0100000000010800][v65536].nsp",
+		"Zelda [0100000000020000][v0].nsp",
+		"readme.txt",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(gamesFolder, fileName), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	if _, err := manager.LastScanInfo(); err != ErrNoScanInfo {
+		t.Fatalf("expected ErrNoScanInfo before the first scan, got %v", err)
+	}
+
+	localDB, err := manager.CreateLocalSwitchFilesDB([]string{gamesFolder}, nil, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := manager.LastScanInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.NumFiles != localDB.NumFiles || meta.NumTitles != len(localDB.TitlesMap) ||
+		meta.NumSkipped != len(localDB.Skipped) {
+		t.Errorf("unexpected counts %+v for library with %v files, %v titles, %v skipped",
+			meta, localDB.NumFiles, len(localDB.TitlesMap), len(localDB.Skipped))
+	}
+	if len(meta.Folders) != 1 || meta.Folders[0] != gamesFolder {
+		t.Errorf("unexpected folders %v", meta.Folders)
+	}
+	if meta.AppVersion != settings.SLM_VERSION || meta.Time.IsZero() {
+		t.Errorf("unexpected version/time %+v", meta)
+	}
+
+	//serving the library from the cache is not a scan
+	before := meta.Time
+	if _, err := manager.CreateLocalSwitchFilesDB([]string{gamesFolder}, nil, true, false); err != nil {
+		t.Fatal(err)
+	}
+	meta, err = manager.LastScanInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.Time.Equal(before) {
+		t.Errorf("expected the cached library to keep the scan time %v, got %v", before, meta.Time)
+	}
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"sort"
+	"strconv"
+)
+
+type SkippedEntry struct {
+	File ExtendedFileInfo
+	SkippedFile
+}
+
+type SkippedGroup struct {
+	ReasonCode int
+	Reason     string
+	//sorted by file name
+	Files []SkippedEntry
+}
+
+// ReasonName returns a human readable name of a skipped file reason code (e.g. REASON_DUPLICATE)
+func ReasonName(code int) string {
+	switch code {
+	case REASON_UNSUPPORTED_TYPE:
+		return "unsupported file type"
+	case REASON_DUPLICATE:
+		return "duplicate"
+	case REASON_OLD_UPDATE:
+		return "old update"
+	case REASON_UNRECOGNISED:
+		return "unrecognised"
+	case REASON_MALFORMED_FILE:
+		return "malformed file"
+	case REASON_CORRUPT:
+		return "corrupt"
+	case REASON_EMPTY_FILE:
+		return "empty file"
+	case REASON_PERMISSION:
+		return "permission denied"
+	case REASON_EXCLUDED:
+		return "excluded"
+	case REASON_TIMEOUT:
+		return "timeout"
+	case REASON_KEY_ERROR:
+		return "key error"
+	}
+	return "unknown reason (" + strconv.Itoa(code) + ")"
+}
+
+// SkippedSummary groups the skipped files by reason code (sorted by code), the files of each group are sorted by name
+func (l *LocalSwitchFilesDB) SkippedSummary() []SkippedGroup {
+	groups := map[int]*SkippedGroup{}
+	for file, skipped := range l.Skipped {
+		group, ok := groups[skipped.ReasonCode]
+		if !ok {
+			group = &SkippedGroup{ReasonCode: skipped.ReasonCode, Reason: ReasonName(skipped.ReasonCode)}
+			groups[skipped.ReasonCode] = group
+		}
+		group.Files = append(group.Files, SkippedEntry{File: file, SkippedFile: skipped})
+	}
+
+	result := make([]SkippedGroup, 0, len(groups))
+	for _, group := range groups {
+		sort.Slice(group.Files, func(i, j int) bool {
+			if group.Files[i].File.FileName != group.Files[j].File.FileName {
+				return group.Files[i].File.FileName < group.Files[j].File.FileName
+			}
+			return group.Files[i].File.BaseFolder < group.Files[j].File.BaseFolder
+		})
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ReasonCode < result[j].ReasonCode
+	})
+	return result
+}
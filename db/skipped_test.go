@@ -0,0 +1,40 @@
+package db
+
+import "testing"
+
+func TestSkippedSummary(t *testing.T) {
+	localDB := Group([]SwitchFileInfo{
+		testSwitchFile("base.nsp", "0100000000010000", 0),
+		testSwitchFile("copy b.nsp", "0100000000010000", 0),
+		testSwitchFile("copy a.nsp", "0100000000010000", 0),
+		testSwitchFile("update1.nsp", "0100000000010800", 65536),
+		testSwitchFile("update2.nsp", "0100000000010800", 131072),
+	}, GroupOptions{})
+
+	summary := localDB.SkippedSummary()
+	if len(summary) != 2 {
+		t.Fatalf("expected 2 reason groups, got %+v", summary)
+	}
+	if summary[0].ReasonCode != REASON_DUPLICATE || summary[0].Reason != "duplicate" || len(summary[0].Files) != 2 ||
+		summary[0].Files[0].File.FileName != "copy a.nsp" || summary[0].Files[1].File.FileName != "copy b.nsp" {
+		t.Errorf("unexpected duplicates group %+v", summary[0])
+	}
+	if summary[1].ReasonCode != REASON_OLD_UPDATE || len(summary[1].Files) != 1 || summary[1].Files[0].File.FileName != "update1.nsp" {
+		t.Errorf("unexpected old updates group %+v", summary[1])
+	}
+	if ReasonName(100) != "unknown reason (100)" {
+		t.Errorf("unexpected name %v", ReasonName(100))
+	}
+}
+
+func TestReasonCodes(t *testing.T) {
+	//the codes are stored with the cached libraries
+	expected := []int{REASON_UNSUPPORTED_TYPE, REASON_DUPLICATE, REASON_OLD_UPDATE, REASON_UNRECOGNISED,
+		REASON_MALFORMED_FILE, REASON_CORRUPT, REASON_EMPTY_FILE, REASON_PERMISSION, REASON_EXCLUDED, REASON_TIMEOUT,
+		REASON_KEY_ERROR}
+	for i, code := range expected {
+		if code != i+2 {
+			t.Errorf("reason %v (%v) - expected code %v, got %v", i, ReasonName(code), i+2, code)
+		}
+	}
+}
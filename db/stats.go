@@ -0,0 +1,92 @@
+package db
+
+import "strings"
+
+type LibraryStats struct {
+	NumFiles int `json:"num_files"`
+	//titles having a base game
+	BaseGames int `json:"base_games"`
+	//base games whose updates are all present, see StatsWithCatalog
+	UpdatesComplete int `json:"updates_complete"`
+	Updates         int `json:"updates"`
+	Dlc             int `json:"dlc"`
+	//titles whose base is a split file
+	SplitGames int `json:"split_games"`
+	//size (in bytes) of the base, update and DLC files, all the parts are counted for split files
+	DiskBytes int64 `json:"disk_bytes"`
+	//number of base, update and DLC files by file format (nsp, nsz, xci, xcz)
+	ByExtension map[string]int `json:"by_extension"`
+}
+
+// Stats returns the library totals, without a catalog a base game has all its updates when no update
+// is missing between the first update and the latest local one (see StatsWithCatalog)
+func (l *LocalSwitchFilesDB) Stats() LibraryStats {
+	return l.StatsWithCatalog(nil)
+}
+
+// StatsWithCatalog returns the library totals, computed from the scanned titles (no rescan is needed).
+// available maps the title id (or its prefix) to the latest known update version (see MissingUpdates) and is optional,
+// with it a base game only has all its updates when the latest available update is present as well.
+// files holding several contents (e.g. a base and its update) are counted once
+func (l *LocalSwitchFilesDB) StatsWithCatalog(available map[string]int) LibraryStats {
+	stats := LibraryStats{NumFiles: l.NumFiles, ByExtension: map[string]int{}}
+
+	var outdated map[*SwitchGameFiles]bool
+	if available != nil {
+		outdated = map[*SwitchGameFiles]bool{}
+		for _, missing := range l.MissingUpdates(available) {
+			outdated[missing.Title] = true
+		}
+	}
+
+	counted := map[string]struct{}{}
+	countFile := func(file SwitchFileInfo) {
+		key := fileKey(file.ExtendedInfo)
+		if _, ok := counted[key]; ok {
+			return
+		}
+		counted[key] = struct{}{}
+		size := file.ExtendedInfo.Size
+		if file.Split != nil && file.Split.TotalSize != 0 {
+			size = file.Split.TotalSize
+		}
+		stats.DiskBytes += size
+		format := file.Format
+		if format == "" {
+			format = getFileFormat(file.ExtendedInfo.ContentName(), nil)
+		}
+		stats.ByExtension[strings.ToLower(format)]++
+	}
+
+	for _, switchFile := range l.TitlesMap {
+		if switchFile.BaseExist {
+			stats.BaseGames++
+			if switchFile.IsSplit {
+				stats.SplitGames++
+			}
+			if !outdated[switchFile] && !hasMissingUpdates(switchFile) {
+				stats.UpdatesComplete++
+			}
+			countFile(switchFile.File)
+		}
+		for _, update := range switchFile.Updates {
+			countFile(update)
+		}
+		for _, dlc := range switchFile.Dlc {
+			countFile(dlc)
+		}
+		stats.Updates += len(switchFile.Updates)
+		stats.Dlc += len(switchFile.Dlc)
+	}
+	return stats
+}
+
+// hasMissingUpdates returns true when an update between the first update and the latest local one is missing
+func hasMissingUpdates(switchFile *SwitchGameFiles) bool {
+	for version := updateVersionStep; version < switchFile.LatestUpdate; version += updateVersionStep {
+		if _, ok := switchFile.Updates[version]; !ok {
+			return true
+		}
+	}
+	return false
+}
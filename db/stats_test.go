@@ -0,0 +1,51 @@
+package db
+
+import (
+	"github.com/giwty/switch-library-manager/fileio"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	split := testSwitchFile("00", "0100000000030000", 0)
+	split.Format = "xci"
+	split.Split = &fileio.SplitFileInfo{NumParts: 3, TotalSize: 30, Format: "xci"}
+	multiContent := testSwitchFile("multi.nsp", "0100000000040000", 0)
+	multiContentUpdate := testSwitchFile("multi.nsp", "0100000000040800", 65536)
+	files := []SwitchFileInfo{
+		testSwitchFile("base.nsp", "0100000000010000", 0),
+		testSwitchFile("update1.nsp", "0100000000010800", 65536),
+		testSwitchFile("update2.nsp", "0100000000010800", 131072),
+		testSwitchFile("dlc1.nsp", "0100000000011001", 0),
+		testSwitchFile("dlc2.nsp", "0100000000011002", 0),
+		//update v65536 is missing
+		testSwitchFile("base2.nsz", "0100000000020000", 0),
+		testSwitchFile("update2.nsz", "0100000000020800", 131072),
+		split,
+		multiContent,
+		multiContentUpdate,
+	}
+	files[5].Format = "nsz"
+	files[6].Format = "nsz"
+	localDB := Group(files, GroupOptions{})
+	localDB.NumFiles = 9
+
+	stats := localDB.Stats()
+	if stats.NumFiles != 9 || stats.BaseGames != 4 || stats.Updates != 4 || stats.Dlc != 2 || stats.SplitGames != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if stats.UpdatesComplete != 3 {
+		t.Errorf("expected 3 games with all their updates, got %v", stats.UpdatesComplete)
+	}
+	//8 single part files of 1 byte (the multi-content file is counted once) and the split file
+	if stats.DiskBytes != 8+30 {
+		t.Errorf("expected %v bytes, got %v", 8+30, stats.DiskBytes)
+	}
+	if stats.ByExtension["nsp"] != 6 || stats.ByExtension["nsz"] != 2 || stats.ByExtension["xci"] != 1 {
+		t.Errorf("unexpected counts by extension %v", stats.ByExtension)
+	}
+
+	stats = localDB.StatsWithCatalog(map[string]int{"0100000000010000": 196608})
+	if stats.UpdatesComplete != 2 {
+		t.Errorf("expected 2 up to date games with a catalog, got %v", stats.UpdatesComplete)
+	}
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"github.com/giwty/switch-library-manager/settings"
+	"sort"
+)
+
+// CreateLocalSwitchFilesDBStream scans the folders like CreateLocalSwitchFilesDB (the library cache is not read), and
+// reports the titles and skipped files as the files are resolved instead of returning the whole library.
+// the callbacks are called from a single goroutine, both are optional:
+//   - onSkip is called once per skipped file - files which can't be read are reported as soon as they are resolved,
+//     the grouping decisions (duplicate and old files) once all the files are read
+//   - onGame is called with a snapshot of the title each time a file adds content to it (base, update or DLC),
+//     and once more with the final title once all the files are read - the snapshots are grouped from the files read
+//     so far (e.g. an update may later be superseded), only the last call for a title holds its final state.
+//     the snapshots are not modified afterwards
+func (ldb *LocalSwitchDBManager) CreateLocalSwitchFilesDBStream(folders []string, recursive bool,
+	onGame func(*SwitchGameFiles), onSkip func(ExtendedFileInfo, SkippedFile)) error {
+
+	options := settings.ReadSettings(ldb.baseFolder).ScanOptions
+	files := []ExtendedFileInfo{}
+	if _, err := scanFolders(folders, recursive, options, &files, nil); err != nil {
+		return err
+	}
+	files = uniqueFiles(files)
+	checkKeys()
+
+	//the contents read so far, by title
+	resolved := map[string][]SwitchFileInfo{}
+	onFile := func(file ExtendedFileInfo, switchFiles []SwitchFileInfo, skip *SkippedFile) {
+		if skip != nil && onSkip != nil {
+			onSkip(file, *skip)
+		}
+		var keys []string
+		for _, switchFile := range switchFiles {
+			key := groupingKey(switchFile.Metadata.TitleId)
+			if len(keys) == 0 || keys[len(keys)-1] != key {
+				keys = append(keys, key)
+			}
+			resolved[key] = append(resolved[key], switchFile)
+		}
+		if onGame == nil {
+			return
+		}
+		for _, key := range keys {
+			if title, ok := Group(resolved[key], ldb.groupOptions).TitlesMap[key]; ok {
+				onGame(title)
+			}
+		}
+	}
+	switchFiles, skipped := ldb.gatherFiles(files, nil, onFile)
+
+	grouped := Group(switchFiles, ldb.groupOptions)
+	titles := grouped.TitlesMap
+	for file, skip := range grouped.Skipped {
+		skipped[file] = skip
+	}
+	if onSkip != nil {
+		groupSkipped := make([]ExtendedFileInfo, 0, len(grouped.Skipped))
+		for file := range grouped.Skipped {
+			groupSkipped = append(groupSkipped, file)
+		}
+		sort.Slice(groupSkipped, func(i, j int) bool {
+			return fileKey(groupSkipped[i]) < fileKey(groupSkipped[j])
+		})
+		for _, file := range groupSkipped {
+			onSkip(file, grouped.Skipped[file])
+		}
+	}
+	if onGame != nil {
+		keys := make([]string, 0, len(titles))
+		for key := range titles {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			onGame(titles[key])
+		}
+	}
+
+	ldb.saveScanMeta(folders, len(files), titles, skipped)
+	if !ldb.quickScan {
+		ldb.db.AddEntry(DB_TABLE_LOCAL_LIBRARY, "files", files)
+		ldb.db.AddEntry(DB_TABLE_LOCAL_LIBRARY, "skipped", skipped)
+		ldb.db.AddEntry(DB_TABLE_LOCAL_LIBRARY, "titles", titles)
+		ldb.updateTitleNames(titles)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateLocalSwitchFilesDBStream(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+
+	for _, fileName := range []string{
+		"Super Mario Odyssey [0100000000010000][v0].nsp",
+		"Super Mario Odyssey [0100000000010800][v65536].nsp",
+		"Super Mario Odyssey [0100000000010800][v131072].nsp",
+		"Super Mario Odyssey [0100000000011001][v0].nsp",
+		"Zelda [0100000000020000][v0].nsp",
+		"readme.txt",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(gamesFolder, fileName), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	calls := map[string]int{}
+	last := map[string]*SwitchGameFiles{}
+	skipped := map[string]SkippedFile{}
+	err = manager.CreateLocalSwitchFilesDBStream([]string{gamesFolder}, true, func(title *SwitchGameFiles) {
+		key := groupingKey(title.File.Metadata.TitleId)
+		if !title.BaseExist {
+			for _, update := range title.Updates {
+				key = groupingKey(update.Metadata.TitleId)
+			}
+			for _, dlc := range title.Dlc {
+				key = groupingKey(dlc.Metadata.TitleId)
+			}
+		}
+		calls[key]++
+		last[key] = title
+	}, func(file ExtendedFileInfo, skip SkippedFile) {
+		skipped[file.FileName] = skip
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//4 files add content to the first title, then the final title
+	if calls["0100000000010000"] != 5 || calls["0100000000020000"] != 2 {
+		t.Errorf("unexpected onGame calls %v", calls)
+	}
+	title := last["0100000000010000"]
+	if !title.BaseExist || title.LatestUpdate != 131072 || len(title.Dlc) != 1 {
+		t.Errorf("unexpected final title %+v", title)
+	}
+	if len(skipped) != 2 || skipped["readme.txt"].ReasonCode != REASON_UNSUPPORTED_TYPE ||
+		skipped["Super Mario Odyssey [0100000000010800][v65536].nsp"].ReasonCode != REASON_OLD_UPDATE {
+		t.Errorf("unexpected skipped files %v", skipped)
+	}
+
+	//same outcome as a full scan
+	localDB, err := manager.CreateLocalSwitchFilesDB([]string{gamesFolder}, nil, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(localDB.TitlesMap) != len(last) || len(localDB.Skipped) != len(skipped) {
+		t.Errorf("expected the stream to match the scan, got %v titles and %v skipped", len(last), len(skipped))
+	}
+}
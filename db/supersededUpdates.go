@@ -0,0 +1,76 @@
+package db
+
+import (
+	"github.com/giwty/switch-library-manager/switchfs"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DeleteSupersededUpdates deletes the files flagged as old updates (REASON_OLD_UPDATE) - all the parts of split files -
+// and drops them from the library (skipped files and Updates) and from the metadata cache. with dryRun nothing is
+// deleted. the paths (to be) deleted are returned sorted, files stored in archives are left untouched.
+// the deletion stops at the first file which can't be deleted, the paths deleted so far are returned with the error
+func (ldb *LocalSwitchDBManager) DeleteSupersededUpdates(localDB *LocalSwitchFilesDB, dryRun bool) ([]string, error) {
+	var files []ExtendedFileInfo
+	for file, skipped := range localDB.Skipped {
+		//deleting the archive would delete the other files as well
+		if skipped.ReasonCode != REASON_OLD_UPDATE || file.ArchiveEntry != "" {
+			continue
+		}
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return filepath.Join(files[i].BaseFolder, files[i].FileName) < filepath.Join(files[j].BaseFolder, files[j].FileName)
+	})
+
+	var deleted []string
+	for _, file := range files {
+		filePath := filepath.Join(file.BaseFolder, file.FileName)
+		paths := []string{filePath}
+		if ldb.splitSchemes.IsSplitPart(filePath) {
+			parts, _, err := ldb.splitSchemes.FileParts(filePath)
+			if err != nil {
+				return deleted, err
+			}
+			paths = parts
+		}
+		if dryRun {
+			deleted = append(deleted, paths...)
+			continue
+		}
+
+		for _, path := range paths {
+			zap.S().Infof("Deleting superseded update: %v", path)
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				sort.Strings(deleted)
+				return deleted, err
+			}
+			deleted = append(deleted, path)
+		}
+		//parts stored in a folder named after the file (e.g. "Game.nsp/00"), fails when the folder isn't empty
+		if len(paths) > 1 && switchfs.ClassifyFileName(filepath.Clean(file.BaseFolder)) != switchfs.FileType_Unsupported {
+			_ = os.Remove(filepath.Clean(file.BaseFolder))
+		}
+
+		//the cache keys start with the path, see fileCacheKey
+		if _, err := ldb.db.DeleteEntries(DB_TABLE_FILE_SCAN_METADATA, func(key string) bool {
+			return strings.HasPrefix(key, filePath+"|")
+		}); err != nil {
+			zap.S().Warnf("failed to delete the cached metadata of %v - %v", filePath, err)
+		}
+		delete(localDB.Skipped, file)
+		localDB.NumFiles--
+		for _, title := range localDB.TitlesMap {
+			for version, update := range title.Updates {
+				if update.ExtendedInfo == file {
+					delete(title.Updates, version)
+				}
+			}
+		}
+	}
+	sort.Strings(deleted)
+	return deleted, nil
+}
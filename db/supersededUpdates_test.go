@@ -0,0 +1,103 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeleteSupersededUpdates(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+
+	fileNames := []string{
+		"Super Mario Odyssey [0100000000010000][v0].nsp",
+		"Super Mario Odyssey [0100000000010800][v65536].nsp.00",
+		"Super Mario Odyssey [0100000000010800][v65536].nsp.01",
+		"Super Mario Odyssey [0100000000010800][v131072].nsp",
+		"Super Mario Odyssey [0100000000010800][v196608].nsp",
+	}
+	for _, fileName := range fileNames {
+		if err := ioutil.WriteFile(filepath.Join(gamesFolder, fileName), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	localDB, err := manager.CreateLocalSwitchFilesDB([]string{gamesFolder}, nil, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		filepath.Join(gamesFolder, fileNames[3]),
+		filepath.Join(gamesFolder, fileNames[1]),
+		filepath.Join(gamesFolder, fileNames[2]),
+	}
+	checkPaths := func(paths []string) {
+		if len(paths) != len(expected) {
+			t.Fatalf("expected %v, got %v", expected, paths)
+		}
+		for i := range expected {
+			if paths[i] != expected[i] {
+				t.Errorf("expected %v, got %v", expected[i], paths[i])
+			}
+		}
+	}
+
+	paths, err := manager.DeleteSupersededUpdates(localDB, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPaths(paths)
+	for _, path := range expected {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %v to be kept in dry run", path)
+		}
+	}
+
+	//the files were identified by their name, cache some metadata to check it is dropped
+	manager.db.AddEntry(DB_TABLE_FILE_SCAN_METADATA, expected[0]+"|cached", "deleted")
+	manager.db.AddEntry(DB_TABLE_FILE_SCAN_METADATA, filepath.Join(gamesFolder, fileNames[4])+"|cached", "kept")
+
+	paths, err = manager.DeleteSupersededUpdates(localDB, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPaths(paths)
+	for _, path := range expected {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %v to be deleted", path)
+		}
+	}
+	title := localDB.TitlesMap["0100000000010000"]
+	if len(title.Updates) != 1 || title.Updates[196608].Metadata == nil {
+		t.Errorf("expected only the latest update to be left, got %v", title.Updates)
+	}
+	if len(localDB.Skipped) != 0 {
+		t.Errorf("expected no skipped files left, got %v", localDB.Skipped)
+	}
+
+	count := 0
+	manager.db.ForEachEntry(DB_TABLE_FILE_SCAN_METADATA, func(key string, decode func(value interface{}) error) error {
+		count++
+		return nil
+	})
+	if count != 1 {
+		t.Errorf("expected the cached metadata of the deleted file to be dropped, got %v entries", count)
+	}
+}
@@ -18,6 +18,8 @@ type TitleAttributes struct {
 	BannerUrl   string      `json:"bannerUrl,omitempty"`
 	Description string      `json:"description,omitempty"`
 	Size        int         `json:"size,omitempty"`
+	//max number of players (0 when unknown)
+	NumberOfPlayers json.Number `json:"numberOfPlayers,omitempty"`
 }
 
 type SwitchTitle struct {
@@ -54,12 +56,13 @@ func CreateSwitchTitleDB(titlesFile, versionsFile io.Reader) (*SwitchTitlesDB, e
 		//main TitleAttributes ends with 000
 		//Updates ends with 800
 		//Dlc have a running counter (starting with 001) in the 4 last chars
-		idPrefix := id[0 : len(id)-4]
+		//titles are grouped by the base title id (see BaseTitleId)
+		key := groupingKey(id)
 		switchTitle := &SwitchTitle{Dlc: map[string]TitleAttributes{}}
-		if t, ok := result.TitlesMap[idPrefix]; ok {
+		if t, ok := result.TitlesMap[key]; ok {
 			switchTitle = t
 		}
-		result.TitlesMap[idPrefix] = switchTitle
+		result.TitlesMap[key] = switchTitle
 
 		//process Updates
 		if strings.HasSuffix(id, "800") {
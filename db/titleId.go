@@ -0,0 +1,145 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"github.com/giwty/switch-library-manager/switchfs"
+	"strconv"
+	"strings"
+)
+
+type TitleType int
+
+const (
+	TITLE_TYPE_UNKNOWN TitleType = iota
+	TITLE_TYPE_BASE
+	TITLE_TYPE_UPDATE
+	TITLE_TYPE_DLC
+)
+
+func (t TitleType) String() string {
+	switch t {
+	case TITLE_TYPE_BASE:
+		return "Base"
+	case TITLE_TYPE_UPDATE:
+		return "Update"
+	case TITLE_TYPE_DLC:
+		return "DLC"
+	}
+	return "Unknown"
+}
+
+// ValidateTitleId checks that the given id is a valid title id (see IsValidTitleId) of a base (ends with 000),
+// an update (ends with 800) or a DLC (base + 0x1000 + a non zero counter), and returns its lower cased form and type
+func ValidateTitleId(titleId string) (string, TitleType, error) {
+	titleId = strings.ToLower(strings.TrimSpace(titleId))
+	if err := validateTitleId(titleId); err != nil {
+		return "", TITLE_TYPE_UNKNOWN, err
+	}
+	id, err := strconv.ParseUint(titleId, 16, 64)
+	if err != nil {
+		return "", TITLE_TYPE_UNKNOWN, err
+	}
+	switch {
+	case id&0xFFF == 0:
+		return titleId, TITLE_TYPE_BASE, nil
+	case id&0xFFF == 0x800:
+		return titleId, TITLE_TYPE_UPDATE, nil
+	case id&0x1000 != 0:
+		return titleId, TITLE_TYPE_DLC, nil
+	}
+	return "", TITLE_TYPE_UNKNOWN, errors.New("title id [" + titleId + "] is not a base, update or DLC title id")
+}
+
+// ClassifyContent returns the type of the content ("Base", "Update" or "DLC", see TitleType) - the content meta type
+// of the metadata when known (e.g. a DLC whose id ends with 800), the title id suffix otherwise (see ValidateTitleId).
+// an error is returned for other content types (e.g. system content or delta fragments) and unknown title ids
+func ClassifyContent(titleId string, metadata *switchfs.ContentMetaAttributes) (string, error) {
+	if metadata != nil && metadata.Type != "" {
+		switch strings.ToLower(metadata.Type) {
+		case "base", "application":
+			return TITLE_TYPE_BASE.String(), nil
+		case "upd", "update", "patch":
+			return TITLE_TYPE_UPDATE.String(), nil
+		case "dlc", "addoncontent":
+			return TITLE_TYPE_DLC.String(), nil
+		}
+		return "", errors.New("title id [" + titleId + "] has an unsupported content meta type [" + metadata.Type + "]")
+	}
+	_, titleType, err := ValidateTitleId(titleId)
+	if err != nil {
+		return "", err
+	}
+	return titleType.String(), nil
+}
+
+// IsValidTitleId checks that the given id is a 16 chars hex string in the application
+// title range (0100000000000000 - 01FFFFFFFFFFFFFF)
+func IsValidTitleId(titleId string) bool {
+	return validateTitleId(titleId) == nil
+}
+
+func validateTitleId(titleId string) error {
+	if len(titleId) != 16 {
+		return errors.New("title id [" + titleId + "] should be 16 characters long")
+	}
+	for _, c := range strings.ToLower(titleId) {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return errors.New("title id [" + titleId + "] is not a hex string")
+		}
+	}
+	if titleId[0:2] != "01" {
+		return errors.New("title id [" + titleId + "] is not in the application range (01XXXXXXXXXXXXXX)")
+	}
+	return nil
+}
+
+// BaseTitleId returns the title id of the base application a base/update/DLC title id belongs to
+// (updates are base | 0x800, DLC are base + 0x1000 + running counter)
+func BaseTitleId(titleId string) (string, error) {
+	if err := validateTitleId(titleId); err != nil {
+		return "", err
+	}
+	id, err := strconv.ParseUint(titleId, 16, 64)
+	if err != nil {
+		return "", err
+	}
+	if id&0x1000 != 0 && id&0xFFF != 0 {
+		//DLC
+		id &^= 0x1FFF
+	} else {
+		//base / update
+		id &^= 0xFFF
+	}
+	return fmt.Sprintf("%016x", id), nil
+}
+
+// groupingKey returns the key grouping the base, updates and DLC of the same application (the base title id),
+// invalid title ids are grouped by their (lower cased) prefix
+func groupingKey(titleId string) string {
+	if baseId, err := BaseTitleId(strings.ToLower(titleId)); err == nil {
+		return baseId
+	}
+	titleId = strings.ToLower(titleId)
+	if len(titleId) < 4 {
+		return titleId
+	}
+	return titleId[:len(titleId)-4] + "0000"
+}
+
+// TitleId returns the base title id of the local title (also when the base file is missing),
+// or an empty string when unknown
+func (s *SwitchGameFiles) TitleId() string {
+	if s.BaseExist && s.File.Metadata != nil {
+		return strings.ToLower(s.File.Metadata.TitleId)
+	}
+	for _, file := range s.files() {
+		if file.Metadata == nil {
+			continue
+		}
+		if id, err := BaseTitleId(strings.ToLower(file.Metadata.TitleId)); err == nil {
+			return id
+		}
+	}
+	return ""
+}
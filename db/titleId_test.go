@@ -0,0 +1,137 @@
+package db
+
+import (
+	"github.com/giwty/switch-library-manager/switchfs"
+	"testing"
+)
+
+func TestIsValidTitleId(t *testing.T) {
+	tests := []struct {
+		titleId string
+		valid   bool
+	}{
+		{"0100000000010000", true},
+		{"01007EF00011E000", true},
+		{"01007ef00011e800", true},
+		{"01007EF00011F001", true},
+		{"", false},
+		{"0100000000010", false},
+		{"01000000000100000", false},
+		{"1234567890ABCDEZ", false},
+		{"0100,00000010000", false},
+		{"0500000000010000", false},
+		{"0000000000000800", false},
+	}
+	for _, test := range tests {
+		if IsValidTitleId(test.titleId) != test.valid {
+			t.Errorf("IsValidTitleId(%v) expected %v", test.titleId, test.valid)
+		}
+	}
+}
+
+func TestBaseTitleId(t *testing.T) {
+	tests := map[string]string{
+		"0100000000010000": "0100000000010000",
+		"0100000000010800": "0100000000010000",
+		"0100000000011001": "0100000000010000",
+		"01007ef00011e000": "01007ef00011e000",
+		"01007ef00011e800": "01007ef00011e000",
+		"01007ef00011f001": "01007ef00011e000",
+		"01007ef00011f0ff": "01007ef00011e000",
+	}
+	for titleId, expected := range tests {
+		if baseId, err := BaseTitleId(titleId); err != nil || baseId != expected {
+			t.Errorf("BaseTitleId(%v) = %v (%v), expected %v", titleId, baseId, err, expected)
+		}
+	}
+}
+
+func TestGroupingKey(t *testing.T) {
+	tests := map[string]string{
+		"0100000000012000": "0100000000012000",
+		"0100000000012800": "0100000000012000",
+		"0100000000013001": "0100000000012000",
+		"0100000000011800": "0100000000010000",
+		"01007EF00011E000": "01007ef00011e000",
+		"not a title id!!": "not a title 0000",
+	}
+	for titleId, expected := range tests {
+		if key := groupingKey(titleId); key != expected {
+			t.Errorf("groupingKey(%v) = %v, expected %v", titleId, key, expected)
+		}
+	}
+}
+
+func TestValidateTitleId(t *testing.T) {
+	tests := []struct {
+		titleId    string
+		normalized string
+		titleType  TitleType
+	}{
+		{"0100000000010000", "0100000000010000", TITLE_TYPE_BASE},
+		{"01007EF00011E800", "01007ef00011e800", TITLE_TYPE_UPDATE},
+		{"01007EF00011F001", "01007ef00011f001", TITLE_TYPE_DLC},
+		{"01007ef00011f0ff", "01007ef00011f0ff", TITLE_TYPE_DLC},
+		{"1234567890ABCDEZ", "", TITLE_TYPE_UNKNOWN},
+		{"0100000000010123", "", TITLE_TYPE_UNKNOWN},
+		{"0100000000010", "", TITLE_TYPE_UNKNOWN},
+	}
+	for _, test := range tests {
+		normalized, titleType, err := ValidateTitleId(test.titleId)
+		if normalized != test.normalized || titleType != test.titleType || (err == nil) != (test.titleType != TITLE_TYPE_UNKNOWN) {
+			t.Errorf("ValidateTitleId(%v) = %v, %v, %v - expected %v, %v", test.titleId, normalized, titleType, err,
+				test.normalized, test.titleType)
+		}
+	}
+
+	if _, err := defaultFileNameParser.parseTitleId("Game [0100000000010123][v0].nsp"); err == nil {
+		t.Errorf("expected a title id which is neither a base, update or DLC to be rejected")
+	}
+}
+
+func TestClassifyContent(t *testing.T) {
+	tests := []struct {
+		titleId  string
+		metaType string
+		expected string
+	}{
+		{"0100000000010000", "", "Base"},
+		{"0100000000010800", "", "Update"},
+		{"0100000000011001", "", "DLC"},
+		//the content meta type wins over the title id suffix
+		{"0100000000011800", "DLC", "DLC"},
+		{"0100000000011800", "AddOnContent", "DLC"},
+		{"0100000000010800", "UPD", "Update"},
+		{"0100000000010000", "BASE", "Base"},
+		{"0100000000010000", "Application", "Base"},
+		//already classified (e.g. a cached library)
+		{"0100000000010800", "Update", "Update"},
+		{"0100000000010000", "Delta", ""},
+		{"0100000000010123", "", ""},
+		{"0500000000010000", "", ""},
+	}
+	for _, test := range tests {
+		contentType, err := ClassifyContent(test.titleId, &switchfs.ContentMetaAttributes{TitleId: test.titleId, Type: test.metaType})
+		if test.expected == "" {
+			if err == nil {
+				t.Errorf("ClassifyContent(%v, %v) expected an error, got %v", test.titleId, test.metaType, contentType)
+			}
+			continue
+		}
+		if err != nil || contentType != test.expected {
+			t.Errorf("ClassifyContent(%v, %v) = %v (%v), expected %v", test.titleId, test.metaType, contentType, err, test.expected)
+		}
+	}
+	if contentType, err := ClassifyContent("0100000000010800", nil); err != nil || contentType != "Update" {
+		t.Errorf("expected an update without metadata, got %v (%v)", contentType, err)
+	}
+
+	//a DLC whose id ends with 800 is not grouped as an update
+	dlc := testSwitchFile("dlc.nsp", "0100000000011800", 0)
+	dlc.Metadata.Type = "DLC"
+	localDB := Group([]SwitchFileInfo{testSwitchFile("base.nsp", "0100000000010000", 0), dlc}, GroupOptions{})
+	title := localDB.TitlesMap["0100000000010000"]
+	if title == nil || len(title.Updates) != 0 || len(title.Dlc) != 1 || title.Dlc["0100000000011800"].Metadata.Type != "DLC" {
+		t.Errorf("expected the DLC to be grouped with its base, got %+v", title)
+	}
+}
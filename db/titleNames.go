@@ -0,0 +1,131 @@
+package db
+
+import (
+	"github.com/giwty/switch-library-manager/switchfs"
+	"go.uber.org/zap"
+	"sort"
+	"strings"
+)
+
+// TitleNames returns the title names cached by previous scans (title id -> name), allowing to show the names
+// before (or while) the library is scanned
+func (ldb *LocalSwitchDBManager) TitleNames() map[string]string {
+	names := map[string]string{}
+	err := ldb.db.ForEachEntry(DB_TABLE_TITLE_NAMES, func(titleId string, decode func(value interface{}) error) error {
+		var name string
+		if err := decode(&name); err != nil {
+			return err
+		}
+		names[titleId] = name
+		return nil
+	})
+	if err != nil {
+		zap.S().Warnf("failed to read the cached title names [%v]", err)
+	}
+	return names
+}
+
+// TitleName returns the cached name of the title, or an empty string when unknown
+func (ldb *LocalSwitchDBManager) TitleName(titleId string) string {
+	var name string
+	_ = ldb.db.GetEntry(DB_TABLE_TITLE_NAMES, titleId, &name)
+	return name
+}
+
+// updateTitleNames caches the names (read from the NACP) of the given titles, names which changed since
+// the previous scan are replaced
+func (ldb *LocalSwitchDBManager) updateTitleNames(titles map[string]*SwitchGameFiles) {
+	if !ldb.CacheEnabled() {
+		return
+	}
+	cached := ldb.TitleNames()
+	changed := map[string]interface{}{}
+	for _, switchFile := range titles {
+		name := nacpTitleName(switchFile)
+		if name == "" {
+			continue
+		}
+		titleId := switchFile.TitleId()
+		if cached[titleId] != name {
+			changed[titleId] = name
+		}
+	}
+	if err := ldb.db.AddEntries(DB_TABLE_TITLE_NAMES, changed); err != nil {
+		zap.S().Warnf("failed to cache the title names [%v]", err)
+	}
+}
+
+// nacpTitleName returns the name of the title as found in the NACP of the base (or the latest update),
+// preferring the english name
+func nacpTitleName(switchFile *SwitchGameFiles) string {
+	if name := nacpName(switchFile.File.Metadata); name != "" {
+		return name
+	}
+	if update, ok := switchFile.Updates[switchFile.LatestUpdate]; ok {
+		return nacpName(update.Metadata)
+	}
+	return ""
+}
+
+// nacpName returns the name found in the NACP of the content, preferring the english name
+// (empty when the control data isn't available)
+func nacpName(metadata *switchfs.ContentMetaAttributes) string {
+	if metadata == nil || metadata.Ncap == nil {
+		return ""
+	}
+	if title, ok := metadata.Ncap.TitleName["AmericanEnglish"]; ok && title.Title != "" {
+		return title.Title
+	}
+	languages := make([]string, 0, len(metadata.Ncap.TitleName))
+	for language := range metadata.Ncap.TitleName {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	for _, language := range languages {
+		if title := metadata.Ncap.TitleName[language].Title; title != "" {
+			return title
+		}
+	}
+	return ""
+}
+
+// nacpLanguages returns the sorted languages the NACP of the content has a title name for
+func nacpLanguages(metadata *switchfs.ContentMetaAttributes) []string {
+	if metadata == nil || metadata.Ncap == nil {
+		return nil
+	}
+	var languages []string
+	for language, title := range metadata.Ncap.TitleName {
+		if title.Title != "" {
+			languages = append(languages, language)
+		}
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Name returns the name of the file content - read from the NACP when available, parsed from the file name otherwise
+func (f SwitchFileInfo) Name() string {
+	if f.TitleName != "" {
+		return f.TitleName
+	}
+	if name := nacpName(f.Metadata); name != "" {
+		return name
+	}
+	return strings.TrimSpace(ParseTitleNameFromFileName(f.ExtendedInfo.FileName))
+}
+
+// Name returns the name of the title - read from the NACP of the base (or the latest update) when available,
+// parsed from the file names otherwise
+func (s *SwitchGameFiles) Name() string {
+	if name := nacpTitleName(s); name != "" {
+		return name
+	}
+	if s.BaseExist {
+		return s.File.Name()
+	}
+	if update, ok := s.Updates[s.LatestUpdate]; ok {
+		return update.Name()
+	}
+	return ""
+}
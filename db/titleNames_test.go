@@ -0,0 +1,32 @@
+package db
+
+import (
+	"github.com/giwty/switch-library-manager/switchfs"
+	"reflect"
+	"testing"
+)
+
+func TestSwitchFileInfoName(t *testing.T) {
+	renamed := testSwitchFile("renamed by user [0100000000010000][v0].nsp", "0100000000010000", 0)
+	renamed.Metadata.Ncap = &switchfs.Nacp{TitleName: map[string]switchfs.NacpTitle{
+		"AmericanEnglish": {Language: switchfs.AmericanEnglish, Title: ""},
+		"Japanese":        {Language: switchfs.Japanese, Title: "ゲーム"},
+		"French":          {Language: switchfs.French, Title: "Le Jeu"},
+	}}
+	if name := renamed.Name(); name != "Le Jeu" {
+		t.Errorf("expected the NACP name, got %v", name)
+	}
+	if languages := nacpLanguages(renamed.Metadata); !reflect.DeepEqual(languages, []string{"French", "Japanese"}) {
+		t.Errorf("unexpected languages %v", languages)
+	}
+
+	noControl := testSwitchFile("Game [0100000000020000][v0].nsp", "0100000000020000", 0)
+	if name := noControl.Name(); name != "Game" {
+		t.Errorf("expected the file name, got %v", name)
+	}
+
+	localDB := Group([]SwitchFileInfo{noControl, renamed}, GroupOptions{})
+	if name := localDB.TitlesMap["0100000000010000"].Name(); name != "Le Jeu" {
+		t.Errorf("expected the title name from the NACP, got %v", name)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -17,6 +18,63 @@ type ProgressUpdater interface {
 	UpdateProgress(curr int, total int, message string)
 }
 
+// ProgressUpdaterV2 is implemented by the progress updaters which also want the files parsing progress weighted by
+// the files size. UpdateBytes is called along with UpdateProgress, etaSeconds is the estimated remaining time
+// based on the throughput so far (-1 when unknown)
+type ProgressUpdaterV2 interface {
+	ProgressUpdater
+	UpdateBytes(done int64, total int64, etaSeconds int)
+}
+
+type throttledProgress struct {
+	sync.Mutex
+	target    ProgressUpdater
+	interval  time.Duration
+	last      time.Time
+	lastBytes time.Time
+}
+
+// NewThrottledProgress returns a ProgressUpdater which forwards at most one update per interval to the target,
+// intermediate updates are dropped. completed steps (curr == total) are always forwarded,
+// so the final "Complete" update is never lost. a nil target or a non positive interval returns the target as is.
+func NewThrottledProgress(target ProgressUpdater, interval time.Duration) ProgressUpdater {
+	if target == nil || interval <= 0 {
+		return target
+	}
+	return &throttledProgress{target: target, interval: interval}
+}
+
+func (p *throttledProgress) UpdateProgress(curr int, total int, message string) {
+	p.Lock()
+	now := time.Now()
+	completed := total > 0 && curr >= total
+	if !completed && now.Sub(p.last) < p.interval {
+		p.Unlock()
+		return
+	}
+	p.last = now
+	p.Unlock()
+	p.target.UpdateProgress(curr, total, message)
+}
+
+// UpdateBytes forwards the update when the target implements ProgressUpdaterV2, throttled as UpdateProgress
+func (p *throttledProgress) UpdateBytes(done int64, total int64, etaSeconds int) {
+	target, ok := p.target.(ProgressUpdaterV2)
+	if !ok {
+		return
+	}
+	p.Lock()
+	now := time.Now()
+	completed := total > 0 && done >= total
+	if !completed && now.Sub(p.lastBytes) < p.interval {
+		p.Unlock()
+		return
+	}
+	p.lastBytes = now
+	p.Unlock()
+	target.UpdateBytes(done, total, etaSeconds)
+}
+
 func LoadAndUpdateFile(url string, filePath string, etag string) (*os.File, string, error) {
 
 	//create file if not exist
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type recordingProgress struct {
+	messages []string
+}
+
+func (p *recordingProgress) UpdateProgress(curr int, total int, message string) {
+	p.messages = append(p.messages, message)
+}
+
+type recordingBytesProgress struct {
+	recordingProgress
+	done  int64
+	total int64
+	eta   int
+}
+
+func (p *recordingBytesProgress) UpdateBytes(done int64, total int64, etaSeconds int) {
+	p.done, p.total, p.eta = done, total, etaSeconds
+}
+
+func TestThrottledProgress(t *testing.T) {
+	target := &recordingProgress{}
+	progress := NewThrottledProgress(target, time.Hour)
+	progress.UpdateProgress(1, 100, "first")
+	for i := 2; i < 100; i++ {
+		progress.UpdateProgress(i, 100, "intermediate")
+	}
+	progress.UpdateProgress(-1, -1, "scanning")
+	progress.UpdateProgress(100, 100, "Complete")
+
+	if len(target.messages) != 2 || target.messages[0] != "first" || target.messages[1] != "Complete" {
+		t.Errorf("expected only the first and the final updates, got %v", target.messages)
+	}
+
+	if NewThrottledProgress(target, 0) != ProgressUpdater(target) {
+		t.Errorf("expected no throttling for a zero interval")
+	}
+}
+
+func TestBytesProgress(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+
+	files := map[string]int{
+		"Super Mario Odyssey [0100000000010000][v0].nsp":     1000,
+		"Super Mario Odyssey [0100000000010800][v65536].nsp": 10,
+		"readme.txt": 5,
+	}
+	for fileName, size := range files {
+		if err := ioutil.WriteFile(filepath.Join(gamesFolder, fileName), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	progress := &recordingBytesProgress{}
+	if _, err := manager.CreateLocalSwitchFilesDB([]string{gamesFolder}, progress, true, true); err != nil {
+		t.Fatal(err)
+	}
+	//the unsupported file is not read
+	if progress.done != 1010 || progress.total != 1010 || progress.eta != 0 {
+		t.Errorf("expected the final update to report 1010/1010 bytes, got %v/%v (eta %v)", progress.done, progress.total, progress.eta)
+	}
+	if len(progress.messages) == 0 {
+		t.Errorf("expected the files count progress to be reported as well")
+	}
+}
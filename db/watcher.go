@@ -0,0 +1,441 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/fsnotify/fsnotify"
+	"github.com/giwty/switch-library-manager/settings"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	watchDebounceInterval = 2 * time.Second
+)
+
+type ChangeSet struct {
+	Added   []string
+	Changed []string
+	Removed []string
+	//the library files (archive entries included) which were added or changed
+	Files []ExtendedFileInfo
+}
+
+type pendingChange struct {
+	lastEvent time.Time
+	size      int64
+}
+
+// Watch monitors the given folders (and their sub-folders when recursive) and re-processes files which were added,
+// changed or removed, updating the library in place - only the added and changed files are read, the cached metadata
+// of removed and changed files is dropped. Events are debounced, a file is only processed once its size is stable
+// (e.g. a download in progress). the library is only locked while it is updated, so it can be read meanwhile.
+// onChange is invoked (from the watcher goroutine) after the library was updated, along with the OnTitle and OnSkip
+// callbacks of the manager. a removed folder removes all the files below it, the files of a new (or moved in) folder
+// are added. only the files a full scan would list are added (see scanFolders - scan filter, exclusions and depth).
+// Watch blocks until the context is cancelled.
+func (ldb *LocalSwitchDBManager) Watch(ctx context.Context, folders []string, recursive bool, library *SafeLibrary,
+	onChange func(ChangeSet)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	numWatched := 0
+	for _, folder := range folders {
+		numWatched += addWatch(watcher, folder, recursive)
+	}
+	if numWatched == 0 {
+		return errors.New("unable to watch any of the library folders")
+	}
+
+	pending := map[string]*pendingChange{}
+	addPending := func(path string) {
+		if p, ok := pending[path]; ok {
+			p.lastEvent = time.Now()
+		} else {
+			pending[path] = &pendingChange{lastEvent: time.Now(), size: -1}
+		}
+	}
+	ticker := time.NewTicker(watchDebounceInterval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op&fsnotify.Create == fsnotify.Create {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if recursive {
+						addWatch(watcher, event.Name, recursive)
+						//the files already in the folder (moved in, or written before it was watched) yield no event
+						for _, path := range folderFiles(event.Name) {
+							addPending(path)
+						}
+					}
+					continue
+				}
+			}
+			addPending(event.Name)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			zap.S().Warnf("file watcher error [%v]", err)
+		case <-ticker.C:
+			var ready []string
+			for path, p := range pending {
+				if time.Since(p.lastEvent) < watchDebounceInterval {
+					continue
+				}
+				//wait until the file size is stable before processing it
+				if info, err := os.Stat(path); err == nil && info.Size() != p.size {
+					p.size = info.Size()
+					p.lastEvent = time.Now()
+					continue
+				}
+				ready = append(ready, path)
+			}
+			if len(ready) == 0 {
+				continue
+			}
+			for _, path := range ready {
+				delete(pending, path)
+			}
+			changeSet := ldb.applyChanges(library, folders, recursive, ready)
+			if onChange != nil && (len(changeSet.Added)+len(changeSet.Changed)+len(changeSet.Removed)) != 0 {
+				onChange(changeSet)
+			}
+		}
+	}
+}
+
+// folderFiles lists the files below the folder
+func folderFiles(folder string) []string {
+	var files []string
+	_ = filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files
+}
+
+func addWatch(watcher *fsnotify.Watcher, folder string, recursive bool) int {
+	numWatched := 0
+	_ = filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		if !recursive && path != folder {
+			return filepath.SkipDir
+		}
+		err = watcher.Add(path)
+		if err != nil {
+			//most likely the OS watch limit was reached (inotify max_user_watches), keep what we have
+			zap.S().Warnf("unable to watch folder %v, changes in it will not be detected [reason: %v]", path, err)
+			return filepath.SkipDir
+		}
+		numWatched++
+		return nil
+	})
+	return numWatched
+}
+
+// applyChanges rebuilds the library grouping based on the known files and the given changed paths
+// (metadata of unchanged files is served from the cache). the library is replaced once the files were read
+func (ldb *LocalSwitchDBManager) applyChanges(library *SafeLibrary, folders []string, recursive bool, paths []string) ChangeSet {
+	changeSet := ChangeSet{}
+	options := settings.ReadSettings(ldb.baseFolder).ScanOptions
+	filter, err := newScanFilter(options.ScanFilter, options.ScanExclusions)
+	if err != nil {
+		zap.S().Errorf("%v", err)
+		return changeSet
+	}
+	rules := scanRules{folders: folders, recursive: recursive, filter: filter,
+		limits: scanLimits{maxDepth: options.GetMaxDepth(), maxFiles: options.GetMaxFiles(), folderDepth: options.GetMaxFolderDepth()}}
+	files := map[string]ExtendedFileInfo{}
+	//the titles holding each file, to report the titles losing files
+	previousTitles := map[string][]string{}
+	library.View(func(localDB *LocalSwitchFilesDB) {
+		for _, file := range libraryFiles(localDB) {
+			files[fileKey(file)] = file
+		}
+		for key, title := range localDB.TitlesMap {
+			for _, file := range titleFiles(title) {
+				path := filepath.Join(file.BaseFolder, file.FileName)
+				previousTitles[path] = append(previousTitles[path], key)
+			}
+		}
+	})
+
+	unique := uniqueKeys(files)
+	var stale []ExtendedFileInfo
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		//a removed folder only yields an event for the folder, the files below it are removed as well
+		removedFolder := err != nil
+		//an archive is replaced by all its entries
+		known := false
+		removed := map[string]struct{}{}
+		for key, file := range files {
+			filePath := filepath.Join(file.BaseFolder, file.FileName)
+			if filePath == path || (removedFolder && strings.HasPrefix(filePath, path+string(os.PathSeparator))) {
+				delete(files, key)
+				stale = append(stale, file)
+				known = true
+				removed[filePath] = struct{}{}
+			}
+		}
+		//a file the scan does not list (e.g. excluded) is dropped like a removed file
+		if err != nil || info.IsDir() || !rules.listed(path, info) {
+			removedPaths := make([]string, 0, len(removed))
+			for filePath := range removed {
+				removedPaths = append(removedPaths, filePath)
+			}
+			sort.Strings(removedPaths)
+			changeSet.Removed = append(changeSet.Removed, removedPaths...)
+			continue
+		}
+		file := newExtendedFileInfo(rootFolder(folders, path), path, info)
+		entries := []ExtendedFileInfo{file}
+		if options.ScanZipArchives {
+			if archived := archiveEntries(file); len(archived) != 0 {
+				entries = archived
+			}
+		}
+		var added []ExtendedFileInfo
+		for _, entry := range entries {
+			//a file already listed through another path (overlapping scan folders or symlinks) is left out, see uniqueFiles
+			if key, ok := unique[uniqueKey(entry)]; ok && key != fileKey(entry) {
+				if _, listed := files[key]; listed {
+					continue
+				}
+			}
+			if len(files) >= rules.limits.maxFiles {
+				zap.S().Warnf("skipping [%v] - more than %v files in the library", path, rules.limits.maxFiles)
+				break
+			}
+			files[fileKey(entry)] = entry
+			unique[uniqueKey(entry)] = fileKey(entry)
+			added = append(added, entry)
+		}
+		if len(added) == 0 {
+			if known {
+				changeSet.Removed = append(changeSet.Removed, path)
+			}
+			continue
+		}
+		changeSet.Files = append(changeSet.Files, added...)
+		if known {
+			changeSet.Changed = append(changeSet.Changed, path)
+		} else {
+			changeSet.Added = append(changeSet.Added, path)
+		}
+	}
+
+	for _, file := range stale {
+		//a file modified in place gets a new cache key, its previous entry would never be used again
+		filePath := filepath.Join(file.BaseFolder, file.FileName)
+		if current, ok := files[fileKey(file)]; ok && fileCacheKey(current, filePath) == fileCacheKey(file, filePath) {
+			continue
+		}
+		for _, table := range []string{DB_TABLE_FILE_SCAN_METADATA, DB_TABLE_FILE_SCAN_FAILURES} {
+			if err := ldb.db.DeleteEntry(table, fileCacheKey(file, filePath)); err != nil {
+				zap.S().Warnf("%v", err)
+			}
+		}
+	}
+
+	fileList := make([]ExtendedFileInfo, 0, len(files))
+	for _, file := range files {
+		fileList = append(fileList, file)
+	}
+	sort.Slice(fileList, func(i, j int) bool {
+		return fileKey(fileList[i]) < fileKey(fileList[j])
+	})
+
+	titles := map[string]*SwitchGameFiles{}
+	skipped := map[ExtendedFileInfo]SkippedFile{}
+	ldb.processLocalFiles(fileList, nil, titles, skipped)
+	library.Update(func(localDB *LocalSwitchFilesDB) {
+		localDB.TitlesMap = titles
+		localDB.Skipped = skipped
+		localDB.NumFiles = len(fileList)
+	})
+
+	if !ldb.quickScan {
+		ldb.db.AddEntry(DB_TABLE_LOCAL_LIBRARY, "files", fileList)
+		ldb.db.AddEntry(DB_TABLE_LOCAL_LIBRARY, "skipped", skipped)
+		ldb.db.AddEntry(DB_TABLE_LOCAL_LIBRARY, "titles", titles)
+		ldb.updateTitleNames(titles)
+	}
+	ldb.notifyChanges(changeSet, previousTitles, titles, skipped)
+	return changeSet
+}
+
+// notifyChanges invokes the OnTitle callback with the titles holding (or which held) the changed files, and the
+// OnSkip callback with the changed files which are skipped, both in a stable order
+func (ldb *LocalSwitchDBManager) notifyChanges(changeSet ChangeSet, previousTitles map[string][]string,
+	titles map[string]*SwitchGameFiles, skipped map[ExtendedFileInfo]SkippedFile) {
+	changed := map[string]struct{}{}
+	for _, paths := range [][]string{changeSet.Added, changeSet.Changed, changeSet.Removed} {
+		for _, path := range paths {
+			changed[path] = struct{}{}
+		}
+	}
+	if ldb.OnTitle != nil {
+		keys := map[string]struct{}{}
+		for path := range changed {
+			for _, key := range previousTitles[path] {
+				keys[key] = struct{}{}
+			}
+		}
+		for key, title := range titles {
+			for _, file := range titleFiles(title) {
+				if _, ok := changed[filepath.Join(file.BaseFolder, file.FileName)]; ok {
+					keys[key] = struct{}{}
+				}
+			}
+		}
+		sortedKeys := make([]string, 0, len(keys))
+		for key := range keys {
+			sortedKeys = append(sortedKeys, key)
+		}
+		sort.Strings(sortedKeys)
+		for _, key := range sortedKeys {
+			//a title whose files were all removed is no longer part of the library
+			if title, ok := titles[key]; ok {
+				ldb.OnTitle(title)
+			}
+		}
+	}
+	if ldb.OnSkip != nil {
+		var skippedFiles []ExtendedFileInfo
+		for file := range skipped {
+			if _, ok := changed[filepath.Join(file.BaseFolder, file.FileName)]; ok {
+				skippedFiles = append(skippedFiles, file)
+			}
+		}
+		sort.Slice(skippedFiles, func(i, j int) bool {
+			return fileKey(skippedFiles[i]) < fileKey(skippedFiles[j])
+		})
+		for _, file := range skippedFiles {
+			ldb.OnSkip(file, skipped[file])
+		}
+	}
+}
+
+// scanRules applies the rules of a full scan (see scanFolder) to single paths
+type scanRules struct {
+	folders   []string
+	recursive bool
+	filter    scanFilter
+	limits    scanLimits
+}
+
+// listed returns true when a full scan of the folders lists the file - below a scan folder, within the folder
+// depth and neither the file nor one of its parent folders rejected by the scan filter
+func (r scanRules) listed(path string, info os.FileInfo) bool {
+	root := rootFolder(r.folders, path)
+	if root == "" {
+		return false
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(rel, string(os.PathSeparator))
+	depth := len(parts) - 1
+	if (depth > 0 && !r.recursive) || (r.limits.folderDepth >= 0 && depth > r.limits.folderDepth) || depth > r.limits.maxDepth {
+		return false
+	}
+	folder := root
+	for _, part := range parts[:depth] {
+		folder = filepath.Join(folder, part)
+		folderInfo, err := os.Lstat(folder)
+		if err != nil || r.filter.skip(folder, folderInfo) {
+			return false
+		}
+	}
+	return !r.filter.skip(path, info)
+}
+
+// uniqueKeys maps the unique key (see uniqueKey) of each file to its file key
+func uniqueKeys(files map[string]ExtendedFileInfo) map[string]string {
+	keys := make(map[string]string, len(files))
+	for key, file := range files {
+		keys[uniqueKey(file)] = key
+	}
+	return keys
+}
+
+// rootFolder returns the (innermost) scan folder containing the path
+func rootFolder(folders []string, path string) string {
+	root := ""
+	for _, folder := range folders {
+		folder = filepath.Clean(folder)
+		if strings.HasPrefix(path, folder+string(os.PathSeparator)) && len(folder) > len(root) {
+			root = folder
+		}
+	}
+	return root
+}
+
+// fileKey identifies a library file by its path (and archive entry)
+func fileKey(file ExtendedFileInfo) string {
+	key := filepath.Join(file.BaseFolder, file.FileName)
+	if file.ArchiveEntry != "" {
+		key += "|" + file.ArchiveEntry
+	}
+	return key
+}
+
+// titleFiles returns the files of the title (base, updates and DLC)
+func titleFiles(title *SwitchGameFiles) []ExtendedFileInfo {
+	var files []ExtendedFileInfo
+	if title.BaseExist {
+		files = append(files, title.File.ExtendedInfo)
+	}
+	for _, update := range title.Updates {
+		files = append(files, update.ExtendedInfo)
+	}
+	for _, dlc := range title.Dlc {
+		files = append(files, dlc.ExtendedInfo)
+	}
+	return files
+}
+
+// libraryFiles returns all the files known to the library (grouped and skipped)
+func libraryFiles(localDB *LocalSwitchFilesDB) []ExtendedFileInfo {
+	files := map[ExtendedFileInfo]struct{}{}
+	for _, switchFile := range localDB.TitlesMap {
+		if switchFile.BaseExist {
+			files[switchFile.File.ExtendedInfo] = struct{}{}
+		}
+		for _, update := range switchFile.Updates {
+			files[update.ExtendedInfo] = struct{}{}
+		}
+		for _, dlc := range switchFile.Dlc {
+			files[dlc.ExtendedInfo] = struct{}{}
+		}
+	}
+	for file := range localDB.Skipped {
+		files[file] = struct{}{}
+	}
+	result := make([]ExtendedFileInfo, 0, len(files))
+	for file := range files {
+		result = append(result, file)
+	}
+	return result
+}
@@ -0,0 +1,290 @@
+package db
+
+import (
+	"context"
+	"github.com/giwty/switch-library-manager/settings"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestApplyChanges(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+
+	base := filepath.Join(gamesFolder, "Super Mario Odyssey [0100000000010000][v0].nsp")
+	dlc := filepath.Join(gamesFolder, "Super Mario Odyssey [0100000000011001][v0].nsp")
+	update := filepath.Join(gamesFolder, "Super Mario Odyssey [0100000000010800][v65536].nsp")
+	for _, path := range []string{base, dlc} {
+		if err := ioutil.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	localDB, err := manager.CreateLocalSwitchFilesDB([]string{gamesFolder}, nil, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed := localDB.TitlesMap["0100000000010000"].Dlc["0100000000011001"].ExtendedInfo
+	removedKey := fileCacheKey(removed, dlc)
+	if err := manager.db.AddEntry(DB_TABLE_FILE_SCAN_METADATA, removedKey, "metadata"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Remove(dlc); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(update, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var titles []*SwitchGameFiles
+	manager.OnTitle = func(title *SwitchGameFiles) {
+		titles = append(titles, title)
+	}
+	changeSet := manager.applyChanges(NewSafeLibrary(localDB, GroupOptions{}), []string{gamesFolder}, true, []string{dlc, update})
+
+	if len(changeSet.Added) != 1 || changeSet.Added[0] != update || len(changeSet.Removed) != 1 || changeSet.Removed[0] != dlc {
+		t.Errorf("unexpected change set %+v", changeSet)
+	}
+	if len(changeSet.Files) != 1 || changeSet.Files[0].FileName != filepath.Base(update) {
+		t.Errorf("expected the added file info, got %+v", changeSet.Files)
+	}
+	title := localDB.TitlesMap["0100000000010000"]
+	if len(titles) != 1 || titles[0] != title {
+		t.Errorf("expected OnTitle to be invoked once with the changed title, got %v", titles)
+	}
+	if title == nil || len(title.Dlc) != 0 || title.LatestUpdate != 65536 {
+		t.Errorf("expected the DLC to be removed and the update to be added, got %+v", title)
+	}
+	var cached string
+	if err := manager.db.GetEntry(DB_TABLE_FILE_SCAN_METADATA, removedKey, &cached); err != nil || cached != "" {
+		t.Errorf("expected the cached metadata of the removed file to be dropped, got %v (%v)", cached, err)
+	}
+}
+
+func TestApplyChangesRemovedFolder(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+
+	subFolder := filepath.Join(gamesFolder, "Super Mario Odyssey")
+	if err := os.Mkdir(subFolder, 0755); err != nil {
+		t.Fatal(err)
+	}
+	base := filepath.Join(gamesFolder, "Super Mario Odyssey [0100000000010000][v0].nsp")
+	dlc := filepath.Join(subFolder, "Super Mario Odyssey [0100000000011001][v0].nsp")
+	update := filepath.Join(subFolder, "Super Mario Odyssey [0100000000010800][v65536].nsp")
+	for _, path := range []string{base, dlc, update} {
+		if err := ioutil.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	localDB, err := manager.CreateLocalSwitchFilesDB([]string{gamesFolder}, nil, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	title := localDB.TitlesMap["0100000000010000"]
+	if title == nil || len(title.Dlc) != 1 || len(title.Updates) != 1 {
+		t.Fatalf("expected the DLC and the update in the sub folder to be found, got %+v", title)
+	}
+
+	if err := os.RemoveAll(subFolder); err != nil {
+		t.Fatal(err)
+	}
+	//only the folder itself is reported when it is removed
+	changeSet := manager.applyChanges(NewSafeLibrary(localDB, GroupOptions{}), []string{gamesFolder}, true, []string{subFolder})
+
+	if len(changeSet.Removed) != 2 || changeSet.Removed[0] != update || changeSet.Removed[1] != dlc {
+		t.Errorf("expected the files of the removed folder in the change set, got %+v", changeSet)
+	}
+	title = localDB.TitlesMap["0100000000010000"]
+	if title == nil || !title.BaseExist || len(title.Dlc) != 0 || len(title.Updates) != 0 {
+		t.Errorf("expected only the base to remain, got %+v", title)
+	}
+	if localDB.NumFiles != 1 {
+		t.Errorf("expected 1 file in the library, got %v", localDB.NumFiles)
+	}
+}
+
+func TestWatchNewFolder(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+
+	base := filepath.Join(gamesFolder, "Super Mario Odyssey [0100000000010000][v0].nsp")
+	if err := ioutil.WriteFile(base, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	//the folder is prepared outside of the library, and then moved in at once
+	download := filepath.Join(baseFolder, "Super Mario Odyssey")
+	if err := os.Mkdir(download, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, fileName := range []string{"Super Mario Odyssey [0100000000011001][v0].nsp",
+		"Super Mario Odyssey [0100000000010800][v65536].nsp"} {
+		if err := ioutil.WriteFile(filepath.Join(download, fileName), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+	localDB, err := manager.CreateLocalSwitchFilesDB([]string{gamesFolder}, nil, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	library := NewSafeLibrary(localDB, GroupOptions{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan ChangeSet, 1)
+	done := make(chan error)
+	go func() {
+		done <- manager.Watch(ctx, []string{gamesFolder}, true, library, func(changeSet ChangeSet) {
+			changes <- changeSet
+		})
+	}()
+	//let the watcher add its watches
+	time.Sleep(200 * time.Millisecond)
+
+	moved := filepath.Join(gamesFolder, "Super Mario Odyssey")
+	if err := os.Rename(download, moved); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case changeSet := <-changes:
+		if len(changeSet.Added) != 2 || len(changeSet.Files) != 2 || filepath.Dir(changeSet.Added[0]) != moved {
+			t.Errorf("expected the files of the moved folder to be added, got %+v", changeSet)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the files of the moved folder were not detected")
+	}
+	library.View(func(localDB *LocalSwitchFilesDB) {
+		title := localDB.TitlesMap["0100000000010000"]
+		if title == nil || len(title.Dlc) != 1 || title.LatestUpdate != 65536 || localDB.NumFiles != 3 {
+			t.Errorf("expected the DLC and the update to be added to the library, got %+v", title)
+		}
+	})
+	cancel()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestApplyChangesScanRules(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+
+	settingsObj := settings.ReadSettings(baseFolder)
+	options := settingsObj.ScanOptions
+	defer func() {
+		settingsObj.ScanOptions = options
+	}()
+	folderDepth := 1
+	settingsObj.ScanOptions.MaxFolderDepth = &folderDepth
+	settingsObj.ScanOptions.ScanFilter.ExcludeGlobs = []string{"thumbs.db"}
+	settingsObj.ScanOptions.ScanExclusions.PathGlobs = []string{filepath.Join(gamesFolder, "demos")}
+
+	base := filepath.Join(gamesFolder, "Super Mario Odyssey [0100000000010000][v0].nsp")
+	if err := ioutil.WriteFile(base, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manager, err := NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+	localDB, err := manager.CreateLocalSwitchFilesDB([]string{gamesFolder}, nil, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	update := filepath.Join(gamesFolder, "updates", "Super Mario Odyssey [0100000000010800][v65536].nsp")
+	ignored := []string{
+		filepath.Join(gamesFolder, "._Super Mario Odyssey [0100000000011001][v0].nsp"),
+		filepath.Join(gamesFolder, "Thumbs.db"),
+		filepath.Join(gamesFolder, "demos", "Super Mario Odyssey [0100000000011002][v0].nsp"),
+		//beyond the folder depth
+		filepath.Join(gamesFolder, "updates", "old", "Super Mario Odyssey [0100000000011003][v0].nsp"),
+	}
+	for _, path := range append(ignored, update) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//the base, listed through another path
+	link := filepath.Join(gamesFolder, "link.nsp")
+	if err := os.Symlink(base, link); err != nil {
+		t.Fatal(err)
+	}
+	ignored = append(ignored, link)
+
+	var skippedFiles []ExtendedFileInfo
+	manager.OnSkip = func(file ExtendedFileInfo, skip SkippedFile) {
+		skippedFiles = append(skippedFiles, file)
+	}
+	changeSet := manager.applyChanges(NewSafeLibrary(localDB, GroupOptions{}), []string{gamesFolder}, true,
+		append(ignored, update))
+
+	if len(changeSet.Added) != 1 || changeSet.Added[0] != update || len(changeSet.Removed) != 0 {
+		t.Errorf("expected only the update to be added, got %+v", changeSet)
+	}
+	if len(skippedFiles) != 0 {
+		t.Errorf("expected the ignored files not to be reported, got %+v", skippedFiles)
+	}
+	title := localDB.TitlesMap["0100000000010000"]
+	if localDB.NumFiles != 2 || title == nil || len(title.Dlc) != 0 || title.LatestUpdate != 65536 {
+		t.Errorf("expected the base and the update only, got %v files %+v", localDB.NumFiles, title)
+	}
+}
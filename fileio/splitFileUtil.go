@@ -6,23 +6,86 @@ import (
 	"os"
 )
 
-func ReadSplitFileMetadata(filePath string) (map[string]*switchfs.ContentMetaAttributes, error) {
+type SplitFileInfo struct {
+	//ordered part paths
+	Parts     []string
+	NumParts  int
+	TotalSize int64
+	//the container stored in the split parts - "nsp", "nsz", "nsx", "xci" or "xcz" (detected from the content,
+	//the compressed/variant formats are told by the parts names only)
+	Format string
+	//issues found with the parts (missing/duplicate parts, mixed naming schemes)
+	Warnings []string
+}
+
+type SplitFileMetadata struct {
+	SplitFileInfo
+	Metadata map[string]*switchfs.ContentMetaAttributes
+}
+
+// ReadSplitFileMetadata reads the metadata stored in the split file parts (named after the given schemes, nil - the
+// default ones), the reads are counted against the limiter (nil - unlimited)
+func ReadSplitFileMetadata(schemes switchfs.SplitSchemes, filePath string, limiter *switchfs.IOLimiter) (*SplitFileMetadata, error) {
+	info, err := GetSplitFileInfo(schemes, filePath)
+	if err != nil {
+		return nil, err
+	}
+	file, err := schemes.OpenFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := limiter.Limit(file)
+
+	result := &SplitFileMetadata{SplitFileInfo: *info}
+	if info.Format == "xci" || info.Format == "xcz" {
+		result.Metadata, err = switchfs.ReadXciMetadataFrom(reader, info.TotalSize)
+	} else {
+		result.Metadata, err = switchfs.ReadNspMetadataFrom(reader, info.TotalSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetSplitFileInfo lists the parts of the split file (named after the given schemes, nil - the default ones)
+// and detects the container format stored in them
+func GetSplitFileInfo(schemes switchfs.SplitSchemes, filePath string) (*SplitFileInfo, error) {
+	parts, warnings, err := schemes.FileParts(filePath)
+	if err != nil {
+		return nil, err
+	}
+	result := &SplitFileInfo{Parts: parts, NumParts: len(parts), Warnings: warnings}
+	for _, part := range parts {
+		info, err := os.Stat(part)
+		if err != nil {
+			return nil, err
+		}
+		result.TotalSize += info.Size()
+	}
+
 	//check if this is a NS* or XC* file
-	_, err := switchfs.ReadPfs0File(filePath)
-	isXCI := false
+	file, err := schemes.OpenFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	_, err = switchfs.ReadPfs0From(file)
+	file.Close()
+	result.Format = "nsp"
 	if err != nil {
 		_, err = readXciHeader(filePath)
 		if err != nil {
 			return nil, errors.New("split file is not an XCI/XCZ or NSP/NSZ")
 		}
-		isXCI = true
+		result.Format = "xci"
 	}
-
-	if isXCI {
-		return switchfs.ReadXciMetadata(filePath)
-	} else {
-		return switchfs.ReadNspMetadata(filePath)
+	//the content tells the container family only (e.g. a XCZ is read as a XCI)
+	if container := schemes.ContainerType(filePath); (container.IsXci() && result.Format == "xci") ||
+		(container.IsNsp() && result.Format == "nsp") {
+		result.Format = container.String()
 	}
+	return result, nil
 }
 
 func readXciHeader(filePath string) ([]byte, error) {
@@ -44,3 +107,10 @@ func readXciHeader(filePath string) ([]byte, error) {
 	}
 	return header, nil
 }
+
+// GetSplitFileParts returns the ordered paths of all the parts belonging to the same split file
+// (files in the same folder sharing the base name and the naming scheme, see switchfs.SplitSchemes.FileParts)
+func GetSplitFileParts(schemes switchfs.SplitSchemes, filePath string) ([]string, error) {
+	parts, _, err := schemes.FileParts(filePath)
+	return parts, err
+}
@@ -0,0 +1,115 @@
+package fileio
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeParts(t *testing.T, folder string, names []string, first []byte) {
+	for i, name := range names {
+		data := make([]byte, 0x400)
+		if i == 0 {
+			copy(data, first)
+		}
+		if err := ioutil.WriteFile(filepath.Join(folder, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGetSplitFileInfo(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-split")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+
+	//PFS0 header with no files
+	writeParts(t, folder, []string{"00", "01", "02", "10"}, []byte("PFS0\x00\x00\x00\x00\x00\x00\x00\x00"))
+
+	info, err := GetSplitFileInfo(nil, filepath.Join(folder, "00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.NumParts != 4 || info.TotalSize != 4*0x400 || info.Format != "nsp" {
+		t.Errorf("unexpected split file info %+v", info)
+	}
+	expected := []string{"00", "01", "02", "10"}
+	for i, part := range info.Parts {
+		if part != filepath.Join(folder, expected[i]) {
+			t.Errorf("part %v - expected %v, got %v", i, expected[i], part)
+		}
+	}
+}
+
+func TestGetSplitFileInfoXci(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-split")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+
+	header := make([]byte, 0x200)
+	copy(header[0x100:], "HEAD")
+	writeParts(t, folder, []string{"game.xci.00", "game.xci.01"}, header)
+
+	info, err := GetSplitFileInfo(nil, filepath.Join(folder, "game.xci.00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.NumParts != 2 || info.Format != "xci" {
+		t.Errorf("unexpected split file info %+v", info)
+	}
+}
+
+func TestGetSplitFilePartsSchemes(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []string
+		first    string
+		expected []string
+		warnings int
+	}{
+		{"three digits", []string{"game.nsp.000", "game.nsp.002", "game.nsp.001", "other.nsp.000"}, "game.nsp.000",
+			[]string{"game.nsp.000", "game.nsp.001", "game.nsp.002"}, 0},
+		{"part", []string{"game.nsp.part1", "game.nsp.part10", "game.nsp.part2"}, "game.nsp.part1",
+			[]string{"game.nsp.part1", "game.nsp.part2", "game.nsp.part10"}, 1},
+		{"split", []string{"game.nsp_split00", "game.nsp_split01"}, "game.nsp_split00",
+			[]string{"game.nsp_split00", "game.nsp_split01"}, 0},
+		{"mixed", []string{"game.nsp.00", "game.nsp.part2", "game.nsp.01"}, "game.nsp.00",
+			[]string{"game.nsp.00", "game.nsp.01"}, 1},
+		{"duplicate", []string{"game.nsp.00", "game.nsp.01", "game.nsp.001"}, "game.nsp.00",
+			[]string{"game.nsp.00", "game.nsp.001", "game.nsp.01"}, 1},
+		//parts in a folder of their own may be numbered from 1
+		{"folder", []string{"01", "03", "02"}, "01", []string{"01", "02", "03"}, 0},
+		{"folder missing", []string{"01", "03"}, "01", []string{"01", "03"}, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder, err := ioutil.TempDir("", "slm-split")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(folder)
+			writeParts(t, folder, test.files, []byte("PFS0\x00\x00\x00\x00\x00\x00\x00\x00"))
+
+			info, err := GetSplitFileInfo(nil, filepath.Join(folder, test.first))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(info.Parts) != len(test.expected) {
+				t.Fatalf("expected parts %v, got %v", test.expected, info.Parts)
+			}
+			for i, part := range info.Parts {
+				if part != filepath.Join(folder, test.expected[i]) {
+					t.Errorf("part %v - expected %v, got %v", i, test.expected[i], part)
+				}
+			}
+			if len(info.Warnings) != test.warnings {
+				t.Errorf("expected %v warnings, got %v", test.warnings, info.Warnings)
+			}
+		})
+	}
+}
@@ -8,8 +8,10 @@ require (
 	github.com/asticode/go-astilectron-bootstrap v0.4.1
 	github.com/avast/retry-go v2.6.1+incompatible
 	github.com/boltdb/bolt v1.3.1
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/go-openapi/strfmt v0.19.2 // indirect
 	github.com/jedib0t/go-pretty v4.3.0+incompatible
+	github.com/klauspost/compress v1.11.4
 	github.com/magiconair/properties v1.8.1
 	github.com/mcuadros/go-version v0.0.0-20190830083331-035f6764e8d2
 	github.com/niemeyer/pretty v0.0.0-20200227124842-a10e7caefd8e // indirect
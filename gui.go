@@ -220,6 +220,12 @@ func (g *GUI) handleMessage(m *astilectron.EventMessage) interface{} {
 		response := LocalLibraryData{}
 		libraryData := []LibraryTemplateData{}
 		issues := []Pair{}
+		if localDB.KeysError != nil {
+			issues = append(issues, Pair{Key: "prod.keys", Value: localDB.KeysError.Error()})
+		}
+		for _, scanErr := range localDB.ScanErrors {
+			issues = append(issues, Pair{Key: scanErr.Path, Value: "unable to read, the library may be incomplete - " + scanErr.Err.Error()})
+		}
 		for k, v := range localDB.TitlesMap {
 			if v.BaseExist {
 				version := ""
@@ -228,6 +234,9 @@ func (g *GUI) handleMessage(m *astilectron.EventMessage) interface{} {
 					version = v.File.Metadata.Ncap.DisplayVersion
 					name = v.File.Metadata.Ncap.TitleName["AmericanEnglish"].Title
 				}
+				if name == "" {
+					name = g.localDbManager.TitleName(v.TitleId())
+				}
 
 				if v.Updates != nil && len(v.Updates) != 0 {
 					if v.Updates[v.LatestUpdate].Metadata.Ncap != nil {
@@ -315,6 +324,9 @@ func (g *GUI) handleMessage(m *astilectron.EventMessage) interface{} {
 
 func getType(gameFile *db.SwitchGameFiles) string {
 	if gameFile.IsSplit {
+		if parts := gameFile.File.NumParts(); parts > 1 {
+			return "split (" + strconv.Itoa(parts) + " parts)"
+		}
 		return "split"
 	}
 	if gameFile.MultiContent {
@@ -418,7 +430,7 @@ func (g *GUI) organizeLibrary() {
 		g.state.window.SendMessage(Message{Name: "error", Payload: "the organize options in settings.json are not valid, please check that the template contains file/folder name"}, func(m *astilectron.EventMessage) {})
 		return
 	}
-	process.OrganizeByFolders(folderToScan, g.state.localDB, g.state.switchDB, g)
+	process.OrganizeByFolders(folderToScan, g.state.localDB, g.localDbManager.SplitSchemes(), g.state.switchDB, g)
 	if settings.ReadSettings(g.baseFolder).OrganizeOptions.DeleteOldUpdateFiles {
 		process.DeleteOldUpdates(g.baseFolder, g.state.localDB, g)
 	}
@@ -0,0 +1,61 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"sort"
+)
+
+type FormatDuplicateFile struct {
+	Format string `json:"format"`
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+}
+
+type FormatDuplicate struct {
+	TitleId string                `json:"title_id"`
+	Files   []FormatDuplicateFile `json:"files"`
+}
+
+// DuplicateAcrossFormats lists the titles whose base exists in more than one format (e.g. both XCI and NSP)
+func DuplicateAcrossFormats(localDB *db.LocalSwitchFilesDB) []FormatDuplicate {
+	var result []FormatDuplicate
+
+	for _, switchFile := range localDB.TitlesMap {
+		if !switchFile.BaseExist {
+			continue
+		}
+		bases := []db.SwitchFileInfo{switchFile.File}
+		for _, duplicate := range switchFile.Duplicates {
+			if duplicate.Metadata != nil && duplicate.Metadata.TitleId == switchFile.File.Metadata.TitleId {
+				bases = append(bases, duplicate)
+			}
+		}
+
+		formats := map[string]struct{}{}
+		files := make([]FormatDuplicateFile, 0, len(bases))
+		for _, base := range bases {
+			formats[base.Format] = struct{}{}
+			files = append(files, FormatDuplicateFile{
+				Format: base.Format,
+				Path:   localDB.FilePath(base.ExtendedInfo),
+				Size:   fileSize(base),
+			})
+		}
+		if len(formats) < 2 {
+			continue
+		}
+		result = append(result, FormatDuplicate{TitleId: switchFile.File.Metadata.TitleId, Files: files})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].TitleId < result[j].TitleId
+	})
+	return result
+}
+
+func fileSize(file db.SwitchFileInfo) int64 {
+	if file.Split != nil {
+		return file.Split.TotalSize
+	}
+	return file.ExtendedInfo.Size
+}
@@ -0,0 +1,56 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"sort"
+	"testing"
+)
+
+func TestDuplicateAcrossFormats(t *testing.T) {
+	withFormat := func(file db.SwitchFileInfo, format string, size int64) db.SwitchFileInfo {
+		file.Format = format
+		file.ExtendedInfo.Size = size
+		return file
+	}
+	localDB := db.Group([]db.SwitchFileInfo{
+		withFormat(testSwitchFile("mario.nsp", "0100000000010000", 0), "nsp", 100),
+		withFormat(testSwitchFile("mario.xci", "0100000000010000", 0), "xci", 200),
+		withFormat(testSwitchFile("zelda.nsp", "0100000000020000", 0), "nsp", 300),
+		withFormat(testSwitchFile("zelda.nsz", "0100000000020000", 0), "nsz", 150),
+		//the same format twice is a plain duplicate
+		withFormat(testSwitchFile("kirby.nsp", "0100000000030000", 0), "nsp", 10),
+		withFormat(testSwitchFile("kirby copy.nsp", "0100000000030000", 0), "nsp", 10),
+		//an update in another format is not a duplicate of the base
+		withFormat(testSwitchFile("metroid.nsp", "0100000000040000", 0), "nsp", 10),
+		withFormat(testSwitchFile("metroid update.nsz", "0100000000040800", 65536), "nsz", 10),
+	}, db.GroupOptions{})
+
+	duplicates := DuplicateAcrossFormats(localDB)
+	expected := []FormatDuplicate{
+		{TitleId: "0100000000010000", Files: []FormatDuplicateFile{
+			{Format: "nsp", Path: "/games/mario.nsp", Size: 100},
+			{Format: "xci", Path: "/games/mario.xci", Size: 200},
+		}},
+		{TitleId: "0100000000020000", Files: []FormatDuplicateFile{
+			{Format: "nsp", Path: "/games/zelda.nsp", Size: 300},
+			{Format: "nsz", Path: "/games/zelda.nsz", Size: 150},
+		}},
+	}
+	if len(duplicates) != len(expected) {
+		t.Fatalf("expected %v duplicates, got %+v", len(expected), duplicates)
+	}
+	for i, duplicate := range duplicates {
+		//the file kept as the base depends on the grouping
+		sort.Slice(duplicate.Files, func(i, j int) bool {
+			return duplicate.Files[i].Format < duplicate.Files[j].Format
+		})
+		if duplicate.TitleId != expected[i].TitleId || len(duplicate.Files) != len(expected[i].Files) {
+			t.Fatalf("expected %+v, got %+v", expected[i], duplicate)
+		}
+		for j, file := range duplicate.Files {
+			if file != expected[i].Files[j] {
+				t.Errorf("expected %+v, got %+v", expected[i].Files[j], file)
+			}
+		}
+	}
+}
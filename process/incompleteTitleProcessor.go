@@ -159,3 +159,50 @@ func ScanForBrokenFiles(localDB map[string]*db.SwitchGameFiles) []db.SwitchFileI
 	}
 	return result
 }
+
+type DLCIssue struct {
+	TitleId          string            `json:"title_id"`
+	DlcTitleId       string            `json:"dlc_title_id"`
+	File             db.SwitchFileInfo `json:"-"`
+	RequiredVersion  int               `json:"required_version"`
+	InstalledVersion int               `json:"installed_version"`
+}
+
+// DLCPlayability flags local DLC which requires a newer base/update than the one available locally
+func DLCPlayability(localDB *db.LocalSwitchFilesDB) []DLCIssue {
+	var result []DLCIssue
+
+	for _, switchFile := range localDB.TitlesMap {
+
+		//without a base the DLC is not playable anyway (reported as a missing base)
+		if switchFile.BaseExist == false {
+			continue
+		}
+
+		installedVersion := switchFile.LatestUpdate
+		if switchFile.File.Metadata.Version > installedVersion {
+			installedVersion = switchFile.File.Metadata.Version
+		}
+
+		for id, dlc := range switchFile.Dlc {
+			if dlc.Metadata == nil || dlc.Metadata.RequiredApplicationVersion <= installedVersion {
+				continue
+			}
+			result = append(result, DLCIssue{
+				TitleId:          switchFile.File.Metadata.TitleId,
+				DlcTitleId:       id,
+				File:             dlc,
+				RequiredVersion:  dlc.Metadata.RequiredApplicationVersion,
+				InstalledVersion: installedVersion,
+			})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TitleId != result[j].TitleId {
+			return result[i].TitleId < result[j].TitleId
+		}
+		return result[i].DlcTitleId < result[j].DlcTitleId
+	})
+	return result
+}
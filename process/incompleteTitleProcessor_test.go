@@ -0,0 +1,82 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/switchfs"
+	"testing"
+)
+
+func testSwitchFile(fileName string, titleId string, version int) db.SwitchFileInfo {
+	return db.SwitchFileInfo{
+		ExtendedInfo: db.ExtendedFileInfo{FileName: fileName, BaseFolder: "/games/", Size: 1},
+		Metadata:     &switchfs.ContentMetaAttributes{TitleId: titleId, Version: version},
+		Format:       "nsp",
+	}
+}
+
+func testDlc(fileName string, titleId string, requiredVersion int) db.SwitchFileInfo {
+	dlc := testSwitchFile(fileName, titleId, 0)
+	dlc.Metadata.RequiredApplicationVersion = requiredVersion
+	return dlc
+}
+
+func TestDLCPlayability(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []db.SwitchFileInfo
+		expected []DLCIssue
+	}{
+		{
+			name: "dlc with a base",
+			files: []db.SwitchFileInfo{
+				testSwitchFile("base.nsp", "0100000000010000", 0),
+				testDlc("dlc.nsp", "0100000000011001", 0),
+			},
+		},
+		{
+			name: "dlc without a base",
+			files: []db.SwitchFileInfo{
+				testDlc("dlc.nsp", "0100000000011001", 65536),
+			},
+		},
+		{
+			name: "dlc with the required update",
+			files: []db.SwitchFileInfo{
+				testSwitchFile("base.nsp", "0100000000010000", 0),
+				testSwitchFile("update.nsp", "0100000000010800", 131072),
+				testDlc("dlc.nsp", "0100000000011001", 65536),
+			},
+		},
+		{
+			name: "dlc with a missing update",
+			files: []db.SwitchFileInfo{
+				testSwitchFile("base.nsp", "0100000000010000", 0),
+				testSwitchFile("update.nsp", "0100000000010800", 65536),
+				testDlc("dlc2.nsp", "0100000000011002", 131072),
+				testDlc("dlc1.nsp", "0100000000011001", 196608),
+			},
+			expected: []DLCIssue{
+				{TitleId: "0100000000010000", DlcTitleId: "0100000000011001", RequiredVersion: 196608, InstalledVersion: 65536},
+				{TitleId: "0100000000010000", DlcTitleId: "0100000000011002", RequiredVersion: 131072, InstalledVersion: 65536},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			issues := DLCPlayability(db.Group(test.files, db.GroupOptions{}))
+			if len(issues) != len(test.expected) {
+				t.Fatalf("expected %v issues, got %+v", len(test.expected), issues)
+			}
+			for i, issue := range issues {
+				expected := test.expected[i]
+				if issue.TitleId != expected.TitleId || issue.DlcTitleId != expected.DlcTitleId ||
+					issue.RequiredVersion != expected.RequiredVersion || issue.InstalledVersion != expected.InstalledVersion {
+					t.Errorf("expected %+v, got %+v", expected, issue)
+				}
+				if issue.File.Metadata.TitleId != expected.DlcTitleId {
+					t.Errorf("expected the DLC file, got %+v", issue.File)
+				}
+			}
+		})
+	}
+}
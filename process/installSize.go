@@ -0,0 +1,48 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"sort"
+)
+
+type TitleInstallSize struct {
+	TitleId     string `json:"title_id"`
+	BaseSize    int64  `json:"base_size"`
+	UpdateSize  int64  `json:"update_size"`
+	DlcSize     int64  `json:"dlc_size"`
+	InstallSize int64  `json:"install_size"`
+}
+
+// InstallSizeReport returns the space required to install each title (base, latest update and all DLC),
+// sorted by the install size (descending), together with the total space needed to install everything.
+// Sizes are taken from the CNMT (see switchfs.ContentMetaAttributes.InstallSize), files without metadata count as zero.
+func InstallSizeReport(localDB *db.LocalSwitchFilesDB) ([]TitleInstallSize, int64) {
+	var result []TitleInstallSize
+	total := int64(0)
+
+	for _, switchFile := range localDB.TitlesMap {
+		titleSize := TitleInstallSize{TitleId: switchFile.TitleId()}
+		if switchFile.BaseExist && switchFile.File.Metadata != nil {
+			titleSize.BaseSize = switchFile.File.Metadata.InstallSize
+		}
+		if update, ok := switchFile.Updates[switchFile.LatestUpdate]; ok && update.Metadata != nil {
+			titleSize.UpdateSize = update.Metadata.InstallSize
+		}
+		for _, dlc := range switchFile.Dlc {
+			if dlc.Metadata != nil {
+				titleSize.DlcSize += dlc.Metadata.InstallSize
+			}
+		}
+		titleSize.InstallSize = titleSize.BaseSize + titleSize.UpdateSize + titleSize.DlcSize
+		total += titleSize.InstallSize
+		result = append(result, titleSize)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].InstallSize != result[j].InstallSize {
+			return result[i].InstallSize > result[j].InstallSize
+		}
+		return result[i].TitleId < result[j].TitleId
+	})
+	return result, total
+}
@@ -0,0 +1,47 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/fileio"
+	"testing"
+)
+
+func TestInstallSizeReport(t *testing.T) {
+	withInstallSize := func(file db.SwitchFileInfo, installSize int64) db.SwitchFileInfo {
+		file.Metadata.InstallSize = installSize
+		return file
+	}
+	//split into two parts, the install size is taken from the CNMT and not from the parts
+	splitBase := withInstallSize(testSwitchFile("zelda.nsp.00", "0100000000020000", 0), 5000)
+	splitBase.Split = &fileio.SplitFileInfo{Parts: []string{"/games/zelda.nsp.00", "/games/zelda.nsp.01"}, NumParts: 2, TotalSize: 4000}
+
+	localDB := db.Group([]db.SwitchFileInfo{
+		withInstallSize(testSwitchFile("mario.nsp", "0100000000010000", 0), 1000),
+		//only the latest update is installed
+		withInstallSize(testSwitchFile("mario update1.nsp", "0100000000010800", 65536), 300),
+		withInstallSize(testSwitchFile("mario update2.nsp", "0100000000010800", 131072), 200),
+		withInstallSize(testSwitchFile("mario dlc1.nsp", "0100000000011001", 0), 30),
+		withInstallSize(testSwitchFile("mario dlc2.nsp", "0100000000011002", 0), 20),
+		splitBase,
+		//without a base
+		withInstallSize(testSwitchFile("dlc.nsp", "0100000000031001", 0), 10),
+	}, db.GroupOptions{})
+
+	sizes, total := InstallSizeReport(localDB)
+	expected := []TitleInstallSize{
+		{TitleId: "0100000000020000", BaseSize: 5000, InstallSize: 5000},
+		{TitleId: "0100000000010000", BaseSize: 1000, UpdateSize: 200, DlcSize: 50, InstallSize: 1250},
+		{TitleId: "0100000000030000", DlcSize: 10, InstallSize: 10},
+	}
+	if len(sizes) != len(expected) {
+		t.Fatalf("expected %v titles, got %+v", len(expected), sizes)
+	}
+	for i, size := range sizes {
+		if size != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], size)
+		}
+	}
+	if total != 6260 {
+		t.Errorf("expected a total of 6260, got %v", total)
+	}
+}
@@ -0,0 +1,79 @@
+package process
+
+import (
+	"encoding/json"
+	"github.com/giwty/switch-library-manager/db"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type InstallerIndexFile struct {
+	Url  string `json:"url"`
+	Size int64  `json:"size"`
+}
+
+// InstallerIndex is the index format understood by network installers (e.g. Tinfoil / Awoo)
+type InstallerIndex struct {
+	Files   []InstallerIndexFile `json:"files"`
+	Success string               `json:"success,omitempty"`
+}
+
+// ExportInstallerIndex writes an installer index (JSON) of the library files, each file URL is the baseURL followed by
+// the file path relative to its scan folder. only the kept files are listed (skipped files, such as old updates
+// and duplicates, are left out) unless includeAll is set.
+func ExportInstallerIndex(localDB *db.LocalSwitchFilesDB, baseURL string, w io.Writer, includeAll bool) error {
+	files := map[db.ExtendedFileInfo]struct{}{}
+	addFile := func(file db.ExtendedFileInfo) {
+		if _, skipped := localDB.Skipped[file]; skipped && !includeAll {
+			return
+		}
+		files[file] = struct{}{}
+	}
+	for _, switchFile := range localDB.TitlesMap {
+		if switchFile.BaseExist {
+			addFile(switchFile.File.ExtendedInfo)
+		}
+		for _, update := range switchFile.Updates {
+			addFile(update.ExtendedInfo)
+		}
+		for _, dlc := range switchFile.Dlc {
+			addFile(dlc.ExtendedInfo)
+		}
+	}
+	if includeAll {
+		for file := range localDB.Skipped {
+			files[file] = struct{}{}
+		}
+	}
+
+	index := InstallerIndex{Files: make([]InstallerIndexFile, 0, len(files))}
+	for file := range files {
+		index.Files = append(index.Files, InstallerIndexFile{Url: installerFileUrl(baseURL, file), Size: file.Size})
+	}
+	sort.Slice(index.Files, func(i, j int) bool {
+		return index.Files[i].Url < index.Files[j].Url
+	})
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(index)
+}
+
+// installerFileUrl returns the (escaped) URL of the file, relative to its scan folder
+func installerFileUrl(baseURL string, file db.ExtendedFileInfo) string {
+	path := filepath.Join(file.BaseFolder, file.FileName)
+	relPath := file.FileName
+	if file.Root != "" {
+		if rel, err := filepath.Rel(file.Root, path); err == nil {
+			relPath = rel
+		}
+	}
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/" + strings.Join(parts, "/")
+}
@@ -0,0 +1,72 @@
+package process
+
+import (
+	"bytes"
+	"github.com/giwty/switch-library-manager/db"
+	"testing"
+)
+
+func TestExportInstallerIndex(t *testing.T) {
+	inFolder := func(file db.SwitchFileInfo, folder string) db.SwitchFileInfo {
+		file.ExtendedInfo.BaseFolder = folder
+		file.ExtendedInfo.Root = "/games"
+		return file
+	}
+	localDB := db.Group([]db.SwitchFileInfo{
+		inFolder(testSwitchFile("Super Mario Odyssey [0100000000010000][v0].nsp", "0100000000010000", 0), "/games/Mario/"),
+		inFolder(testSwitchFile("mario update1.nsp", "0100000000010800", 65536), "/games/Mario/"),
+		inFolder(testSwitchFile("mario update2.nsp", "0100000000010800", 131072), "/games/Mario/"),
+		inFolder(testSwitchFile("zelda#1.nsp", "0100000000020000", 0), "/games/"),
+	}, db.GroupOptions{})
+
+	expected := map[bool]string{
+		false: `{
+  "files": [
+    {
+      "url": "http://host:8080/games/Mario/Super%20Mario%20Odyssey%20%5B0100000000010000%5D%5Bv0%5D.nsp",
+      "size": 1
+    },
+    {
+      "url": "http://host:8080/games/Mario/mario%20update2.nsp",
+      "size": 1
+    },
+    {
+      "url": "http://host:8080/games/zelda%231.nsp",
+      "size": 1
+    }
+  ]
+}
+`,
+		//the old update is listed as well
+		true: `{
+  "files": [
+    {
+      "url": "http://host:8080/games/Mario/Super%20Mario%20Odyssey%20%5B0100000000010000%5D%5Bv0%5D.nsp",
+      "size": 1
+    },
+    {
+      "url": "http://host:8080/games/Mario/mario%20update1.nsp",
+      "size": 1
+    },
+    {
+      "url": "http://host:8080/games/Mario/mario%20update2.nsp",
+      "size": 1
+    },
+    {
+      "url": "http://host:8080/games/zelda%231.nsp",
+      "size": 1
+    }
+  ]
+}
+`,
+	}
+	for _, includeAll := range []bool{false, true} {
+		buf := bytes.Buffer{}
+		if err := ExportInstallerIndex(localDB, "http://host:8080/games/", &buf, includeAll); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != expected[includeAll] {
+			t.Errorf("unexpected index (include all %v):\n%v\nexpected:\n%v", includeAll, buf.String(), expected[includeAll])
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"path/filepath"
+	"sort"
+)
+
+// AllFilePaths returns the sorted, de-duplicated paths of all the files (bases, updates, DLC
+// and every split part) represented in the local library
+func AllFilePaths(localDB *db.LocalSwitchFilesDB) []string {
+	paths := map[string]struct{}{}
+	addFile := func(file db.SwitchFileInfo) {
+		if file.Split != nil && len(file.Split.Parts) != 0 {
+			for _, part := range file.Split.Parts {
+				paths[part] = struct{}{}
+			}
+			return
+		}
+		paths[filepath.Join(file.ExtendedInfo.BaseFolder, file.ExtendedInfo.FileName)] = struct{}{}
+	}
+
+	for _, switchFile := range localDB.TitlesMap {
+		if switchFile.BaseExist {
+			addFile(switchFile.File)
+		}
+		for _, update := range switchFile.Updates {
+			addFile(update)
+		}
+		for _, dlc := range switchFile.Dlc {
+			addFile(dlc)
+		}
+	}
+
+	result := make([]string, 0, len(paths))
+	for path := range paths {
+		result = append(result, path)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,53 @@
+package process
+
+import (
+	"fmt"
+	"github.com/giwty/switch-library-manager/db"
+	"sort"
+	"strings"
+)
+
+type MismatchedPair struct {
+	Base   db.SwitchFileInfo `json:"-"`
+	Update db.SwitchFileInfo `json:"-"`
+	Reason string            `json:"reason"`
+}
+
+// MismatchedPairs reports base + update combinations which are unlikely to belong together
+// (update targeting a different application, or base and update with no common language).
+// Checks are best effort - pairs with incomplete metadata are not reported.
+func MismatchedPairs(localDB *db.LocalSwitchFilesDB) []MismatchedPair {
+	var result []MismatchedPair
+
+	for _, switchFile := range localDB.TitlesMap {
+		if !switchFile.BaseExist || len(switchFile.Updates) == 0 {
+			continue
+		}
+		base := switchFile.File
+		for _, update := range switchFile.Updates {
+			if update.Metadata == nil {
+				continue
+			}
+			if update.Metadata.ApplicationId != "" &&
+				!strings.EqualFold(update.Metadata.ApplicationId, base.Metadata.TitleId) {
+				result = append(result, MismatchedPair{Base: base, Update: update,
+					Reason: fmt.Sprintf("update targets application [%v], but the base is [%v]", update.Metadata.ApplicationId, base.Metadata.TitleId)})
+				continue
+			}
+			if base.Metadata.Ncap != nil && update.Metadata.Ncap != nil &&
+				base.Metadata.Ncap.SupportedLanguageFlag != 0 && update.Metadata.Ncap.SupportedLanguageFlag != 0 &&
+				base.Metadata.Ncap.SupportedLanguageFlag&update.Metadata.Ncap.SupportedLanguageFlag == 0 {
+				result = append(result, MismatchedPair{Base: base, Update: update,
+					Reason: "base and update have no supported language in common (region mismatch)"})
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Base.Metadata.TitleId != result[j].Base.Metadata.TitleId {
+			return result[i].Base.Metadata.TitleId < result[j].Base.Metadata.TitleId
+		}
+		return result[i].Update.Metadata.Version < result[j].Update.Metadata.Version
+	})
+	return result
+}
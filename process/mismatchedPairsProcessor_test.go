@@ -0,0 +1,74 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/switchfs"
+	"testing"
+)
+
+func TestMismatchedPairs(t *testing.T) {
+	withLanguages := func(file db.SwitchFileInfo, languages uint32) db.SwitchFileInfo {
+		file.Metadata.Ncap = &switchfs.Nacp{SupportedLanguageFlag: languages}
+		return file
+	}
+	withApplication := func(file db.SwitchFileInfo, applicationId string) db.SwitchFileInfo {
+		file.Metadata.ApplicationId = applicationId
+		return file
+	}
+	tests := []struct {
+		name     string
+		files    []db.SwitchFileInfo
+		expected []int
+	}{
+		{
+			name: "matching pair",
+			files: []db.SwitchFileInfo{
+				withLanguages(testSwitchFile("base.nsp", "0100000000010000", 0), 3),
+				withLanguages(withApplication(testSwitchFile("update.nsp", "0100000000010800", 65536), "0100000000010000"), 1),
+			},
+		},
+		{
+			name: "update of another application",
+			files: []db.SwitchFileInfo{
+				testSwitchFile("base.nsp", "0100000000010000", 0),
+				withApplication(testSwitchFile("update.nsp", "0100000000010800", 65536), "0100000000020000"),
+			},
+			expected: []int{65536},
+		},
+		{
+			name: "no common language",
+			files: []db.SwitchFileInfo{
+				withLanguages(testSwitchFile("base.nsp", "0100000000010000", 0), 1),
+				withLanguages(testSwitchFile("update1.nsp", "0100000000010800", 65536), 1),
+				withLanguages(testSwitchFile("update2.nsp", "0100000000010800", 131072), 2),
+			},
+			expected: []int{131072},
+		},
+		{
+			name: "missing base",
+			files: []db.SwitchFileInfo{
+				withApplication(testSwitchFile("update.nsp", "0100000000010800", 65536), "0100000000020000"),
+			},
+		},
+		{
+			name: "missing update",
+			files: []db.SwitchFileInfo{
+				withLanguages(testSwitchFile("base.nsp", "0100000000010000", 0), 1),
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pairs := MismatchedPairs(db.Group(test.files, db.GroupOptions{}))
+			if len(pairs) != len(test.expected) {
+				t.Fatalf("expected %v mismatched pairs, got %+v", len(test.expected), pairs)
+			}
+			for i, pair := range pairs {
+				if pair.Base.Metadata.TitleId != "0100000000010000" || pair.Update.Metadata.Version != test.expected[i] ||
+					pair.Reason == "" {
+					t.Errorf("unexpected mismatched pair %+v", pair)
+				}
+			}
+		})
+	}
+}
@@ -2,7 +2,9 @@ package process
 
 import (
 	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/fileio"
 	"github.com/giwty/switch-library-manager/settings"
+	"github.com/giwty/switch-library-manager/switchfs"
 	"go.uber.org/zap"
 	"io/ioutil"
 	"os"
@@ -26,6 +28,10 @@ func DeleteOldUpdates(baseFolder string, localDB *db.LocalSwitchFilesDB, updateP
 		switch v.ReasonCode {
 		//case db.REASON_DUPLICATE:
 		case db.REASON_OLD_UPDATE:
+			//the file is stored in an archive, deleting the archive would delete the other files as well
+			if k.ArchiveEntry != "" {
+				continue
+			}
 			fileToRemove := filepath.Join(k.BaseFolder, k.FileName)
 			if updateProgress != nil {
 				updateProgress.UpdateProgress(0, 0, "deleting "+fileToRemove)
@@ -55,8 +61,11 @@ func DeleteOldUpdates(baseFolder string, localDB *db.LocalSwitchFilesDB, updateP
 	}
 }
 
+// OrganizeByFolders organizes the titles as configured in the organize_options, split files are told by the
+// given naming schemes (see db.LocalSwitchDBManager.SplitSchemes)
 func OrganizeByFolders(baseFolder string,
 	localDB *db.LocalSwitchFilesDB,
+	splitSchemes switchfs.SplitSchemes,
 	titlesDB *db.SwitchTitlesDB,
 	updateProgress db.ProgressUpdater) {
 
@@ -114,33 +123,34 @@ func OrganizeByFolders(baseFolder string,
 		if v.IsSplit {
 			//in case of a split file, we only rename the folder and then move all the split
 			//files with the new folder
-			files, err := ioutil.ReadDir(v.File.ExtendedInfo.BaseFolder)
+			parts, err := fileio.GetSplitFileParts(splitSchemes, filepath.Join(v.File.ExtendedInfo.BaseFolder, v.File.ExtendedInfo.FileName))
 			if err != nil {
 				continue
 			}
 
-			for _, file := range files {
-				if _, err := strconv.Atoi(file.Name()[len(file.Name())-1:]); err == nil {
-					from := filepath.Join(v.File.ExtendedInfo.BaseFolder, file.Name())
-					to := filepath.Join(destinationPath, file.Name())
-					err := moveFile(from, to)
-					if err != nil {
-						zap.S().Errorf("Failed to move file [%v]\n", err)
-						continue
-					}
+			for _, from := range parts {
+				to := filepath.Join(destinationPath, filepath.Base(from))
+				err := moveFile(from, to)
+				if err != nil {
+					zap.S().Errorf("Failed to move file [%v]\n", err)
+					continue
 				}
 			}
 			continue
 
 		}
 
-		//process base title
-		from := filepath.Join(v.File.ExtendedInfo.BaseFolder, v.File.ExtendedInfo.FileName)
-		to := filepath.Join(destinationPath, getFileName(options, v.File.ExtendedInfo.FileName, templateData))
-		err := moveFile(from, to)
-		if err != nil {
-			zap.S().Errorf("Failed to move file [%v]\n", err)
-			continue
+		//process base title, files stored in archives are not moved (the archive may hold other titles)
+		var from, to string
+		var err error
+		if v.File.ExtendedInfo.ArchiveEntry == "" {
+			from = filepath.Join(v.File.ExtendedInfo.BaseFolder, v.File.ExtendedInfo.FileName)
+			to = filepath.Join(destinationPath, getFileName(options, v.File.ExtendedInfo.FileName, templateData))
+			err = moveFile(from, to)
+			if err != nil {
+				zap.S().Errorf("Failed to move file [%v]\n", err)
+				continue
+			}
 		}
 
 		//process updates
@@ -148,6 +158,9 @@ func OrganizeByFolders(baseFolder string,
 			if updateInfo.Metadata != nil {
 				templateData[settings.TEMPLATE_TITLE_ID] = updateInfo.Metadata.TitleId
 			}
+			if updateInfo.ExtendedInfo.ArchiveEntry != "" {
+				continue
+			}
 			templateData[settings.TEMPLATE_VERSION] = strconv.Itoa(update)
 			templateData[settings.TEMPLATE_TYPE] = "UPD"
 			if updateInfo.Metadata.Ncap != nil {
@@ -171,6 +184,9 @@ func OrganizeByFolders(baseFolder string,
 
 		//process DLC
 		for id, dlc := range v.Dlc {
+			if dlc.ExtendedInfo.ArchiveEntry != "" {
+				continue
+			}
 			if dlc.Metadata != nil {
 				templateData[settings.TEMPLATE_VERSION] = strconv.Itoa(dlc.Metadata.Version)
 			}
@@ -260,14 +276,8 @@ func getTitleName(switchTitle *db.SwitchTitle, v *db.SwitchGameFiles) string {
 		}
 	}
 
-	if v.File.Metadata.Ncap != nil {
-		name := v.File.Metadata.Ncap.TitleName["AmericanEnglish"].Title
-		if name != "" {
-			return name
-		}
-	}
-	//for non eshop games (cartridge only), grab the name from the file
-	return db.ParseTitleNameFromFileName(v.File.ExtendedInfo.FileName)
+	//read from the NACP, for non eshop games (cartridge only) grab the name from the file
+	return v.File.Name()
 
 }
 
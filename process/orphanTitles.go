@@ -0,0 +1,50 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+)
+
+const (
+	//no base, only updates older than the latest known version
+	ORPHAN_OUTDATED_UPDATES = "outdated_updates"
+	//no base, the latest update is present (or the latest version is unknown)
+	ORPHAN_UPDATES = "updates"
+	//no base and no updates, DLC only
+	ORPHAN_DLC = "dlc"
+)
+
+type OrphanTitle struct {
+	Title *db.SwitchGameFiles
+	Kind  string
+	//the latest local update version (0 when there are no local updates)
+	LocalUpdate int
+	//the latest known update version (0 when unknown)
+	LatestUpdate int
+}
+
+// OrphanTitles reports the local titles without a base, sorted by title id. titles holding only old updates
+// (neither the base nor the latest update, usually useless files) are classified as ORPHAN_OUTDATED_UPDATES.
+// versions maps a base title id to the latest update version (see CompletenessCatalogs, db.LoadVersionsCatalog).
+// titles with a base and a missing update are not orphans (see ScanForMissingUpdates)
+func OrphanTitles(localDB *db.LocalSwitchFilesDB, versions map[string]int) []OrphanTitle {
+	var titles []*db.SwitchGameFiles
+	for _, switchFile := range localDB.TitlesMap {
+		if !switchFile.BaseExist {
+			titles = append(titles, switchFile)
+		}
+	}
+
+	result := make([]OrphanTitle, 0, len(titles))
+	for _, switchFile := range sortedByTitleId(titles) {
+		orphan := OrphanTitle{Title: switchFile, Kind: ORPHAN_DLC, LatestUpdate: versions[switchFile.TitleId()]}
+		if len(switchFile.Updates) != 0 {
+			orphan.LocalUpdate = switchFile.LatestUpdate
+			orphan.Kind = ORPHAN_UPDATES
+			if orphan.LocalUpdate < orphan.LatestUpdate {
+				orphan.Kind = ORPHAN_OUTDATED_UPDATES
+			}
+		}
+		result = append(result, orphan)
+	}
+	return result
+}
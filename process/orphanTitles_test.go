@@ -0,0 +1,50 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"testing"
+)
+
+func TestOrphanTitles(t *testing.T) {
+	versions := map[string]int{"0100000000020000": 131072, "0100000000030000": 131072}
+	localDB := db.Group([]db.SwitchFileInfo{
+		//not an orphan
+		testSwitchFile("mario.nsp", "0100000000010000", 0),
+		testSwitchFile("mario dlc.nsp", "0100000000011001", 0),
+		//the latest update without the base
+		testSwitchFile("zelda update.nsp", "0100000000020800", 131072),
+		testSwitchFile("zelda dlc.nsp", "0100000000021001", 0),
+		//an old update without the base
+		testSwitchFile("kirby update.nsp", "0100000000030800", 65536),
+		//an update of a title missing from the catalog
+		testSwitchFile("metroid update.nsp", "0100000000040800", 65536),
+		//DLC without the base
+		testSwitchFile("pikmin dlc.nsp", "0100000000051001", 0),
+	}, db.GroupOptions{})
+
+	orphans := OrphanTitles(localDB, versions)
+	expected := []struct {
+		titleId      string
+		kind         string
+		localUpdate  int
+		latestUpdate int
+	}{
+		{"0100000000020000", ORPHAN_UPDATES, 131072, 131072},
+		{"0100000000030000", ORPHAN_OUTDATED_UPDATES, 65536, 131072},
+		{"0100000000040000", ORPHAN_UPDATES, 65536, 0},
+		{"0100000000050000", ORPHAN_DLC, 0, 0},
+	}
+	if len(orphans) != len(expected) {
+		t.Fatalf("expected %v orphans, got %+v", len(expected), orphans)
+	}
+	for i, orphan := range orphans {
+		e := expected[i]
+		if orphan.Title.TitleId() != e.titleId || orphan.Kind != e.kind || orphan.LocalUpdate != e.localUpdate ||
+			orphan.LatestUpdate != e.latestUpdate {
+			t.Errorf("expected %+v, got %v %v %v %v", e, orphan.Title.TitleId(), orphan.Kind, orphan.LocalUpdate, orphan.LatestUpdate)
+		}
+	}
+	if len(orphans[0].Title.Dlc) != 1 {
+		t.Errorf("expected the DLC to be grouped with the orphan update, got %+v", orphans[0].Title)
+	}
+}
@@ -0,0 +1,83 @@
+package process
+
+import (
+	"fmt"
+	"io"
+	"robpike.io/nihongo"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	//raw UTF-8 (default)
+	OUTPUT_ENCODING_UTF8 = "utf8"
+	//UTF-8 preceded by a byte order mark, for Windows tools which don't detect UTF-8 otherwise
+	OUTPUT_ENCODING_UTF8_BOM = "utf8-bom"
+	//ASCII only - kana are transliterated to romaji, tree symbols are replaced and other characters are escaped (\uXXXX)
+	OUTPUT_ENCODING_ASCII = "ascii"
+)
+
+var asciiSymbols = strings.NewReplacer("├── ", "|-- ", "└── ", "`-- ", "→", "->", "™", "(TM)", "®", "(R)", "’", "'")
+
+type encodingWriter struct {
+	w        io.Writer
+	encoding string
+	//bytes of an incomplete UTF-8 sequence, completed by the next write
+	pending []byte
+	started bool
+}
+
+// NewEncodingWriter wraps the writer of a text report (e.g. WriteTree) to output the given encoding
+// (see OUTPUT_ENCODING_*), the writer is returned as is for raw UTF-8 or an unknown encoding
+func NewEncodingWriter(w io.Writer, encoding string) io.Writer {
+	switch encoding {
+	case OUTPUT_ENCODING_UTF8_BOM, OUTPUT_ENCODING_ASCII:
+		return &encodingWriter{w: w, encoding: encoding}
+	}
+	return w
+}
+
+func (e *encodingWriter) Write(p []byte) (int, error) {
+	if e.encoding == OUTPUT_ENCODING_UTF8_BOM {
+		if !e.started {
+			e.started = true
+			if _, err := e.w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+				return 0, err
+			}
+		}
+		return e.w.Write(p)
+	}
+
+	data := append(e.pending, p...)
+	//keep an incomplete trailing rune for the next write
+	end := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				end = i
+			}
+			break
+		}
+	}
+	e.pending = append([]byte{}, data[end:]...)
+	if _, err := io.WriteString(e.w, ToASCII(string(data[:end]))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// ToASCII transliterates (kana to romaji, common symbols) or escapes (\uXXXX) the non-ASCII characters of the text
+func ToASCII(text string) string {
+	text = nihongo.RomajiString(asciiSymbols.Replace(text))
+	var sb strings.Builder
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			sb.WriteRune(r)
+		} else if r <= 0xFFFF {
+			sb.WriteString(fmt.Sprintf("\\u%04X", r))
+		} else {
+			sb.WriteString(fmt.Sprintf("\\U%08X", r))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,53 @@
+package process
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestNewEncodingWriter(t *testing.T) {
+	text := "└── Pokémon™: Let’s Go → 2.0\n"
+	tests := []struct {
+		encoding string
+		expected string
+	}{
+		{OUTPUT_ENCODING_UTF8, text + text},
+		{OUTPUT_ENCODING_UTF8_BOM, "\xEF\xBB\xBF" + text + text},
+		{OUTPUT_ENCODING_ASCII, "`-- Pok\\u00E9mon(TM): Let's Go -> 2.0\n`-- Pok\\u00E9mon(TM): Let's Go -> 2.0\n"},
+	}
+	for _, test := range tests {
+		buf := bytes.Buffer{}
+		w := NewEncodingWriter(&buf, test.encoding)
+		//a multi byte character split across writes
+		split := strings.Index(text, "é") + 1
+		for _, part := range []string{text, text[:split], text[split:]} {
+			if n, err := w.Write([]byte(part)); err != nil || n != len(part) {
+				t.Fatalf("expected %v bytes to be written, got %v (%v)", len(part), n, err)
+			}
+		}
+		if buf.String() != test.expected {
+			t.Errorf("expected %q for %v, got %q", test.expected, test.encoding, buf.String())
+		}
+	}
+}
+
+func TestToASCII(t *testing.T) {
+	//kana are transliterated, whatever remains is escaped
+	for _, text := range []string{"ポケモン", "ぽけもん", "포탈 나이츠", "🎮"} {
+		ascii := ToASCII(text)
+		if ascii == "" {
+			t.Errorf("expected %v to be transliterated", text)
+		}
+		for i := 0; i < len(ascii); i++ {
+			if ascii[i] >= utf8.RuneSelf {
+				t.Errorf("expected ASCII only for %v, got %q", text, ascii)
+				break
+			}
+		}
+	}
+	if ascii := ToASCII("🎮"); ascii != "\\U0001F3AE" {
+		t.Errorf("expected an escaped character, got %v", ascii)
+	}
+}
@@ -0,0 +1,65 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"sort"
+)
+
+// PlayerCount returns the max number of players of the title according to the titles catalog (0 when unknown),
+// the NACP doesn't declare the number of players. there is no handheld mode helper for the same reason - neither
+// the NACP nor the catalog tell whether a title supports handheld play
+func PlayerCount(title *db.SwitchTitle) int {
+	if title == nil {
+		return 0
+	}
+	count, err := title.Attributes.NumberOfPlayers.Int64()
+	if err != nil {
+		return 0
+	}
+	return int(count)
+}
+
+// MultiplayerTitles returns the local titles supporting at least minPlayers players (according to the titles
+// catalog), sorted by title id. titles with an unknown player count are left out
+func MultiplayerTitles(localDB *db.LocalSwitchFilesDB, switchDB *db.SwitchTitlesDB, minPlayers int) []*db.SwitchGameFiles {
+	var result []*db.SwitchGameFiles
+	for key, switchFile := range localDB.TitlesMap {
+		if !switchFile.BaseExist {
+			continue
+		}
+		if count := PlayerCount(switchDB.TitlesMap[key]); count != 0 && count >= minPlayers {
+			result = append(result, switchFile)
+		}
+	}
+	return sortedByTitleId(result)
+}
+
+// LocalWirelessTitles returns the local titles supporting local wireless multiplayer (as declared in the NACP
+// of the base or the latest update, see switchfs.Nacp.SupportsLocalWireless), sorted by title id. titles without
+// decryptable control data are left out
+func LocalWirelessTitles(localDB *db.LocalSwitchFilesDB) []*db.SwitchGameFiles {
+	var result []*db.SwitchGameFiles
+	for _, switchFile := range localDB.TitlesMap {
+		if !switchFile.BaseExist {
+			continue
+		}
+		files := []db.SwitchFileInfo{switchFile.File}
+		if update, ok := switchFile.Updates[switchFile.LatestUpdate]; ok {
+			files = append(files, update)
+		}
+		for _, file := range files {
+			if file.Metadata != nil && file.Metadata.Ncap.SupportsLocalWireless(switchFile.TitleId()) {
+				result = append(result, switchFile)
+				break
+			}
+		}
+	}
+	return sortedByTitleId(result)
+}
+
+func sortedByTitleId(titles []*db.SwitchGameFiles) []*db.SwitchGameFiles {
+	sort.Slice(titles, func(i, j int) bool {
+		return titles[i].TitleId() < titles[j].TitleId()
+	})
+	return titles
+}
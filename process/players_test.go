@@ -0,0 +1,62 @@
+package process
+
+import (
+	"encoding/json"
+	"github.com/giwty/switch-library-manager/db"
+	"testing"
+)
+
+func TestMultiplayerTitles(t *testing.T) {
+	catalogTitle := func(players string) *db.SwitchTitle {
+		return &db.SwitchTitle{Attributes: db.TitleAttributes{NumberOfPlayers: json.Number(players)}}
+	}
+	switchDB := &db.SwitchTitlesDB{TitlesMap: map[string]*db.SwitchTitle{
+		"0100000000010000": catalogTitle("1"),
+		"0100000000020000": catalogTitle("2"),
+		"0100000000030000": catalogTitle("4"),
+		"0100000000040000": catalogTitle(""),
+		"0100000000050000": catalogTitle("8"),
+	}}
+	localDB := db.Group([]db.SwitchFileInfo{
+		testSwitchFile("one.nsp", "0100000000010000", 0),
+		testSwitchFile("two.nsp", "0100000000020000", 0),
+		testSwitchFile("four.nsp", "0100000000030000", 0),
+		//unknown player count
+		testSwitchFile("unknown.nsp", "0100000000040000", 0),
+		//not in the catalog
+		testSwitchFile("other.nsp", "0100000000060000", 0),
+		//without a base
+		testSwitchFile("update.nsp", "0100000000050800", 65536),
+	}, db.GroupOptions{})
+
+	tests := []struct {
+		minPlayers int
+		expected   []string
+	}{
+		{0, []string{"0100000000010000", "0100000000020000", "0100000000030000"}},
+		{1, []string{"0100000000010000", "0100000000020000", "0100000000030000"}},
+		{2, []string{"0100000000020000", "0100000000030000"}},
+		{3, []string{"0100000000030000"}},
+		{4, []string{"0100000000030000"}},
+		{5, nil},
+	}
+	for _, test := range tests {
+		titles := MultiplayerTitles(localDB, switchDB, test.minPlayers)
+		if len(titles) != len(test.expected) {
+			t.Errorf("expected %v titles with %v players, got %v", len(test.expected), test.minPlayers, len(titles))
+			continue
+		}
+		for i, title := range titles {
+			if title.TitleId() != test.expected[i] {
+				t.Errorf("expected %v with %v players, got %v", test.expected[i], test.minPlayers, title.TitleId())
+			}
+		}
+	}
+
+	if count := PlayerCount(nil); count != 0 {
+		t.Errorf("expected no players for an unknown title, got %v", count)
+	}
+	if count := PlayerCount(catalogTitle("many")); count != 0 {
+		t.Errorf("expected no players for an invalid player count, got %v", count)
+	}
+}
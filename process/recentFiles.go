@@ -0,0 +1,52 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"path/filepath"
+	"sort"
+)
+
+// RecentUpdates returns the update files in the library, newest (by file modification time) first.
+// a limit <= 0 returns all the update files
+func RecentUpdates(localDB *db.LocalSwitchFilesDB, limit int) []db.SwitchFileInfo {
+	var files []db.SwitchFileInfo
+	for _, switchFile := range localDB.TitlesMap {
+		for _, update := range switchFile.Updates {
+			files = append(files, update)
+		}
+	}
+	return newestFirst(files, limit)
+}
+
+// RecentAdditions returns the base, update and DLC files in the library, newest (by file modification time) first.
+// a limit <= 0 returns all the files
+func RecentAdditions(localDB *db.LocalSwitchFilesDB, limit int) []db.SwitchFileInfo {
+	var files []db.SwitchFileInfo
+	for _, switchFile := range localDB.TitlesMap {
+		if switchFile.BaseExist {
+			files = append(files, switchFile.File)
+		}
+		for _, update := range switchFile.Updates {
+			files = append(files, update)
+		}
+		for _, dlc := range switchFile.Dlc {
+			files = append(files, dlc)
+		}
+	}
+	return newestFirst(files, limit)
+}
+
+func newestFirst(files []db.SwitchFileInfo, limit int) []db.SwitchFileInfo {
+	sort.Slice(files, func(i, j int) bool {
+		ti, tj := files[i].ExtendedInfo.ModTime, files[j].ExtendedInfo.ModTime
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return filepath.Join(files[i].ExtendedInfo.BaseFolder, files[i].ExtendedInfo.FileName) <
+			filepath.Join(files[j].ExtendedInfo.BaseFolder, files[j].ExtendedInfo.FileName)
+	})
+	if limit > 0 && len(files) > limit {
+		files = files[:limit]
+	}
+	return files
+}
@@ -0,0 +1,103 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"path/filepath"
+	"sort"
+)
+
+type RedundantFile struct {
+	TitleId string `json:"title_id"`
+	Version int    `json:"version"`
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	//the multi-content file which contains the same content
+	ContainedIn string `json:"contained_in"`
+}
+
+// RedundantLooseFiles lists the single content files (base/update/DLC) whose content (same title id and version)
+// is also contained in a multi-content file, and can therefore be deleted. Returns the files sorted by path, together
+// with the total space that would be freed
+func RedundantLooseFiles(localDB *db.LocalSwitchFilesDB) ([]RedundantFile, int64) {
+	var entries []db.SwitchFileInfo
+	for _, switchFile := range localDB.TitlesMap {
+		if switchFile.BaseExist {
+			entries = append(entries, switchFile.File)
+		}
+		for _, update := range switchFile.Updates {
+			entries = append(entries, update)
+		}
+		for _, dlc := range switchFile.Dlc {
+			entries = append(entries, dlc)
+		}
+		entries = append(entries, switchFile.Duplicates...)
+	}
+
+	//count the contents per source file, to identify the multi-content files
+	contentsPerFile := map[string]map[string]struct{}{}
+	for _, entry := range entries {
+		if entry.Metadata == nil {
+			continue
+		}
+		source := sourceFileId(entry)
+		if _, ok := contentsPerFile[source]; !ok {
+			contentsPerFile[source] = map[string]struct{}{}
+		}
+		contentsPerFile[source][entry.Metadata.TitleId] = struct{}{}
+	}
+
+	type contentKey struct {
+		titleId string
+		version int
+	}
+	containedIn := map[contentKey]db.SwitchFileInfo{}
+	for _, entry := range entries {
+		if entry.Metadata == nil || len(contentsPerFile[sourceFileId(entry)]) < 2 {
+			continue
+		}
+		key := contentKey{entry.Metadata.TitleId, entry.Metadata.Version}
+		if existing, ok := containedIn[key]; !ok || sourceFileId(entry) < sourceFileId(existing) {
+			containedIn[key] = entry
+		}
+	}
+
+	var result []RedundantFile
+	var total int64
+	seen := map[string]struct{}{}
+	for _, entry := range entries {
+		source := sourceFileId(entry)
+		if entry.Metadata == nil || len(contentsPerFile[source]) > 1 {
+			continue
+		}
+		compilation, ok := containedIn[contentKey{entry.Metadata.TitleId, entry.Metadata.Version}]
+		if !ok {
+			continue
+		}
+		if _, ok := seen[source]; ok {
+			continue
+		}
+		seen[source] = struct{}{}
+		size := fileSize(entry)
+		total += size
+		result = append(result, RedundantFile{
+			TitleId:     entry.Metadata.TitleId,
+			Version:     entry.Metadata.Version,
+			Path:        localDB.FilePath(entry.ExtendedInfo),
+			Size:        size,
+			ContainedIn: localDB.FilePath(compilation.ExtendedInfo),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result, total
+}
+
+// sourceFileId returns the id of the file the entry was read from (older cached entries have no SourceFileId)
+func sourceFileId(file db.SwitchFileInfo) string {
+	if file.SourceFileId != "" {
+		return file.SourceFileId
+	}
+	return filepath.Join(file.ExtendedInfo.BaseFolder, file.ExtendedInfo.FileName)
+}
@@ -0,0 +1,52 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"testing"
+)
+
+func TestRedundantLooseFiles(t *testing.T) {
+	//a multi-content XCI stored in a zip archive, holding the base and the update
+	archiveEntry := func(titleId string, version int) db.SwitchFileInfo {
+		file := testSwitchFile("pack.zip", titleId, version)
+		file.ExtendedInfo.ArchiveEntry = "mario collection.xci"
+		file.ExtendedInfo.Size = 1000
+		file.Format = "xci"
+		file.SourceFileId = "/games/pack.zip|mario collection.xci"
+		return file
+	}
+	looseFile := func(fileName string, titleId string, version int, size int64) db.SwitchFileInfo {
+		file := testSwitchFile(fileName, titleId, version)
+		file.ExtendedInfo.Size = size
+		file.SourceFileId = "/games/" + fileName
+		return file
+	}
+	localDB := db.Group([]db.SwitchFileInfo{
+		archiveEntry("0100000000010000", 0),
+		archiveEntry("0100000000010800", 65536),
+		looseFile("mario.nsp", "0100000000010000", 0, 300),
+		looseFile("mario update.nsp", "0100000000010800", 65536, 20),
+		//another version of the update, and a DLC, which are not part of the archive
+		looseFile("mario update2.nsp", "0100000000010800", 131072, 30),
+		looseFile("mario dlc.nsp", "0100000000011001", 0, 10),
+	}, db.GroupOptions{})
+
+	redundant, total := RedundantLooseFiles(localDB)
+	expected := []RedundantFile{
+		{TitleId: "0100000000010800", Version: 65536, Path: "/games/mario update.nsp", Size: 20,
+			ContainedIn: "/games/pack.zip/mario collection.xci"},
+		{TitleId: "0100000000010000", Version: 0, Path: "/games/mario.nsp", Size: 300,
+			ContainedIn: "/games/pack.zip/mario collection.xci"},
+	}
+	if len(redundant) != len(expected) {
+		t.Fatalf("expected %v redundant files, got %+v", len(expected), redundant)
+	}
+	for i, file := range redundant {
+		if file != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], file)
+		}
+	}
+	if total != 320 {
+		t.Errorf("expected 320 bytes to be freed, got %v", total)
+	}
+}
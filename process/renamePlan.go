@@ -0,0 +1,196 @@
+package process
+
+import (
+	"errors"
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/settings"
+	"github.com/giwty/switch-library-manager/switchfs"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type RenamePlan struct {
+	From string
+	To   string
+	//the destination already exists (or is the destination of another file), the file is not moved
+	Conflict bool
+}
+
+// renameSet holds the moves of a single library file (a split file is moved with all its parts)
+type renameSet struct {
+	moves []RenamePlan
+	//the scanned file and its path once moved, used to move its cached metadata
+	file    db.ExtendedFileInfo
+	newPath string
+}
+
+// Organize renames the library files (base, updates and DLC) after the template, keeping their folder and extension.
+// the template uses the file_name_template tokens, TITLE_NAME, TITLE_ID, VERSION and TYPE are supported
+// (e.g. "{TITLE_NAME} [{TITLE_ID}][v{VERSION}]"). with dryRun the plan is returned without touching the files.
+// a file is never moved over an existing file (or the destination of another file), such moves are marked as conflicts.
+// split files are renamed with all their parts - or their folder, for parts stored in a folder named after the file.
+// files stored in archives are not renamed. the cached metadata of the moved files is moved along (when manager is set),
+// the library should be rescanned once the files were moved
+func Organize(manager *db.LocalSwitchDBManager, localDB *db.LocalSwitchFilesDB, template string, dryRun bool) ([]RenamePlan, error) {
+	if strings.TrimSpace(template) == "" {
+		return nil, errors.New("empty file name template")
+	}
+
+	keys := make([]string, 0, len(localDB.TitlesMap))
+	for key := range localDB.TitlesMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var splitSchemes switchfs.SplitSchemes
+	if manager != nil {
+		splitSchemes = manager.SplitSchemes()
+	}
+
+	var sets []renameSet
+	planned := map[string]bool{}
+	seen := map[string]bool{}
+	for _, key := range keys {
+		title := localDB.TitlesMap[key]
+		titleName := title.Name()
+
+		var files []db.SwitchFileInfo
+		var types []string
+		if title.BaseExist {
+			files = append(files, title.File)
+			types = append(types, "BASE")
+		}
+		versions := make([]int, 0, len(title.Updates))
+		for version := range title.Updates {
+			versions = append(versions, version)
+		}
+		sort.Ints(versions)
+		for _, version := range versions {
+			files = append(files, title.Updates[version])
+			types = append(types, "UPD")
+		}
+		dlcIds := make([]string, 0, len(title.Dlc))
+		for id := range title.Dlc {
+			dlcIds = append(dlcIds, id)
+		}
+		sort.Strings(dlcIds)
+		for _, id := range dlcIds {
+			files = append(files, title.Dlc[id])
+			types = append(types, "DLC")
+		}
+
+		for i, file := range files {
+			filePath := filepath.Join(file.ExtendedInfo.BaseFolder, file.ExtendedInfo.FileName)
+			//a multi-content file is named after its first content
+			if file.ExtendedInfo.ArchiveEntry != "" || file.Metadata == nil || seen[filePath] {
+				continue
+			}
+			seen[filePath] = true
+
+			templateData := map[string]string{
+				settings.TEMPLATE_TITLE_NAME: titleName,
+				settings.TEMPLATE_TITLE_ID:   file.Metadata.TitleId,
+				settings.TEMPLATE_VERSION:    strconv.Itoa(file.Metadata.Version),
+				settings.TEMPLATE_TYPE:       types[i],
+			}
+			newName := applyTemplate(templateData, false, template)
+			if newName == "" {
+				continue
+			}
+			set, err := planRename(splitSchemes, file.ExtendedInfo, newName)
+			if err != nil {
+				zap.S().Warnf("[file:%v] unable to rename the file [reason: %v]", file.ExtendedInfo.FileName, err)
+				continue
+			}
+			if len(set.moves) == 0 {
+				continue
+			}
+			conflict := false
+			for _, move := range set.moves {
+				if planned[move.To] {
+					conflict = true
+				}
+				//the destination differs by case only on a case insensitive file system
+				if _, err := os.Stat(move.To); err == nil && !strings.EqualFold(move.From, move.To) {
+					conflict = true
+				}
+			}
+			for j := range set.moves {
+				set.moves[j].Conflict = conflict
+				planned[set.moves[j].To] = true
+			}
+			sets = append(sets, set)
+		}
+	}
+
+	var plan []RenamePlan
+	for _, set := range sets {
+		plan = append(plan, set.moves...)
+		if dryRun || set.moves[0].Conflict {
+			continue
+		}
+		for _, move := range set.moves {
+			if err := moveFile(move.From, move.To); err != nil {
+				return plan, err
+			}
+		}
+		if manager != nil {
+			if err := manager.MoveCacheEntry(set.file, set.newPath); err != nil {
+				zap.S().Warnf("failed to move the cached metadata of %v - %v", set.newPath, err)
+			}
+		}
+	}
+	return plan, nil
+}
+
+// planRename returns the moves renaming the file to newName (without extension), a file whose name
+// already matches yields no moves. split files are told by the given naming schemes
+func planRename(splitSchemes switchfs.SplitSchemes, file db.ExtendedFileInfo, newName string) (renameSet, error) {
+	filePath := filepath.Join(file.BaseFolder, file.FileName)
+	set := renameSet{file: file}
+	if _, ok := splitSchemes.ParsePart(file.FileName); !ok || !splitSchemes.IsSplitPart(filePath) {
+		set.newPath = filepath.Join(file.BaseFolder, newName+filepath.Ext(file.FileName))
+		if set.newPath != filePath {
+			set.moves = append(set.moves, RenamePlan{From: filePath, To: set.newPath})
+		}
+		return set, nil
+	}
+
+	//parts stored in a folder named after the file (e.g. "Game.nsp/00", "Game/01"), the folder is renamed
+	folder := filepath.Clean(file.BaseFolder)
+	namedFolder := switchfs.ClassifyFileName(folder) != switchfs.FileType_Unsupported
+	if part, _ := splitSchemes.ParsePart(file.FileName); part.InFolder() || namedFolder {
+		ext := ""
+		if namedFolder {
+			ext = filepath.Ext(folder)
+		}
+		newFolder := filepath.Join(filepath.Dir(folder), newName+ext)
+		set.newPath = filepath.Join(newFolder, file.FileName)
+		if newFolder != folder {
+			set.moves = append(set.moves, RenamePlan{From: folder, To: newFolder})
+		}
+		return set, nil
+	}
+
+	//parts named after the file (e.g. "Game.nsp.00", "Game.xci.part1"), all the parts are renamed
+	parts, _, err := splitSchemes.FileParts(filePath)
+	if err != nil {
+		return set, err
+	}
+	for _, part := range parts {
+		split, _ := splitSchemes.ParsePart(filepath.Base(part))
+		suffix := filepath.Base(part)[len(split.Base):]
+		to := filepath.Join(filepath.Dir(part), newName+filepath.Ext(split.Base)+suffix)
+		if part == filePath {
+			set.newPath = to
+		}
+		if to != part {
+			set.moves = append(set.moves, RenamePlan{From: part, To: to})
+		}
+	}
+	return set, nil
+}
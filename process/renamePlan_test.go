@@ -0,0 +1,100 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/switchfs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOrganize(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-organize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+
+	fileNames := []string{"mario.nsp", "mario update.nsp", "zelda.nsp.00", "zelda.nsp.01",
+		"Zelda [0100000000020800][v65536].nsp", "other.nsp"}
+	for _, fileName := range fileNames {
+		if err := ioutil.WriteFile(filepath.Join(folder, fileName), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	switchFile := func(fileName string, titleId string, version int, name string) db.SwitchFileInfo {
+		return db.SwitchFileInfo{
+			ExtendedInfo: db.ExtendedFileInfo{FileName: fileName, BaseFolder: folder + string(os.PathSeparator), Size: 4},
+			Metadata:     &switchfs.ContentMetaAttributes{TitleId: titleId, Version: version},
+			TitleName:    name,
+		}
+	}
+	localDB := db.Group([]db.SwitchFileInfo{
+		switchFile("mario.nsp", "0100000000010000", 0, "Super Mario Odyssey"),
+		switchFile("mario update.nsp", "0100000000010800", 65536, ""),
+		switchFile("zelda.nsp.00", "0100000000020000", 0, "Zelda"),
+		//already named after the template
+		switchFile("Zelda [0100000000020800][v65536].nsp", "0100000000020800", 65536, ""),
+		//renamed to an existing file name
+		switchFile("other.nsp", "0100000000030000", 0, "mario"),
+	}, db.GroupOptions{})
+
+	template := "{TITLE_NAME} [{TITLE_ID}][v{VERSION}]"
+	expected := map[string]string{
+		"mario.nsp":        "Super Mario Odyssey [0100000000010000][v0].nsp",
+		"mario update.nsp": "Super Mario Odyssey [0100000000010800][v65536].nsp",
+		"zelda.nsp.00":     "Zelda [0100000000020000][v0].nsp.00",
+		"zelda.nsp.01":     "Zelda [0100000000020000][v0].nsp.01",
+		"other.nsp":        "mario [0100000000030000][v0].nsp",
+	}
+
+	plan, err := Organize(nil, localDB, template, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != len(expected) {
+		t.Fatalf("expected %v moves, got %+v", len(expected), plan)
+	}
+	for _, move := range plan {
+		if move.From == filepath.Join(folder, "other.nsp") || move.Conflict {
+			continue
+		}
+		if filepath.Join(folder, expected[filepath.Base(move.From)]) != move.To {
+			t.Errorf("unexpected move %v -> %v", move.From, move.To)
+		}
+	}
+	for _, fileName := range fileNames {
+		if _, err := os.Stat(filepath.Join(folder, fileName)); err != nil {
+			t.Errorf("expected a dry run to leave %v untouched", fileName)
+		}
+	}
+
+	//the destination exists
+	if err := ioutil.WriteFile(filepath.Join(folder, expected["other.nsp"]), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	plan, err = Organize(nil, localDB, template, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, move := range plan {
+		conflict := filepath.Base(move.From) == "other.nsp"
+		if move.Conflict != conflict {
+			t.Errorf("unexpected conflict flag for %v", move.From)
+		}
+	}
+	for from, to := range expected {
+		_, fromErr := os.Stat(filepath.Join(folder, from))
+		_, toErr := os.Stat(filepath.Join(folder, to))
+		if from == "other.nsp" {
+			if fromErr != nil {
+				t.Errorf("expected the conflicting file not to be moved")
+			}
+			continue
+		}
+		if fromErr == nil || toErr != nil {
+			t.Errorf("expected %v to be moved to %v", from, to)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/switchfs"
+)
+
+// MaxRequiredFirmware returns the firmware version (e.g. "10.0.0") required to run the whole library - the max
+// required system version of the bases and their latest updates, and the titles requiring it (sorted by title id).
+// an empty version is returned when the required firmware is unknown (e.g. metadata parsed from the file names)
+func MaxRequiredFirmware(localDB *db.LocalSwitchFilesDB) (string, []*db.SwitchGameFiles) {
+	maxVersion := 0
+	var titles []*db.SwitchGameFiles
+	for _, switchFile := range localDB.TitlesMap {
+		required := requiredSystemVersion(switchFile)
+		if required == 0 || required < maxVersion {
+			continue
+		}
+		if required > maxVersion {
+			maxVersion = required
+			titles = nil
+		}
+		titles = append(titles, switchFile)
+	}
+	return switchfs.FirmwareVersion(maxVersion), sortedByTitleId(titles)
+}
+
+// requiredSystemVersion returns the system version required by the title (base or latest update, whichever is higher)
+func requiredSystemVersion(switchFile *db.SwitchGameFiles) int {
+	required := 0
+	if switchFile.BaseExist && switchFile.File.Metadata != nil {
+		required = switchFile.File.Metadata.RequiredSystemVersion
+	}
+	if update, ok := switchFile.Updates[switchFile.LatestUpdate]; ok && update.Metadata != nil &&
+		update.Metadata.RequiredSystemVersion > required {
+		required = update.Metadata.RequiredSystemVersion
+	}
+	return required
+}
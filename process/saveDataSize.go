@@ -0,0 +1,44 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/switchfs"
+	"sort"
+)
+
+type TitleSaveDataSize struct {
+	TitleId      string                `json:"title_id"`
+	SaveDataSize switchfs.SaveDataSize `json:"save_data_size"`
+	Total        int64                 `json:"total"`
+}
+
+// SaveDataReport returns the save data space (in bytes, for a single user) declared by each title, sorted by the
+// total size (descending), together with the space the saves of the whole library would take.
+// The sizes of the latest update are used when available (updates may grow the save data), titles without
+// control data (NACP) count as zero.
+func SaveDataReport(localDB *db.LocalSwitchFilesDB) ([]TitleSaveDataSize, int64) {
+	var result []TitleSaveDataSize
+	total := int64(0)
+
+	for _, switchFile := range localDB.TitlesMap {
+		titleSize := TitleSaveDataSize{TitleId: switchFile.TitleId()}
+		if switchFile.BaseExist && switchFile.File.Metadata != nil {
+			titleSize.SaveDataSize = switchFile.File.Metadata.SaveDataSize
+		}
+		if update, ok := switchFile.Updates[switchFile.LatestUpdate]; ok && update.Metadata != nil &&
+			update.Metadata.SaveDataSize.Total() != 0 {
+			titleSize.SaveDataSize = update.Metadata.SaveDataSize
+		}
+		titleSize.Total = titleSize.SaveDataSize.Total()
+		total += titleSize.Total
+		result = append(result, titleSize)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Total != result[j].Total {
+			return result[i].Total > result[j].Total
+		}
+		return result[i].TitleId < result[j].TitleId
+	})
+	return result, total
+}
@@ -0,0 +1,173 @@
+package process
+
+import (
+	"encoding/json"
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/switchfs"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type SidecarDlc struct {
+	TitleId string `json:"title_id"`
+	Name    string `json:"name,omitempty"`
+	Version int    `json:"version"`
+}
+
+// TitleSidecar is the metadata written for each title by WriteSidecars
+type TitleSidecar struct {
+	Name             string       `json:"name"`
+	TitleId          string       `json:"title_id"`
+	Version          int          `json:"version"`
+	DisplayVersion   string       `json:"display_version,omitempty"`
+	Dlc              []SidecarDlc `json:"dlc"`
+	RequiredFirmware string       `json:"required_firmware,omitempty"`
+	Languages        []string     `json:"languages,omitempty"`
+	Players          int          `json:"players,omitempty"`
+	LocalWireless    bool         `json:"local_wireless"`
+}
+
+// SidecarFormat encodes the title metadata written by WriteSidecars
+type SidecarFormat interface {
+	//file extension of the sidecar files (e.g. ".json")
+	Extension() string
+	Encode(w io.Writer, sidecar TitleSidecar) error
+}
+
+type jsonSidecarFormat struct{}
+
+func (jsonSidecarFormat) Extension() string {
+	return ".json"
+}
+
+func (jsonSidecarFormat) Encode(w io.Writer, sidecar TitleSidecar) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sidecar)
+}
+
+var (
+	SidecarFormatJSON SidecarFormat = jsonSidecarFormat{}
+)
+
+// WriteSidecars writes a metadata file per title into dir, named after the title (e.g. "Game [0100000000010000].json").
+// the titles DB is optional, it provides the names of the titles, DLC and the number of players.
+// titles without a base are skipped unless includeMissingBase is set, existing sidecar files are left untouched (they may
+// have been edited by the user). it returns the number of files written
+func WriteSidecars(localDB *db.LocalSwitchFilesDB, switchDB *db.SwitchTitlesDB, dir string, format SidecarFormat,
+	includeMissingBase bool) (int, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return 0, err
+	}
+	keys := make([]string, 0, len(localDB.TitlesMap))
+	for key := range localDB.TitlesMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	written := 0
+	for _, key := range keys {
+		switchFile := localDB.TitlesMap[key]
+		if !switchFile.BaseExist && !includeMissingBase {
+			continue
+		}
+		var switchTitle *db.SwitchTitle
+		if switchDB != nil {
+			switchTitle = switchDB.TitlesMap[key]
+		}
+		sidecar := titleSidecar(switchFile, switchTitle)
+		fileName := folderIllegalCharsRegex.ReplaceAllString(sidecar.Name+" ["+strings.ToUpper(sidecar.TitleId)+"]", "")
+		err := writeSidecar(filepath.Join(dir, strings.TrimSpace(fileName)+format.Extension()), format, sidecar)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return written, err
+		}
+		written++
+	}
+	return written, nil
+}
+
+func writeSidecar(path string, format SidecarFormat, sidecar TitleSidecar) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	err = format.Encode(file, sidecar)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func titleSidecar(switchFile *db.SwitchGameFiles, switchTitle *db.SwitchTitle) TitleSidecar {
+	sidecar := TitleSidecar{TitleId: switchFile.TitleId(), Version: switchFile.LatestUpdate, Dlc: []SidecarDlc{},
+		Players: PlayerCount(switchTitle)}
+	if switchFile.BaseExist {
+		sidecar.Name = getTitleName(switchTitle, switchFile)
+	} else if switchTitle != nil && switchTitle.Attributes.Name != "" {
+		sidecar.Name = switchTitle.Attributes.Name
+	}
+
+	var metadata []*switchfs.ContentMetaAttributes
+	if switchFile.BaseExist && switchFile.File.Metadata != nil {
+		metadata = append(metadata, switchFile.File.Metadata)
+	}
+	if update, ok := switchFile.Updates[switchFile.LatestUpdate]; ok {
+		if update.Metadata != nil {
+			metadata = append(metadata, update.Metadata)
+		}
+		if sidecar.Name == "" {
+			sidecar.Name = strings.TrimSpace(db.ParseTitleNameFromFileName(update.ExtendedInfo.FileName))
+		}
+	}
+	if update, ok := switchFile.Updates[switchFile.LatestUpdate]; ok && update.Metadata != nil {
+		sidecar.DisplayVersion = update.Metadata.DisplayVersion()
+	} else if switchFile.BaseExist && switchFile.File.Metadata != nil {
+		sidecar.DisplayVersion = switchFile.File.Metadata.DisplayVersion()
+	}
+	sidecar.RequiredFirmware = switchfs.FirmwareVersion(requiredSystemVersion(switchFile))
+
+	languages := map[string]struct{}{}
+	for _, m := range metadata {
+		if m.Ncap == nil {
+			continue
+		}
+		if m.Ncap.SupportsLocalWireless(switchFile.TitleId()) {
+			sidecar.LocalWireless = true
+		}
+		for i := 0; i < 16; i++ {
+			if m.Ncap.SupportedLanguageFlag&(1<<uint(i)) != 0 {
+				languages[switchfs.Language(i).String()] = struct{}{}
+			}
+		}
+	}
+	for language := range languages {
+		sidecar.Languages = append(sidecar.Languages, language)
+	}
+	sort.Strings(sidecar.Languages)
+
+	dlcIds := make([]string, 0, len(switchFile.Dlc))
+	for id := range switchFile.Dlc {
+		dlcIds = append(dlcIds, id)
+	}
+	sort.Strings(dlcIds)
+	for _, id := range dlcIds {
+		dlc := SidecarDlc{TitleId: id, Version: switchFile.Dlc[id].Metadata.Version}
+		if switchTitle != nil {
+			dlc.Name = switchTitle.Dlc[id].Name
+		}
+		sidecar.Dlc = append(sidecar.Dlc, dlc)
+		if sidecar.Name == "" {
+			sidecar.Name = strings.TrimSpace(db.ParseTitleNameFromFileName(switchFile.Dlc[id].ExtendedInfo.FileName))
+		}
+	}
+	if sidecar.Name == "" {
+		sidecar.Name = sidecar.TitleId
+	}
+	return sidecar
+}
@@ -0,0 +1,85 @@
+package process
+
+import (
+	"encoding/json"
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/switchfs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteSidecars(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slm-sidecars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	update := testSwitchFile("mario update.nsp", "0100000000010800", 131072)
+	update.Metadata.Ncap = &switchfs.Nacp{DisplayVersion: "1.2.0", SupportedLanguageFlag: 1<<0 | 1<<2,
+		LocalCommunicationIds: []uint64{0x0100000000010000, 0x0100000000012000}}
+	localDB := db.Group([]db.SwitchFileInfo{
+		testSwitchFile("mario.nsp", "0100000000010000", 0),
+		update,
+		testSwitchFile("mario dlc.nsp", "0100000000011001", 65536),
+		testSwitchFile("zelda.nsp", "0100000000020000", 0),
+		//without a base
+		testSwitchFile("Kirby [0100000000030800][v65536].nsp", "0100000000030800", 65536),
+	}, db.GroupOptions{})
+	switchDB := &db.SwitchTitlesDB{TitlesMap: map[string]*db.SwitchTitle{
+		"0100000000010000": {Attributes: db.TitleAttributes{Name: "Super Mario Odyssey", NumberOfPlayers: "2"},
+			Dlc: map[string]db.TitleAttributes{"0100000000011001": {Name: "Costume Pack"}}},
+		"0100000000020000": {Attributes: db.TitleAttributes{Name: "Zelda: Breath of the Wild"}},
+	}}
+
+	//edited by the user
+	existing := filepath.Join(dir, "Zelda Breath of the Wild [0100000000020000].json")
+	if err := ioutil.WriteFile(existing, []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := WriteSidecars(localDB, switchDB, dir, SidecarFormatJSON, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != 1 {
+		t.Errorf("expected 1 sidecar to be written, got %v", written)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "Super Mario Odyssey [0100000000010000].json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sidecar := TitleSidecar{}
+	if err := json.Unmarshal(data, &sidecar); err != nil {
+		t.Fatal(err)
+	}
+	if sidecar.Name != "Super Mario Odyssey" || sidecar.TitleId != "0100000000010000" || sidecar.Version != 131072 ||
+		sidecar.DisplayVersion != "1.2.0" || sidecar.Players != 2 || !sidecar.LocalWireless ||
+		len(sidecar.Languages) != 2 || sidecar.Languages[0] != "AmericanEnglish" || sidecar.Languages[1] != "Japanese" ||
+		len(sidecar.Dlc) != 1 || sidecar.Dlc[0] != (SidecarDlc{TitleId: "0100000000011001", Name: "Costume Pack", Version: 65536}) {
+		t.Errorf("unexpected sidecar %+v", sidecar)
+	}
+	if data, err := ioutil.ReadFile(existing); err != nil || string(data) != "edited" {
+		t.Errorf("expected the existing sidecar to be kept, got %v (%v)", string(data), err)
+	}
+
+	written, err = WriteSidecars(localDB, switchDB, dir, SidecarFormatJSON, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != 1 {
+		t.Errorf("expected only the title without a base to be written, got %v", written)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Kirby [0100000000030000].json")); err != nil {
+		t.Errorf("expected the sidecar of the title without a base, got %v", err)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Errorf("expected 3 sidecars, got %v", len(files))
+	}
+}
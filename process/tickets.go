@@ -0,0 +1,62 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/switchfs"
+	"sort"
+)
+
+type TicketReport struct {
+	//contents of NSP/NSZ files holding their ticket
+	HasTicket []db.SwitchFileInfo `json:"has_ticket"`
+	//contents of NSP/NSZ files without a ticket ("ticketless"), some install methods can't install them
+	Ticketless []db.SwitchFileInfo `json:"ticketless"`
+	//XCI/XCZ files (gamecard images hold no tickets) and files whose metadata was not read from the file itself
+	Unknown []db.SwitchFileInfo `json:"unknown"`
+}
+
+// TicketsReport partitions the grouped contents (bases, updates and DLC) of the library by the presence of their
+// ticket, each list is sorted by the file path. Split and compressed NSP (NSZ) files are read like plain NSP files.
+func TicketsReport(localDB *db.LocalSwitchFilesDB) TicketReport {
+	report := TicketReport{}
+	for _, switchFile := range localDB.TitlesMap {
+		var files []db.SwitchFileInfo
+		if switchFile.BaseExist {
+			files = append(files, switchFile.File)
+		}
+		for _, update := range switchFile.Updates {
+			files = append(files, update)
+		}
+		for _, dlc := range switchFile.Dlc {
+			files = append(files, dlc)
+		}
+		for _, file := range files {
+			if file.Metadata == nil {
+				report.Unknown = append(report.Unknown, file)
+				continue
+			}
+			switch file.Metadata.Ticket {
+			case switchfs.TicketStatus_Present:
+				report.HasTicket = append(report.HasTicket, file)
+			case switchfs.TicketStatus_Missing:
+				report.Ticketless = append(report.Ticketless, file)
+			default:
+				report.Unknown = append(report.Unknown, file)
+			}
+		}
+	}
+	for _, files := range [][]db.SwitchFileInfo{report.HasTicket, report.Ticketless, report.Unknown} {
+		sortByFilePath(localDB, files)
+	}
+	return report
+}
+
+func sortByFilePath(localDB *db.LocalSwitchFilesDB, files []db.SwitchFileInfo) {
+	sort.Slice(files, func(i, j int) bool {
+		pathI, pathJ := localDB.FilePath(files[i].ExtendedInfo), localDB.FilePath(files[j].ExtendedInfo)
+		if pathI != pathJ {
+			return pathI < pathJ
+		}
+		return files[i].Metadata != nil && files[j].Metadata != nil && files[i].Metadata.TitleId < files[j].Metadata.TitleId
+	})
+}
@@ -0,0 +1,123 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"sort"
+	"strings"
+)
+
+// TitleCompletenessScore describes how complete the local collection of a title is.
+// a dimension without catalog data is left nil and excluded from the overall Completeness
+type TitleCompletenessScore struct {
+	TitleId        string `json:"title_id"`
+	BasePresent    bool   `json:"base_present"`
+	OnLatestUpdate *bool  `json:"on_latest_update,omitempty"`
+	//percent of the known DLC present locally
+	DlcPresent *float64 `json:"dlc_present,omitempty"`
+	//percent of the local DLC (with a known latest version) which are on the latest version
+	DlcOnLatest *float64 `json:"dlc_on_latest,omitempty"`
+	//average of the known dimensions (0-100)
+	Completeness float64 `json:"completeness"`
+}
+
+// TitleCompleteness scores the completeness of each local title (base present, on latest update,
+// all DLC present and all DLC on latest version), sorted by title id.
+// versions maps a base title id to the latest update version, dlcCatalog maps a base title id to its DLC
+// title ids, and dlcVersions maps a DLC title id to its latest version (see CompletenessCatalogs,
+// db.LoadVersionsCatalog and db.LoadDLCCatalog)
+func TitleCompleteness(localDB *db.LocalSwitchFilesDB, versions map[string]int,
+	dlcCatalog map[string][]string, dlcVersions map[string]int) []TitleCompletenessScore {
+	var result []TitleCompletenessScore
+	for _, switchFile := range localDB.TitlesMap {
+		titleId := switchFile.TitleId()
+		if titleId == "" {
+			continue
+		}
+		score := TitleCompletenessScore{TitleId: titleId, BasePresent: switchFile.BaseExist}
+		total := 0.0
+		dimensions := 1
+		if switchFile.BaseExist {
+			total += 100
+		}
+
+		if latest, ok := versions[titleId]; ok {
+			onLatest := switchFile.LatestUpdate >= latest
+			score.OnLatestUpdate = &onLatest
+			dimensions++
+			if onLatest {
+				total += 100
+			}
+		}
+
+		if dlcIds, ok := dlcCatalog[titleId]; ok && len(dlcIds) != 0 {
+			present := 0
+			for _, dlcId := range dlcIds {
+				if _, ok := switchFile.Dlc[strings.ToLower(dlcId)]; ok {
+					present++
+				}
+			}
+			dlcPresent := percent(present, len(dlcIds))
+			score.DlcPresent = &dlcPresent
+			dimensions++
+			total += dlcPresent
+		}
+
+		known := 0
+		onLatest := 0
+		for dlcId, dlc := range switchFile.Dlc {
+			latest, ok := dlcVersions[dlcId]
+			if !ok || dlc.Metadata == nil {
+				continue
+			}
+			known++
+			if dlc.Metadata.Version >= latest {
+				onLatest++
+			}
+		}
+		if known != 0 {
+			dlcOnLatest := percent(onLatest, known)
+			score.DlcOnLatest = &dlcOnLatest
+			dimensions++
+			total += dlcOnLatest
+		}
+
+		score.Completeness = total / float64(dimensions)
+		result = append(result, score)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].TitleId < result[j].TitleId
+	})
+	return result
+}
+
+// CompletenessCatalogs builds the catalogs used by TitleCompleteness from the titles DB
+func CompletenessCatalogs(switchDB *db.SwitchTitlesDB) (map[string]int, map[string][]string, map[string]int) {
+	versions := map[string]int{}
+	dlcCatalog := map[string][]string{}
+	dlcVersions := map[string]int{}
+	for _, switchTitle := range switchDB.TitlesMap {
+		if switchTitle.Attributes.Id == "" {
+			continue
+		}
+		titleId := strings.ToLower(switchTitle.Attributes.Id)
+		latest := 0
+		for version := range switchTitle.Updates {
+			if version > latest {
+				latest = version
+			}
+		}
+		versions[titleId] = latest
+		for dlcId, dlc := range switchTitle.Dlc {
+			dlcCatalog[titleId] = append(dlcCatalog[titleId], dlcId)
+			if version, err := dlc.Version.Int64(); err == nil {
+				dlcVersions[dlcId] = int(version)
+			}
+		}
+		sort.Strings(dlcCatalog[titleId])
+	}
+	return versions, dlcCatalog, dlcVersions
+}
+
+func percent(count int, total int) float64 {
+	return float64(count) * 100 / float64(total)
+}
@@ -0,0 +1,71 @@
+package process
+
+import (
+	"github.com/giwty/switch-library-manager/db"
+	"testing"
+)
+
+func TestTitleCompleteness(t *testing.T) {
+	versions := map[string]int{"0100000000010000": 131072, "0100000000020000": 131072, "0100000000030000": 0}
+	dlcCatalog := map[string][]string{
+		"0100000000010000": {"0100000000011001"},
+		"0100000000030000": {"0100000000031001", "0100000000031002"},
+	}
+	dlcVersions := map[string]int{"0100000000011001": 65536, "0100000000031001": 0}
+	localDB := db.Group([]db.SwitchFileInfo{
+		//complete
+		testSwitchFile("mario.nsp", "0100000000010000", 0),
+		testSwitchFile("mario update.nsp", "0100000000010800", 131072),
+		testSwitchFile("mario dlc.nsp", "0100000000011001", 65536),
+		//missing the latest update
+		testSwitchFile("zelda.nsp", "0100000000020000", 0),
+		testSwitchFile("zelda update.nsp", "0100000000020800", 65536),
+		//missing one of the DLC
+		testSwitchFile("kirby.nsp", "0100000000030000", 0),
+		testSwitchFile("kirby dlc.nsp", "0100000000031001", 0),
+		//missing the base, not in the catalogs
+		testSwitchFile("metroid update.nsp", "0100000000040800", 65536),
+	}, db.GroupOptions{})
+
+	scores := TitleCompleteness(localDB, versions, dlcCatalog, dlcVersions)
+	expected := []struct {
+		titleId        string
+		basePresent    bool
+		onLatestUpdate *bool
+		dlcPresent     *float64
+		dlcOnLatest    *float64
+		completeness   float64
+	}{
+		{"0100000000010000", true, boolPtr(true), floatPtr(100), floatPtr(100), 100},
+		{"0100000000020000", true, boolPtr(false), nil, nil, 50},
+		{"0100000000030000", true, boolPtr(true), floatPtr(50), floatPtr(100), 87.5},
+		{"0100000000040000", false, nil, nil, nil, 0},
+	}
+	if len(scores) != len(expected) {
+		t.Fatalf("expected %v scores, got %+v", len(expected), scores)
+	}
+	for i, score := range scores {
+		e := expected[i]
+		if score.TitleId != e.titleId || score.BasePresent != e.basePresent || score.Completeness != e.completeness ||
+			!equalBool(score.OnLatestUpdate, e.onLatestUpdate) || !equalFloat(score.DlcPresent, e.dlcPresent) ||
+			!equalFloat(score.DlcOnLatest, e.dlcOnLatest) {
+			t.Errorf("unexpected score %+v for %v", score, e.titleId)
+		}
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func equalBool(a *bool, b *bool) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
+
+func equalFloat(a *float64, b *float64) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
@@ -0,0 +1,104 @@
+package process
+
+import (
+	"fmt"
+	"github.com/giwty/switch-library-manager/db"
+	"io"
+	"sort"
+	"strings"
+)
+
+type treeNode struct {
+	name    string
+	titleId string
+	lines   []string
+}
+
+// WriteTree writes the local library as a text outline - each title with its updates and DLC beneath it
+func WriteTree(localDB *db.LocalSwitchFilesDB, w io.Writer) error {
+	var nodes []treeNode
+	numUpdates := 0
+	numDlc := 0
+
+	for _, switchFile := range localDB.TitlesMap {
+		node := treeNode{titleId: switchFile.TitleId()}
+		if switchFile.BaseExist {
+			node.name = getTitleName(nil, switchFile)
+			node.lines = append(node.lines, fmt.Sprintf("Base%v - %v", versionText(switchFile.File), switchFile.File.ExtendedInfo.FileName))
+		}
+
+		versions := make([]int, 0, len(switchFile.Updates))
+		for version := range switchFile.Updates {
+			versions = append(versions, version)
+		}
+		sort.Ints(versions)
+		for _, version := range versions {
+			update := switchFile.Updates[version]
+			line := fmt.Sprintf("Update v%v - %v", version, update.ExtendedInfo.FileName)
+			if update.Metadata != nil && update.Metadata.Ncap != nil && update.Metadata.Ncap.DisplayVersion != "" {
+				line = fmt.Sprintf("Update v%v → app version %v - %v", version, update.Metadata.DisplayVersion(), update.ExtendedInfo.FileName)
+			}
+			if version != switchFile.LatestUpdate {
+				line += " (old)"
+			}
+			node.lines = append(node.lines, line)
+			numUpdates++
+			if node.name == "" {
+				node.name = update.Name()
+			}
+		}
+
+		dlcIds := make([]string, 0, len(switchFile.Dlc))
+		for id := range switchFile.Dlc {
+			dlcIds = append(dlcIds, id)
+		}
+		sort.Strings(dlcIds)
+		for _, id := range dlcIds {
+			dlc := switchFile.Dlc[id]
+			node.lines = append(node.lines, fmt.Sprintf("DLC %v%v - %v", id, versionText(dlc), dlc.ExtendedInfo.FileName))
+			numDlc++
+			if node.name == "" {
+				node.name = dlc.Name()
+			}
+		}
+
+		node.name = strings.TrimSpace(node.name)
+		if !switchFile.BaseExist {
+			node.name += " (base missing)"
+		}
+		nodes = append(nodes, node)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		ni, nj := strings.ToLower(nodes[i].name), strings.ToLower(nodes[j].name)
+		if ni != nj {
+			return ni < nj
+		}
+		return nodes[i].titleId < nodes[j].titleId
+	})
+
+	for _, node := range nodes {
+		if _, err := fmt.Fprintf(w, "%v [%v]\n", node.name, strings.ToUpper(node.titleId)); err != nil {
+			return err
+		}
+		for i, line := range node.lines {
+			prefix := "├── "
+			if i == len(node.lines)-1 {
+				prefix = "└── "
+			}
+			if _, err := fmt.Fprintf(w, "%v%v\n", prefix, line); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "\n%v titles, %v updates, %v DLC\n", len(nodes), numUpdates, numDlc)
+	return err
+}
+
+// versionText returns the " v<version>" label of the file, empty when its metadata is missing
+func versionText(file db.SwitchFileInfo) string {
+	if file.Metadata == nil {
+		return ""
+	}
+	return fmt.Sprintf(" v%v", file.Metadata.Version)
+}
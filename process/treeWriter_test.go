@@ -0,0 +1,52 @@
+package process
+
+import (
+	"bytes"
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/switchfs"
+	"testing"
+)
+
+func TestWriteTree(t *testing.T) {
+	withName := func(file db.SwitchFileInfo, name string) db.SwitchFileInfo {
+		file.TitleName = name
+		return file
+	}
+	update := testSwitchFile("mario update2.nsp", "0100000000010800", 131072)
+	update.Metadata.Ncap = &switchfs.Nacp{DisplayVersion: "1.2.0"}
+	localDB := db.Group([]db.SwitchFileInfo{
+		withName(testSwitchFile("zelda.nsp", "0100000000020000", 0), "Zelda"),
+		withName(testSwitchFile("mario.nsp", "0100000000010000", 0), "Super Mario Odyssey"),
+		testSwitchFile("mario update1.nsp", "0100000000010800", 65536),
+		update,
+		testSwitchFile("mario dlc2.nsp", "0100000000011002", 0),
+		testSwitchFile("mario dlc1.nsp", "0100000000011001", 0),
+		testSwitchFile("orphan dlc.nsp", "0100000000031001", 0),
+	}, db.GroupOptions{})
+	//without metadata
+	unknown := testSwitchFile("zelda dlc.nsp", "0100000000021001", 0)
+	unknown.Metadata = nil
+	localDB.TitlesMap["0100000000020000"].Dlc["0100000000021001"] = unknown
+
+	buf := bytes.Buffer{}
+	if err := WriteTree(localDB, &buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `orphan dlc.nsp (base missing) [0100000000030000]
+└── DLC 0100000000031001 v0 - orphan dlc.nsp
+Super Mario Odyssey [0100000000010000]
+├── Base v0 - mario.nsp
+├── Update v65536 - mario update1.nsp (old)
+├── Update v131072 → app version 1.2.0 - mario update2.nsp
+├── DLC 0100000000011001 v0 - mario dlc1.nsp
+└── DLC 0100000000011002 v0 - mario dlc2.nsp
+Zelda [0100000000020000]
+├── Base v0 - zelda.nsp
+└── DLC 0100000000021001 - zelda dlc.nsp
+
+3 titles, 2 updates, 4 DLC
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected tree:\n%v\nexpected:\n%v", buf.String(), expected)
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/process"
+	"github.com/giwty/switch-library-manager/settings"
+	"go.uber.org/zap"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type APITitle struct {
+	TitleId      string `json:"title_id"`
+	Name         string `json:"name"`
+	BaseExist    bool   `json:"base_exist"`
+	Path         string `json:"path,omitempty"`
+	LatestUpdate int    `json:"latest_update"`
+	//the application version after applying the latest update (e.g. "1.2.0")
+	LatestVersion string   `json:"latest_version,omitempty"`
+	Updates       []int    `json:"updates"`
+	Dlc           []string `json:"dlc"`
+}
+
+type APISkippedFile struct {
+	Path       string `json:"path"`
+	ReasonCode int    `json:"reason_code"`
+	Reason     string `json:"reason"`
+}
+
+type APIStats struct {
+	db.LibraryStats
+	NumTitles      int   `json:"num_titles"`
+	NumSkipped     int   `json:"num_skipped"`
+	NumKnownTitles int   `json:"num_known_titles"`
+	InstallSize    int64 `json:"install_size"`
+	SaveDataSize   int64 `json:"save_data_size"`
+	CacheHits      int   `json:"cache_hits"`
+	CacheMisses    int   `json:"cache_misses"`
+	//files identified by their name only (e.g. scanned without keys)
+	NumUnverified int `json:"num_unverified"`
+	//set when prod.keys is present but invalid
+	KeysError string `json:"keys_error,omitempty"`
+	//the paths which could not be read during the scan, the library may be incomplete
+	ScanErrors []string `json:"scan_errors,omitempty"`
+}
+
+// Server exposes the library reports as a read-only JSON HTTP API (plus a scan trigger)
+type Server struct {
+	sync.Mutex
+	baseFolder     string
+	sugarLogger    *zap.SugaredLogger
+	localDbManager *db.LocalSwitchDBManager
+	switchDB       *db.SwitchTitlesDB
+	localDB        *db.LocalSwitchFilesDB
+	scanning       bool
+}
+
+func CreateServer(baseFolder string, sugarLogger *zap.SugaredLogger) *Server {
+	return &Server{baseFolder: baseFolder, sugarLogger: sugarLogger}
+}
+
+// Start loads the titles DB and the local library, and serves the API on the given address (blocking)
+func (s *Server) Start(address string) error {
+	switchDB, err := s.buildSwitchDb()
+	if err != nil {
+		return err
+	}
+	s.switchDB = switchDB
+
+	settings.InitSwitchKeys(s.baseFolder)
+	localDbManager, err := db.NewLocalSwitchDBManager(s.baseFolder)
+	if err != nil {
+		return err
+	}
+	defer localDbManager.Close()
+	s.localDbManager = localDbManager
+
+	localDB, err := s.buildLocalDB(nil, false)
+	if err != nil {
+		return err
+	}
+	s.localDB = localDB
+
+	server := &http.Server{
+		Addr:              address,
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+		//no write timeout, the scan progress is streamed for the whole scan
+	}
+	s.sugarLogger.Infof("[HTTP API listening on %v]", address)
+	fmt.Printf("HTTP API listening on %v\n", address)
+	return server.ListenAndServe()
+}
+
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/titles", s.handleTitles)
+	mux.HandleFunc("/api/missing-updates", s.handleMissingUpdates)
+	mux.HandleFunc("/api/missing-dlc", s.handleMissingDLC)
+	mux.HandleFunc("/api/stats", s.handleStats)
+	mux.HandleFunc("/api/search", s.handleSearch)
+	mux.HandleFunc("/api/skipped", s.handleSkipped)
+	mux.HandleFunc("/api/scan", s.handleScan)
+	return mux
+}
+
+func (s *Server) buildSwitchDb() (*db.SwitchTitlesDB, error) {
+	settingsObj := settings.ReadSettings(s.baseFolder)
+	filename := filepath.Join(s.baseFolder, settings.TITLE_JSON_FILENAME)
+	titleFile, titlesEtag, err := db.LoadAndUpdateFile(settings.TITLES_JSON_URL, filename, settingsObj.TitlesEtag)
+	if err != nil {
+		return nil, errors.New("failed to download switch titles [reason:" + err.Error() + "]")
+	}
+	settingsObj.TitlesEtag = titlesEtag
+
+	filename = filepath.Join(s.baseFolder, settings.VERSIONS_JSON_FILENAME)
+	versionsFile, versionsEtag, err := db.LoadAndUpdateFile(settings.VERSIONS_JSON_URL, filename, settingsObj.VersionsEtag)
+	if err != nil {
+		return nil, errors.New("failed to download switch updates [reason:" + err.Error() + "]")
+	}
+	settingsObj.VersionsEtag = versionsEtag
+
+	settings.SaveSettings(settingsObj, s.baseFolder)
+
+	return db.CreateSwitchTitleDB(titleFile, versionsFile)
+}
+
+func (s *Server) buildLocalDB(progress db.ProgressUpdater, ignoreCache bool) (*db.LocalSwitchFilesDB, error) {
+	settingsObj := settings.ReadSettings(s.baseFolder)
+	scanFolders := append(settingsObj.ScanFolders, settingsObj.Folder)
+	localDB, err := s.localDbManager.CreateLocalSwitchFilesDB(scanFolders, progress, settingsObj.ScanRecursively, ignoreCache)
+	if err != nil {
+		return nil, err
+	}
+	localDB.RelativePaths = settingsObj.RelativePaths
+	return localDB, nil
+}
+
+func (s *Server) library() (*db.LocalSwitchFilesDB, *db.SwitchTitlesDB) {
+	s.Lock()
+	defer s.Unlock()
+	return s.localDB, s.switchDB
+}
+
+func (s *Server) titles(localDB *db.LocalSwitchFilesDB, switchDB *db.SwitchTitlesDB) []APITitle {
+	result := make([]APITitle, 0, len(localDB.TitlesMap))
+	for idPrefix, switchFile := range localDB.TitlesMap {
+		title := APITitle{TitleId: switchFile.TitleId(), BaseExist: switchFile.BaseExist,
+			LatestUpdate: switchFile.LatestUpdate, Updates: []int{}, Dlc: []string{}}
+		if switchTitle, ok := switchDB.TitlesMap[idPrefix]; ok && switchTitle.Attributes.Name != "" {
+			title.Name = switchTitle.Attributes.Name
+		}
+		if switchFile.BaseExist {
+			title.Path = localDB.FilePath(switchFile.File.ExtendedInfo)
+			if title.Name == "" {
+				title.Name = switchFile.File.Name()
+			}
+		}
+		for version := range switchFile.Updates {
+			title.Updates = append(title.Updates, version)
+		}
+		if update, ok := switchFile.Updates[switchFile.LatestUpdate]; ok && update.Metadata != nil {
+			title.LatestVersion = update.Metadata.DisplayVersion()
+		} else if switchFile.BaseExist && switchFile.File.Metadata != nil {
+			title.LatestVersion = switchFile.File.Metadata.DisplayVersion()
+		}
+		sort.Ints(title.Updates)
+		for dlcId := range switchFile.Dlc {
+			title.Dlc = append(title.Dlc, dlcId)
+		}
+		sort.Strings(title.Dlc)
+		result = append(result, title)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].TitleId < result[j].TitleId
+	})
+	return result
+}
+
+func (s *Server) handleTitles(w http.ResponseWriter, r *http.Request) {
+	localDB, switchDB := s.library()
+	writeJSON(w, s.titles(localDB, switchDB))
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+	if query == "" {
+		http.Error(w, "missing 'q' query parameter", http.StatusBadRequest)
+		return
+	}
+	localDB, switchDB := s.library()
+	result := []APITitle{}
+	for _, title := range s.titles(localDB, switchDB) {
+		if strings.Contains(strings.ToLower(title.Name), query) || strings.Contains(title.TitleId, query) {
+			result = append(result, title)
+		}
+	}
+	writeJSON(w, result)
+}
+
+func (s *Server) handleMissingUpdates(w http.ResponseWriter, r *http.Request) {
+	localDB, switchDB := s.library()
+	writeJSON(w, process.ScanForMissingUpdates(localDB.TitlesMap, switchDB.TitlesMap))
+}
+
+func (s *Server) handleMissingDLC(w http.ResponseWriter, r *http.Request) {
+	localDB, switchDB := s.library()
+	ignoreIds := map[string]struct{}{}
+	for _, id := range settings.ReadSettings(s.baseFolder).IgnoreDLCTitleIds {
+		ignoreIds[strings.ToLower(id)] = struct{}{}
+	}
+	writeJSON(w, process.ScanForMissingDLC(localDB.TitlesMap, switchDB.TitlesMap, ignoreIds))
+}
+
+func (s *Server) handleSkipped(w http.ResponseWriter, r *http.Request) {
+	localDB, _ := s.library()
+	result := make([]APISkippedFile, 0, len(localDB.Skipped))
+	for file, skipped := range localDB.Skipped {
+		result = append(result, APISkippedFile{Path: localDB.FilePath(file),
+			ReasonCode: skipped.ReasonCode, Reason: skipped.ReasonText})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	writeJSON(w, result)
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	localDB, switchDB := s.library()
+	stats := APIStats{LibraryStats: localDB.Stats(), NumTitles: len(localDB.TitlesMap),
+		NumSkipped: len(localDB.Skipped), NumKnownTitles: len(switchDB.TitlesMap),
+		CacheHits: localDB.CacheHits, CacheMisses: localDB.CacheMisses, NumUnverified: len(localDB.UnverifiedFiles())}
+	_, stats.InstallSize = process.InstallSizeReport(localDB)
+	_, stats.SaveDataSize = process.SaveDataReport(localDB)
+	if localDB.KeysError != nil {
+		stats.KeysError = localDB.KeysError.Error()
+	}
+	for _, scanErr := range localDB.ScanErrors {
+		stats.ScanErrors = append(stats.ScanErrors, scanErr.Error())
+	}
+	writeJSON(w, stats)
+}
+
+// handleScan rescans the library, the progress is streamed as server-sent events
+func (s *Server) handleScan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "use POST to start a scan", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	s.Lock()
+	if s.scanning {
+		s.Unlock()
+		http.Error(w, "a scan is already running", http.StatusConflict)
+		return
+	}
+	s.scanning = true
+	s.Unlock()
+	defer func() {
+		s.Lock()
+		s.scanning = false
+		s.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	progress := &sseProgress{w: w, flusher: flusher}
+	localDB, err := s.buildLocalDB(progress, true)
+	if err != nil {
+		s.sugarLogger.Errorf("scan failed - %v", err)
+		progress.send("error", err.Error())
+		return
+	}
+	s.Lock()
+	s.localDB = localDB
+	s.Unlock()
+	progress.send("done", ProgressUpdate{Curr: localDB.NumFiles, Total: localDB.NumFiles, Message: "Complete"})
+}
+
+// sseProgress streams the scan progress as server-sent events
+type sseProgress struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func (p *sseProgress) UpdateProgress(curr int, total int, message string) {
+	p.send("progress", ProgressUpdate{Curr: curr, Total: total, Message: message})
+}
+
+func (p *sseProgress) send(event string, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		zap.S().Error(err)
+		return
+	}
+	fmt.Fprintf(p.w, "event: %v\ndata: %s\n\n", event, data)
+	p.flusher.Flush()
+}
+
+func writeJSON(w http.ResponseWriter, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		zap.S().Error(err)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/giwty/switch-library-manager/db"
+	"github.com/giwty/switch-library-manager/settings"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testServer(t *testing.T, baseFolder string, gamesFolder string) *Server {
+	settingsObj := settings.ReadSettings(baseFolder)
+	settingsObj.Folder = gamesFolder
+	settings.SaveSettings(settingsObj, baseFolder)
+
+	manager, err := db.NewLocalSwitchDBManager(baseFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := CreateServer(baseFolder, zap.NewNop().Sugar())
+	server.localDbManager = manager
+	server.switchDB = &db.SwitchTitlesDB{TitlesMap: map[string]*db.SwitchTitle{
+		"0100000000010000": {Attributes: db.TitleAttributes{Name: "Super Mario Odyssey"}},
+	}}
+	localDB, err := server.buildLocalDB(nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.localDB = localDB
+	return server
+}
+
+func TestServer(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	gamesFolder, err := ioutil.TempDir("", "slm-games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gamesFolder)
+	for _, fileName := range []string{"Super Mario Odyssey [0100000000010000][v0].nsp",
+		"Super Mario Odyssey [0100000000010800][v65536].nsp", "Super Mario Odyssey [0100000000011001][v0].nsp", "notes.nsp"} {
+		if err := ioutil.WriteFile(filepath.Join(gamesFolder, fileName), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := testServer(t, baseFolder, gamesFolder)
+	defer server.localDbManager.Close()
+	httpServer := httptest.NewServer(server.handler())
+	defer httpServer.Close()
+
+	getJSON := func(path string, payload interface{}) {
+		res, err := http.Get(httpServer.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status %v for %v", res.Status, path)
+		}
+		if err := json.NewDecoder(res.Body).Decode(payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var titles []APITitle
+	getJSON("/api/titles", &titles)
+	if len(titles) != 1 || titles[0].TitleId != "0100000000010000" || titles[0].Name != "Super Mario Odyssey" ||
+		!titles[0].BaseExist || len(titles[0].Updates) != 1 || titles[0].Updates[0] != 65536 ||
+		len(titles[0].Dlc) != 1 || titles[0].Dlc[0] != "0100000000011001" {
+		t.Errorf("unexpected titles %+v", titles)
+	}
+
+	var skipped []APISkippedFile
+	getJSON("/api/skipped", &skipped)
+	if len(skipped) != 1 || skipped[0].Path != filepath.Join(gamesFolder, "notes.nsp") || skipped[0].Reason == "" {
+		t.Errorf("unexpected skipped files %+v", skipped)
+	}
+
+	var stats APIStats
+	getJSON("/api/stats", &stats)
+	if stats.NumFiles != 4 || stats.NumTitles != 1 || stats.BaseGames != 1 || stats.Updates != 1 || stats.Dlc != 1 ||
+		stats.NumSkipped != 1 || stats.NumKnownTitles != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	//a new file is found by the scan
+	if err := ioutil.WriteFile(filepath.Join(gamesFolder, "Zelda [0100000000020000][v0].nsp"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.Get(httpServer.URL + "/api/scan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected a GET scan to be rejected, got %v", res.Status)
+	}
+	res, err = http.Post(httpServer.URL+"/api/scan", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "text/event-stream" ||
+		!strings.Contains(string(events), "event: done\n") {
+		t.Errorf("expected the scan to complete, got %v %v", res.Status, string(events))
+	}
+	getJSON("/api/titles", &titles)
+	if len(titles) != 2 {
+		t.Errorf("expected the rescanned library to be served, got %+v", titles)
+	}
+}
+
+func TestServerConcurrentScan(t *testing.T) {
+	server := CreateServer("", zap.NewNop().Sugar())
+	//a scan is running
+	server.scanning = true
+	httpServer := httptest.NewServer(server.handler())
+	defer httpServer.Close()
+
+	res, err := http.Post(httpServer.URL+"/api/scan", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusConflict {
+		t.Errorf("expected a concurrent scan to be rejected, got %v", res.Status)
+	}
+}
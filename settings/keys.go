@@ -1,13 +1,18 @@
 package settings
 
 import (
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"github.com/magiconair/properties"
+	"os"
 	"path/filepath"
 )
 
 var (
 	keysInstance *switchKeys
+	//set when a keys file was found but could not be used
+	keysError error
 )
 
 type switchKeys struct {
@@ -18,28 +23,46 @@ func (k *switchKeys) GetKey(keyName string) string {
 	return k.keys[keyName]
 }
 
+// SwitchKeys returns the keys loaded by InitSwitchKeys (nil when no usable keys file was found).
+// the error is set when a keys file is present but invalid, in which case files are only identified by their name
 func SwitchKeys() (*switchKeys, error) {
-	return keysInstance, nil
+	return keysInstance, keysError
 }
 
 func InitSwitchKeys(baseFolder string) (*switchKeys, error) {
+	keysInstance = nil
+	keysError = nil
 
 	// init from a file
-	path := filepath.Join(baseFolder, "prod.keys")
-	p, err := properties.LoadFile(path, properties.UTF8)
-	if err != nil {
-		path = "${HOME}/.switch/prod.keys"
-		p, err = properties.LoadFile(path, properties.UTF8)
-	}
 	settings := ReadSettings(baseFolder)
-	if err != nil {
-		path := settings.Prodkeys
-		if path != "" {
-			p, err = properties.LoadFile(filepath.Join(path, "prod.keys"), properties.UTF8)
+	candidates := []string{filepath.Join(baseFolder, "prod.keys"), "${HOME}/.switch/prod.keys"}
+	if settings.Prodkeys != "" {
+		candidates = append(candidates, filepath.Join(settings.Prodkeys, "prod.keys"))
+	}
+	var p *properties.Properties
+	var err error
+	path := ""
+	for _, candidate := range candidates {
+		if _, statErr := os.Stat(os.ExpandEnv(candidate)); statErr != nil {
+			continue
+		}
+		p, err = properties.LoadFile(candidate, properties.UTF8)
+		if err == nil {
+			err = validateKeys(p)
+		}
+		if err != nil {
+			keysError = fmt.Errorf("prod.keys is present but invalid: %v", err)
+			continue
 		}
+		path = candidate
+		keysError = nil
+		break
 	}
-	if err != nil {
-		return nil, errors.New("Error trying to read prod.keys [reason:" + err.Error() + "]")
+	if path == "" {
+		if keysError != nil {
+			return nil, keysError
+		}
+		return nil, errors.New("Error trying to read prod.keys [reason: file not found]")
 	}
 	settings.Prodkeys = path
 	SaveSettings(settings, baseFolder)
@@ -51,3 +74,16 @@ func InitSwitchKeys(baseFolder string) (*switchKeys, error) {
 
 	return keysInstance, nil
 }
+
+// validateKeys checks the keys required to read the files are present and well formed, so that keys problems are
+// reported once instead of failing the read of every file
+func validateKeys(p *properties.Properties) error {
+	headerKey, ok := p.Get("header_key")
+	if !ok {
+		return errors.New("header_key is missing")
+	}
+	if key, err := hex.DecodeString(headerKey); err != nil || len(key) != 32 {
+		return errors.New("header_key must be 32 bytes (64 hex digits)")
+	}
+	return nil
+}
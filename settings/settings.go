@@ -9,6 +9,9 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
 )
 
 var (
@@ -25,6 +28,16 @@ const (
 	VERSIONS_JSON_URL = "https://tinfoil.media/repo/db/versions.json"
 	//VERSIONS_JSON_URL = "https://raw.githubusercontent.com/blawar/titledb/master/versions.json"
 	SLM_VERSION_URL = "https://raw.githubusercontent.com/giwty/switch-library-manager/master/slm.json"
+
+	DEFAULT_IO_CONCURRENCY = 4
+	DEFAULT_MAX_SCAN_DEPTH = 64
+	DEFAULT_MAX_SCAN_FILES = 1000000
+	DEFAULT_PROGRESS_MS    = 100
+	DEFAULT_READ_TIMEOUT_S = 120
+	SCAN_DEPTH_QUICK       = "quick"
+	SCAN_DEPTH_FULL        = "full"
+	DUPLICATE_PREFER_PATH  = "path"
+	DUPLICATE_PREFER_SIZE  = "size"
 )
 
 const (
@@ -47,6 +60,153 @@ type OrganizeOptions struct {
 	FileNameTemplate     string `json:"file_name_template"`
 }
 
+type ScanOptions struct {
+	//max number of concurrent disk reads (0 = default)
+	IOConcurrency int `json:"io_concurrency"`
+	//max number of files decrypted/parsed concurrently (0 = number of CPUs)
+	CPUConcurrency int `json:"cpu_concurrency"`
+	//max folder depth below a scan folder, the scan is aborted when exceeded (0 = default)
+	MaxDepth int `json:"max_depth"`
+	//sub-folders deeper than this below a scan folder are not scanned (0 = the scan folder only, default -1 = unlimited),
+	//only applies to recursive scans
+	MaxFolderDepth *int `json:"max_folder_depth"`
+	//max number of files found in all the scan folders, the scan is aborted when exceeded (0 = default)
+	MaxFiles int `json:"max_files"`
+	//max time in seconds to read the metadata of a single file, the file is skipped when exceeded (e.g. a stalled
+	//network share) - 0 = default, -1 = no limit
+	ReadTimeoutSeconds int `json:"read_timeout_seconds"`
+	//number of times a file read failing with an I/O error (or timing out) is retried, with an increasing delay
+	ReadRetries int `json:"read_retries"`
+	//read again the files whose metadata failed to be read by a previous scan, instead of skipping them right away
+	RecheckFailedFiles bool `json:"recheck_failed_files"`
+	//custom pattern used to parse the version from file names, must contain a (?P<version>...) group (empty = default)
+	VersionPattern string `json:"version_pattern"`
+	//custom pattern used to parse the title id from file names, must contain a (?P<titleId>...) group (empty = default)
+	TitleIdPattern string `json:"title_id_pattern"`
+	//"quick" - read the metadata from the file names and .cnmt.xml files only (no keys needed, low confidence),
+	//"full" - decrypt the files (default)
+	ScanDepth string `json:"scan_depth"`
+	//keep superseded updates grouped with their title without reporting them as skipped (old update files
+	//are then not deleted by delete_old_update_files)
+	KeepOldUpdates bool `json:"keep_old_updates"`
+	//additional split file naming schemes, checked before the default ones (.00, .part1, _split00)
+	SplitPatterns []SplitPattern `json:"split_patterns"`
+	//min interval between progress updates in milliseconds, intermediate updates are dropped (0 = default, -1 = report every update)
+	ProgressIntervalMs int `json:"progress_interval_ms"`
+	//walk symlinked folders and read symlinked files through to their target (symlink loops are detected)
+	FollowSymlinks bool `json:"follow_symlinks"`
+	//hash the whole files when verifying the library integrity, instead of their first and last MB (slow)
+	FullHash bool `json:"full_hash"`
+	//list the NSP/NSZ files stored inside .zip archives, they are read in place (not extracted)
+	ScanZipArchives bool `json:"scan_zip_archives"`
+	//files (and folders) left out of the scan
+	ScanFilter ScanFilter `json:"scan_filter"`
+	//titles and paths never tracked (e.g. homebrew or demos kept next to the library)
+	ScanExclusions ScanExclusions `json:"scan_exclusions"`
+	//of several files holding the same content (title id and version), the file kept - "path" the smallest
+	//path (default), "size" the largest file. the other files are reported as duplicates
+	DuplicatePreference string `json:"duplicate_preference"`
+}
+
+type ScanFilter struct {
+	//skip the files whose name starts with "." (e.g. macOS resource forks), default true
+	SkipDotFiles *bool `json:"skip_dot_files"`
+	//skip the files and folders with the hidden or system attribute (Windows only)
+	SkipWindowsHidden bool `json:"skip_windows_hidden"`
+	//skip the files and folders whose name matches one of the patterns (e.g. "Thumbs.db", "*.ini"), case insensitive
+	ExcludeGlobs []string `json:"exclude_globs"`
+}
+
+func (f ScanFilter) GetSkipDotFiles() bool {
+	return f.SkipDotFiles == nil || *f.SkipDotFiles
+}
+
+type ScanExclusions struct {
+	//the contents whose title id starts with one of the prefixes are dropped (e.g. "0100ffff"), case insensitive
+	TitleIdPrefixes []string `json:"title_id_prefixes"`
+	//the files and folders whose full path matches one of the patterns are not read (e.g. "/games/homebrew/*"),
+	//case insensitive, "/" matches the path separator on all systems
+	PathGlobs []string `json:"path_globs"`
+	//report the files dropped by the title id prefixes as skipped ("excluded"), instead of leaving them out silently
+	ReportExcluded bool `json:"report_excluded"`
+}
+
+type SplitPattern struct {
+	//must contain the (?P<base>...) and (?P<part>...) groups
+	Pattern string `json:"pattern"`
+	//number of the first part (e.g. 0 for ".00", 1 for ".part1")
+	FirstPart int `json:"first_part"`
+}
+
+func (o ScanOptions) GetIOConcurrency() int {
+	if o.IOConcurrency <= 0 {
+		return DEFAULT_IO_CONCURRENCY
+	}
+	return o.IOConcurrency
+}
+
+func (o ScanOptions) GetCPUConcurrency() int {
+	if o.CPUConcurrency <= 0 {
+		return runtime.NumCPU()
+	}
+	return o.CPUConcurrency
+}
+
+func (o ScanOptions) GetMaxDepth() int {
+	if o.MaxDepth <= 0 {
+		return DEFAULT_MAX_SCAN_DEPTH
+	}
+	return o.MaxDepth
+}
+
+func (o ScanOptions) GetMaxFiles() int {
+	if o.MaxFiles <= 0 {
+		return DEFAULT_MAX_SCAN_FILES
+	}
+	return o.MaxFiles
+}
+
+func (o ScanOptions) GetMaxFolderDepth() int {
+	if o.MaxFolderDepth == nil || *o.MaxFolderDepth < 0 {
+		return -1
+	}
+	return *o.MaxFolderDepth
+}
+
+func (o ScanOptions) GetReadTimeout() time.Duration {
+	if o.ReadTimeoutSeconds < 0 {
+		return 0
+	}
+	if o.ReadTimeoutSeconds == 0 {
+		return DEFAULT_READ_TIMEOUT_S * time.Second
+	}
+	return time.Duration(o.ReadTimeoutSeconds) * time.Second
+}
+
+func (o ScanOptions) GetScanDepth() string {
+	if strings.ToLower(o.ScanDepth) == SCAN_DEPTH_QUICK {
+		return SCAN_DEPTH_QUICK
+	}
+	return SCAN_DEPTH_FULL
+}
+
+func (o ScanOptions) GetDuplicatePreference() string {
+	if strings.ToLower(o.DuplicatePreference) == DUPLICATE_PREFER_SIZE {
+		return DUPLICATE_PREFER_SIZE
+	}
+	return DUPLICATE_PREFER_PATH
+}
+
+func (o ScanOptions) GetProgressInterval() time.Duration {
+	if o.ProgressIntervalMs < 0 {
+		return 0
+	}
+	if o.ProgressIntervalMs == 0 {
+		return DEFAULT_PROGRESS_MS * time.Millisecond
+	}
+	return time.Duration(o.ProgressIntervalMs) * time.Millisecond
+}
+
 type AppSettings struct {
 	VersionsEtag           string          `json:"versions_etag"`
 	TitlesEtag             string          `json:"titles_etag"`
@@ -61,6 +221,10 @@ type AppSettings struct {
 	ScanRecursively        bool            `json:"scan_recursively"`
 	GuiPagingSize          int             `json:"gui_page_size"`
 	IgnoreDLCTitleIds      []string        `json:"ignore_dlc_title_ids"`
+	ScanOptions            ScanOptions     `json:"scan_options"`
+	RelativePaths          bool            `json:"relative_paths"`
+	//encoding of the text reports (tree/tables) - "utf8" (default), "utf8-bom" or "ascii"
+	OutputEncoding string `json:"output_encoding"`
 }
 
 func ReadSettingsAsJSON(baseFolder string) string {
@@ -93,6 +257,7 @@ func ReadSettings(baseFolder string) *AppSettings {
 }
 
 func saveDefaultSettings(baseFolder string) *AppSettings {
+	skipDotFiles := true
 	settingsInstance = &AppSettings{
 		TitlesEtag:             "W/\"a5b02845cf6bd61:0\"",
 		VersionsEtag:           "W/\"2ef50d1cb6bd61:0\"",
@@ -115,6 +280,19 @@ func saveDefaultSettings(baseFolder string) *AppSettings {
 			SwitchSafeFileNames:  true,
 			DeleteOldUpdateFiles: false,
 		},
+		ScanOptions: ScanOptions{
+			IOConcurrency:      DEFAULT_IO_CONCURRENCY,
+			CPUConcurrency:     0,
+			MaxDepth:           DEFAULT_MAX_SCAN_DEPTH,
+			MaxFiles:           DEFAULT_MAX_SCAN_FILES,
+			ReadTimeoutSeconds: DEFAULT_READ_TIMEOUT_S,
+			ProgressIntervalMs: DEFAULT_PROGRESS_MS,
+			ScanFilter: ScanFilter{
+				SkipDotFiles: &skipDotFiles,
+				ExcludeGlobs: []string{"Thumbs.db", "desktop.ini"},
+			},
+			ScanExclusions: ScanExclusions{TitleIdPrefixes: []string{}, PathGlobs: []string{}},
+		},
 	}
 	return SaveSettings(settingsInstance, baseFolder)
 }
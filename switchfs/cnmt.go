@@ -5,9 +5,22 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"strconv"
 	"strings"
 )
 
+const (
+	MetadataSource_CnmtXml  = "cnmt.xml"
+	MetadataSource_FileName = "file_name"
+)
+
+const (
+	TicketStatus_Present = "present"
+	TicketStatus_Missing = "missing"
+)
+
 const (
 	ContentMetaType_SystemProgram        = 1
 	ContentMetaType_SystemData           = 2
@@ -35,6 +48,27 @@ type ContentMetaAttributes struct {
 	Type     string `json:"type"`
 	Contents map[string]Content
 	Ncap     *Nacp
+	//the base application the content belongs to (same as TitleId for a base)
+	ApplicationId string `json:"application_id"`
+	//only set for DLC - the minimal application (base/update) version required by the DLC
+	RequiredApplicationVersion int `json:"required_application_version"`
+	//only set for base/update - the minimal system (firmware) version required, see FirmwareVersion
+	RequiredSystemVersion int `json:"required_system_version"`
+	//the space (in bytes) required to install the content - sum of the (decompressed) NCA sizes listed
+	//in the CNMT, this is not the file size (NSZ/XCZ are compressed) and does not include save data.
+	//zero when unknown (e.g. metadata parsed from the file name)
+	InstallSize int64 `json:"install_size"`
+	//only set for base/update - the save data sizes declared in the NACP (bytes),
+	//zero when the control data isn't available
+	SaveDataSize SaveDataSize `json:"save_data_size"`
+	//additional content meta entries with the same title id found in the same file (malformed or
+	//specially packed files), kept for diagnosis instead of being silently dropped
+	Conflicts []*ContentMetaAttributes `json:"conflicts,omitempty"`
+	//only set for NSP/NSZ - whether the file holds the title ticket (TicketStatus_Present / TicketStatus_Missing),
+	//empty when unknown (XCI, metadata parsed from the file name)
+	Ticket string `json:"ticket,omitempty"`
+	//where the metadata was read from, empty when read from the file itself
+	Source string `json:"source,omitempty"`
 }
 
 type ContentMeta struct {
@@ -52,10 +86,43 @@ type ContentMeta struct {
 		Hash          string `xml:"Hash"`
 		KeyGeneration string `xml:"KeyGeneration"`
 	} `xml:"Content"`
-	Digest                string `xml:"Digest"`
-	KeyGenerationMin      string `xml:"KeyGenerationMin"`
-	RequiredSystemVersion string `xml:"RequiredSystemVersion"`
-	OriginalId            string `xml:"OriginalId"`
+	Digest                     string `xml:"Digest"`
+	KeyGenerationMin           string `xml:"KeyGenerationMin"`
+	RequiredSystemVersion      string `xml:"RequiredSystemVersion"`
+	RequiredApplicationVersion int    `xml:"RequiredApplicationVersion"`
+	OriginalId                 string `xml:"OriginalId"`
+	ApplicationId              string `xml:"ApplicationId"`
+}
+
+// DisplayVersion returns the application version (e.g. "1.2.0") the content brings the application to,
+// taken from the NACP (base/update), falling back to the numeric version (e.g. "v262144") when unknown
+func (c *ContentMetaAttributes) DisplayVersion() string {
+	if c.Ncap != nil && c.Ncap.DisplayVersion != "" {
+		return c.Ncap.DisplayVersion
+	}
+	return "v" + strconv.Itoa(c.Version)
+}
+
+// FirmwareVersion formats a system version (e.g. RequiredSystemVersion) as a firmware version (e.g. "9.1.0"),
+// an empty string is returned for 0
+func FirmwareVersion(systemVersion int) string {
+	if systemVersion <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d.%d", (systemVersion>>26)&0x3F, (systemVersion>>20)&0x3F, (systemVersion>>16)&0xF)
+}
+
+// addContentMeta adds the cnmt to the content map, a cnmt colliding with an existing entry is recorded
+// on the existing entry (see Conflicts) rather than overriding it
+func addContentMeta(contentMap map[string]*ContentMetaAttributes, cnmt *ContentMetaAttributes) {
+	existing, ok := contentMap[cnmt.TitleId]
+	if !ok {
+		contentMap[cnmt.TitleId] = cnmt
+		return
+	}
+	zap.S().Warnf("found multiple content meta entries for title id %v (type %v, versions %v / %v)",
+		cnmt.TitleId, cnmt.Type, existing.Version, cnmt.Version)
+	existing.Conflicts = append(existing.Conflicts, cnmt)
 }
 
 func readBinaryCnmt(pfs0 *PFS0, data []byte) (*ContentMetaAttributes, error) {
@@ -70,9 +137,15 @@ func readBinaryCnmt(pfs0 *PFS0, data []byte) (*ContentMetaAttributes, error) {
 	contentEntryCount := binary.LittleEndian.Uint16(cnmt[0x10:0x12])
 	//metaEntryCount := binary.LittleEndian.Uint16(cnmt[0x12:0x14])
 	contents := map[string]Content{}
+	installSize := int64(0)
 	for i := uint16(0); i < contentEntryCount; i++ {
 		position := 0x20 /*size of cnmt header*/ + tableOffset + (i * uint16(0x38))
 		ncaId := cnmt[position+0x20 : position+0x20+0x10]
+		//content size is a 6 bytes LE value
+		sizeBytes := make([]byte, 8)
+		copy(sizeBytes, cnmt[position+0x30:position+0x36])
+		contentSize := int64(binary.LittleEndian.Uint64(sizeBytes))
+		installSize += contentSize
 		//fmt.Println(fmt.Sprintf("0%x", ncaId))
 		contentType := ""
 		switch cnmt[position+0x36 : position+0x36+1][0] {
@@ -91,19 +164,37 @@ func readBinaryCnmt(pfs0 *PFS0, data []byte) (*ContentMetaAttributes, error) {
 		case 6:
 			contentType = "DeltaFragment"
 		}
-		contents[contentType] = Content{ID: fmt.Sprintf("%x", ncaId)}
+		contents[contentType] = Content{ID: fmt.Sprintf("%x", ncaId), Size: strconv.FormatInt(contentSize, 10)}
 	}
-	metaType := ""
+	result := &ContentMetaAttributes{Contents: contents, Version: int(version), TitleId: fmt.Sprintf("0%x", titleId),
+		InstallSize: installSize}
 	switch cnmt[0xC:0xD][0] {
 	case ContentMetaType_Application:
-		metaType = "BASE"
+		result.Type = "BASE"
+		result.ApplicationId = result.TitleId
+		//extended header - PatchId (0x8), RequiredSystemVersion (0x4)
+		if tableOffset >= 0xC {
+			result.RequiredSystemVersion = int(binary.LittleEndian.Uint32(cnmt[0x20+0x8 : 0x20+0xC]))
+		}
 	case ContentMetaType_AddOnContent:
-		metaType = "DLC"
+		result.Type = "DLC"
+		//extended header - ApplicationId (0x8), RequiredApplicationVersion (0x4)
+		if tableOffset >= 0xC {
+			result.ApplicationId = fmt.Sprintf("0%x", binary.LittleEndian.Uint64(cnmt[0x20:0x20+0x8]))
+			result.RequiredApplicationVersion = int(binary.LittleEndian.Uint32(cnmt[0x20+0x8 : 0x20+0xC]))
+		}
 	case ContentMetaType_Patch:
-		metaType = "UPD"
+		result.Type = "UPD"
+		//extended header - ApplicationId (0x8), RequiredSystemVersion (0x4)
+		if tableOffset >= 0x8 {
+			result.ApplicationId = fmt.Sprintf("0%x", binary.LittleEndian.Uint64(cnmt[0x20:0x20+0x8]))
+		}
+		if tableOffset >= 0xC {
+			result.RequiredSystemVersion = int(binary.LittleEndian.Uint32(cnmt[0x20+0x8 : 0x20+0xC]))
+		}
 	}
 
-	return &ContentMetaAttributes{Contents: contents, Version: int(version), TitleId: fmt.Sprintf("0%x", titleId), Type: metaType}, nil
+	return result, nil
 }
 
 func readXmlCnmt(xmlBytes []byte) (*ContentMetaAttributes, error) {
@@ -113,5 +204,43 @@ func readXmlCnmt(xmlBytes []byte) (*ContentMetaAttributes, error) {
 		return nil, err
 	}
 	titleId := strings.Replace(cmt.ID, "0x", "", 1)
-	return &ContentMetaAttributes{Version: cmt.Version, TitleId: titleId, Type: cmt.Type}, nil
+	applicationId := strings.Replace(cmt.ApplicationId, "0x", "", 1)
+	installSize := int64(0)
+	for _, content := range cmt.Content {
+		if size, err := strconv.ParseInt(content.Size, 10, 64); err == nil {
+			installSize += size
+		}
+	}
+	requiredSystemVersion, _ := strconv.Atoi(cmt.RequiredSystemVersion)
+	metaType := cmt.Type
+	switch cmt.Type {
+	case "Application":
+		metaType = "BASE"
+		applicationId = titleId
+	case "Patch":
+		metaType = "UPD"
+	case "AddOnContent":
+		metaType = "DLC"
+	}
+	return &ContentMetaAttributes{Version: cmt.Version, TitleId: strings.ToLower(titleId), Type: metaType,
+		ApplicationId: strings.ToLower(applicationId), RequiredApplicationVersion: cmt.RequiredApplicationVersion,
+		RequiredSystemVersion: requiredSystemVersion, InstallSize: installSize}, nil
+}
+
+// ReadCnmtXmlFile reads the content metadata from a .cnmt.xml file (as emitted by extraction tools),
+// no keys are required
+func ReadCnmtXmlFile(filePath string) (*ContentMetaAttributes, error) {
+	xmlBytes, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	cnmt, err := readXmlCnmt(xmlBytes)
+	if err != nil {
+		return nil, err
+	}
+	if cnmt.TitleId == "" {
+		return nil, errors.New("missing title id in " + filePath)
+	}
+	cnmt.Source = MetadataSource_CnmtXml
+	return cnmt, nil
 }
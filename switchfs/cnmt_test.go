@@ -0,0 +1,78 @@
+package switchfs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// buildCnmtPartition fabricates a decrypted meta section - a PFS0 holding a single binary cnmt
+func buildCnmtPartition(titleId uint64, version uint32, metaType byte) []byte {
+	name := []byte("Application_" + "test.cnmt\x00")
+	cnmt := make([]byte, 0x20)
+	binary.LittleEndian.PutUint64(cnmt[0:0x8], titleId)
+	binary.LittleEndian.PutUint32(cnmt[0x8:0xC], version)
+	cnmt[0xC] = metaType
+
+	header := make([]byte, 0x10+PfsfileEntryTableSize)
+	copy(header, pfs0Magic)
+	binary.LittleEndian.PutUint32(header[0x4:0x8], 1)
+	binary.LittleEndian.PutUint32(header[0x8:0xC], uint32(len(name)))
+	binary.LittleEndian.PutUint64(header[0x10:0x18], 0)
+	binary.LittleEndian.PutUint64(header[0x18:0x20], uint64(len(cnmt)))
+
+	section := append(header, name...)
+	return append(section, cnmt...)
+}
+
+func readTestCnmt(t *testing.T, section []byte) *ContentMetaAttributes {
+	pfs0, err := readPfs0(bytes.NewReader(section), 0x0)
+	if err != nil {
+		t.Fatalf("failed to read pfs0 - %v", err)
+	}
+	cnmt, err := readBinaryCnmt(pfs0, section)
+	if err != nil {
+		t.Fatalf("failed to read cnmt - %v", err)
+	}
+	return cnmt
+}
+
+func TestAddContentMetaKeepsCollidingEntries(t *testing.T) {
+	first := readTestCnmt(t, buildCnmtPartition(0x0100000000010000, 0, ContentMetaType_Application))
+	second := readTestCnmt(t, buildCnmtPartition(0x0100000000010000, 65536, ContentMetaType_Application))
+	other := readTestCnmt(t, buildCnmtPartition(0x0100000000010800, 65536, ContentMetaType_Patch))
+
+	contentMap := map[string]*ContentMetaAttributes{}
+	addContentMeta(contentMap, first)
+	addContentMeta(contentMap, second)
+	addContentMeta(contentMap, other)
+
+	if len(contentMap) != 2 {
+		t.Fatalf("expected 2 title ids, got %v", len(contentMap))
+	}
+	base := contentMap["0100000000010000"]
+	if base != first {
+		t.Fatalf("expected the first cnmt to be kept")
+	}
+	if len(base.Conflicts) != 1 || base.Conflicts[0].Version != 65536 {
+		t.Fatalf("expected the colliding cnmt to be recorded, got %v", base.Conflicts)
+	}
+	if len(contentMap["0100000000010800"].Conflicts) != 0 {
+		t.Fatalf("expected no conflicts for a distinct title id")
+	}
+}
+
+func TestFirmwareVersion(t *testing.T) {
+	tests := map[int]string{
+		0:         "",
+		603979776: "9.0.0",
+		605028352: "9.1.0",
+		671088640: "10.0.0",
+		201457664: "3.0.2",
+	}
+	for systemVersion, expected := range tests {
+		if version := FirmwareVersion(systemVersion); version != expected {
+			t.Errorf("FirmwareVersion(%v) - expected %v, got %v", systemVersion, expected, version)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package switchfs
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+type FileType int
+
+const (
+	FileType_Unsupported FileType = iota
+	FileType_NSP
+	FileType_NSZ
+	FileType_XCI
+	FileType_XCZ
+	//NSP variant used by some community tools, read as a NSP
+	FileType_NSX
+	//a part of a split file, see SplitContainerType for the container stored in the parts
+	FileType_SplitPart
+)
+
+func (t FileType) String() string {
+	switch t {
+	case FileType_NSP:
+		return "nsp"
+	case FileType_NSZ:
+		return "nsz"
+	case FileType_XCI:
+		return "xci"
+	case FileType_XCZ:
+		return "xcz"
+	case FileType_NSX:
+		return "nsx"
+	case FileType_SplitPart:
+		return "split"
+	}
+	return "unsupported"
+}
+
+// IsNsp returns true for the PFS0 based containers (NSP, NSZ, NSX)
+func (t FileType) IsNsp() bool {
+	return t == FileType_NSP || t == FileType_NSZ || t == FileType_NSX
+}
+
+// IsXci returns true for the game card containers (XCI, XCZ)
+func (t FileType) IsXci() bool {
+	return t == FileType_XCI || t == FileType_XCZ
+}
+
+// ClassifyFileName returns the container type of the file from its extension (case insensitive),
+// split parts are not detected (see ClassifyFile)
+func ClassifyFileName(fileName string) FileType {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".nsp":
+		return FileType_NSP
+	case ".nsz":
+		return FileType_NSZ
+	case ".xci":
+		return FileType_XCI
+	case ".xcz":
+		return FileType_XCZ
+	case ".nsx":
+		return FileType_NSX
+	}
+	return FileType_Unsupported
+}
+
+// ClassifyFile classifies the file using the default split schemes (see SplitSchemes.ClassifyFile)
+func ClassifyFile(filePath string) FileType {
+	return defaultSplitSchemes.ClassifyFile(filePath)
+}
+
+// ClassifyFile returns the type of the file - FileType_SplitPart for split file parts (see SplitSchemes.IsSplitPart),
+// the container type from the extension otherwise
+func (s SplitSchemes) ClassifyFile(filePath string) FileType {
+	if s.IsSplitPart(filePath) {
+		return FileType_SplitPart
+	}
+	return ClassifyFileName(filepath.Base(filePath))
+}
+
+// SplitContainerType returns the container using the default split schemes (see SplitSchemes.ContainerType)
+func SplitContainerType(filePath string) FileType {
+	return defaultSplitSchemes.ContainerType(filePath)
+}
+
+// ContainerType returns the container stored in the split file parts, as told by their names - the folder
+// holding the parts (e.g. "Game.xcz/00") or the parts base name (e.g. "Game.xcz.00").
+// FileType_Unsupported is returned when the names don't tell, the container is then detected from the content
+func (s SplitSchemes) ContainerType(filePath string) FileType {
+	if t := ClassifyFileName(filepath.Dir(filePath)); t != FileType_Unsupported {
+		return t
+	}
+	if part, ok := s.ParsePart(filepath.Base(filePath)); ok {
+		return ClassifyFileName(part.Base)
+	}
+	return FileType_Unsupported
+}
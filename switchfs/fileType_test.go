@@ -0,0 +1,62 @@
+package switchfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClassifyFile(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-types")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+
+	files := []string{
+		"Game.NSP", "Game.nsz", "Game.Xci", "game.xcz", "Game.nsx", "Game.txt", "Game [v0]00",
+		"Split.xcz.00", "Split.xcz.01",
+		filepath.Join("Folder.XCZ", "00"),
+		filepath.Join("parts", "00"), filepath.Join("parts", "01"),
+	}
+	for _, file := range files {
+		path := filepath.Join(folder, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := map[string]FileType{
+		"Game.NSP":                        FileType_NSP,
+		"Game.nsz":                        FileType_NSZ,
+		"Game.Xci":                        FileType_XCI,
+		"game.xcz":                        FileType_XCZ,
+		"Game.nsx":                        FileType_NSX,
+		"Game.txt":                        FileType_Unsupported,
+		"Game [v0]00":                     FileType_Unsupported,
+		"Split.xcz.00":                    FileType_SplitPart,
+		"Split.xcz.01":                    FileType_SplitPart,
+		filepath.Join("Folder.XCZ", "00"): FileType_SplitPart,
+		filepath.Join("parts", "00"):      FileType_SplitPart,
+	}
+	for file, fileType := range expected {
+		if actual := ClassifyFile(filepath.Join(folder, file)); actual != fileType {
+			t.Errorf("expected %v to be classified as %v, got %v", file, fileType, actual)
+		}
+	}
+
+	containers := map[string]FileType{
+		"Split.xcz.00":                    FileType_XCZ,
+		filepath.Join("Folder.XCZ", "00"): FileType_XCZ,
+		filepath.Join("parts", "00"):      FileType_Unsupported,
+	}
+	for file, fileType := range containers {
+		if actual := SplitContainerType(filepath.Join(folder, file)); actual != fileType {
+			t.Errorf("expected the container of %v to be %v, got %v", file, fileType, actual)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/binary"
 	"errors"
 	"io"
+	"strconv"
+	"strings"
 )
 
 type Language int
@@ -36,6 +38,43 @@ type Nacp struct {
 	Isbn                  string
 	DisplayVersion        string
 	SupportedLanguageFlag uint32
+	//ids used for local wireless (LDN) sessions, the tools fill them with the application id by default
+	//(see SupportsLocalWireless)
+	LocalCommunicationIds []uint64
+	SaveDataSize          SaveDataSize
+}
+
+// SaveDataSize holds the save data sizes declared in the NACP, all sizes are in bytes.
+// The journal sizes are the extra space reserved to commit the save data atomically.
+type SaveDataSize struct {
+	UserAccount        int64 `json:"user_account"`
+	UserAccountJournal int64 `json:"user_account_journal"`
+	Device             int64 `json:"device"`
+	DeviceJournal      int64 `json:"device_journal"`
+}
+
+// Total returns the space (in bytes) the save data takes on the console for a single user
+func (s SaveDataSize) Total() int64 {
+	return s.UserAccount + s.UserAccountJournal + s.Device + s.DeviceJournal
+}
+
+// SupportsLocalWireless returns true when the title declares a local communication (local wireless multiplayer) id
+// other than its own application id (the default value of the ids, set whether or not the title plays locally).
+// the NACP declares neither the number of players nor the handheld mode support
+func (n *Nacp) SupportsLocalWireless(applicationId string) bool {
+	if n == nil {
+		return false
+	}
+	id, err := strconv.ParseUint(applicationId, 16, 64)
+	if err != nil {
+		return false
+	}
+	for _, communicationId := range n.LocalCommunicationIds {
+		if communicationId != id {
+			return true
+		}
+	}
+	return false
 }
 
 func (l Language) String() string {
@@ -62,7 +101,11 @@ func ExtractNacp(cnmt *ContentMetaAttributes, file io.ReaderAt, securePartition
 	if control, ok := cnmt.Contents["Control"]; ok {
 		controlNca := getNcaById(securePartition, control.ID)
 		if controlNca != nil {
-			fsHeader, section, err := openMetaNcaDataSection(file, securePartitionOffset+int64(controlNca.StartOffset))
+			openDataSection := openMetaNcaDataSection
+			if strings.HasSuffix(strings.ToLower(controlNca.Name), ".ncz") {
+				openDataSection = openNczDataSection
+			}
+			fsHeader, section, err := openDataSection(file, securePartitionOffset+int64(controlNca.StartOffset))
 			if err != nil {
 				return nil, err
 			}
@@ -108,8 +151,22 @@ func readNacp(data []byte, romFsHeader RomfsHeader, fileEntry RomfsFileEntry) (N
 	isbn := readBytesUntilZero(data[offset+0x3000 : offset+0x3000+0x25])
 	displayVersion := readBytesUntilZero(data[offset+0x3060 : offset+0x3060+0x10])
 	supportedLanguageFlag := binary.BigEndian.Uint32(data[offset+0x302C : offset+0x302C+0x4])
+	var localCommunicationIds []uint64
+	for i := uint64(0); i < 8; i++ {
+		id := binary.LittleEndian.Uint64(data[offset+0x30B0+i*0x8 : offset+0x30B0+i*0x8+0x8])
+		if id != 0 {
+			localCommunicationIds = append(localCommunicationIds, id)
+		}
+	}
+	saveDataSize := SaveDataSize{
+		UserAccount:        int64(binary.LittleEndian.Uint64(data[offset+0x3080 : offset+0x3080+0x8])),
+		UserAccountJournal: int64(binary.LittleEndian.Uint64(data[offset+0x3088 : offset+0x3088+0x8])),
+		Device:             int64(binary.LittleEndian.Uint64(data[offset+0x3090 : offset+0x3090+0x8])),
+		DeviceJournal:      int64(binary.LittleEndian.Uint64(data[offset+0x3098 : offset+0x3098+0x8])),
+	}
 
-	return Nacp{TitleName: titles, Isbn: string(isbn), DisplayVersion: string(displayVersion), SupportedLanguageFlag: supportedLanguageFlag}, nil
+	return Nacp{TitleName: titles, Isbn: string(isbn), DisplayVersion: string(displayVersion), SupportedLanguageFlag: supportedLanguageFlag,
+		LocalCommunicationIds: localCommunicationIds, SaveDataSize: saveDataSize}, nil
 	/*
 
 
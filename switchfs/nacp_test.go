@@ -0,0 +1,54 @@
+package switchfs
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestReadNacpSaveDataSize(t *testing.T) {
+	const dataOffset, fileOffset = 0x10, 0x20
+	data := make([]byte, dataOffset+fileOffset+0x4000)
+	nacp := data[dataOffset+fileOffset:]
+	copy(nacp, "Test Game")
+	binary.LittleEndian.PutUint64(nacp[0x3080:], 0x400000)
+	binary.LittleEndian.PutUint64(nacp[0x3088:], 0x100000)
+	binary.LittleEndian.PutUint64(nacp[0x3090:], 0x200000)
+	binary.LittleEndian.PutUint64(nacp[0x3098:], 0x80000)
+
+	result, err := readNacp(data, RomfsHeader{DataOffset: dataOffset}, RomfsFileEntry{offset: fileOffset})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := SaveDataSize{UserAccount: 0x400000, UserAccountJournal: 0x100000, Device: 0x200000, DeviceJournal: 0x80000}
+	if result.SaveDataSize != expected {
+		t.Errorf("expected %+v, got %+v", expected, result.SaveDataSize)
+	}
+	if result.SaveDataSize.Total() != 0x780000 {
+		t.Errorf("unexpected total %v", result.SaveDataSize.Total())
+	}
+	if result.TitleName["AmericanEnglish"].Title != "Test Game" {
+		t.Errorf("unexpected title %v", result.TitleName["AmericanEnglish"].Title)
+	}
+}
+
+func TestSupportsLocalWireless(t *testing.T) {
+	tests := []struct {
+		ids      []uint64
+		expected bool
+	}{
+		{nil, false},
+		//the default value, the application id
+		{[]uint64{0x0100000000010000}, false},
+		//shared with another title
+		{[]uint64{0x0100000000010000, 0x0100000000020000}, true},
+	}
+	for _, test := range tests {
+		nacp := &Nacp{LocalCommunicationIds: test.ids}
+		if actual := nacp.SupportsLocalWireless("0100000000010000"); actual != test.expected {
+			t.Errorf("%x - expected %v, got %v", test.ids, test.expected, actual)
+		}
+	}
+	if (*Nacp)(nil).SupportsLocalWireless("0100000000010000") {
+		t.Error("expected no local wireless without control data")
+	}
+}
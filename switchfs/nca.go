@@ -12,6 +12,22 @@ import (
 	"io"
 )
 
+// KeyError is returned when a file can't be decrypted because of the keys (a missing key, or a header_key which
+// doesn't decrypt the NCA headers) rather than because of the file itself
+type KeyError struct {
+	Reason string
+}
+
+func (e *KeyError) Error() string {
+	return "prod.keys problem - " + e.Reason
+}
+
+// IsKeyError returns true when the error (or an error it wraps) is a KeyError
+func IsKeyError(err error) bool {
+	var keyErr *KeyError
+	return errors.As(err, &keyErr)
+}
+
 const (
 	NcaSectionType_Code = iota
 	NcaSectionType_Data
@@ -26,34 +42,49 @@ const (
 	NcaContentType_PublicData
 )
 
-func openMetaNcaDataSection(reader io.ReaderAt, ncaOffset int64) (*fsHeader, []byte, error) {
+func readNcaHeader(reader io.ReaderAt, ncaOffset int64) (*ncaHeader, error) {
 	//read the NCA headerBytes
 	encNcaHeader := make([]byte, 0xC00)
 	n, err := reader.ReadAt(encNcaHeader, ncaOffset)
 
 	if err != nil {
-		return nil, nil, errors.New("failed to read NCA header " + err.Error())
+		return nil, errors.New("failed to read NCA header " + err.Error())
 	}
 	if n != 0xC00 {
-		return nil, nil, errors.New("failed to read NCA header")
+		return nil, errors.New("failed to read NCA header")
 	}
 
 	keys, err := settings.SwitchKeys()
 	if err != nil {
-		return nil, nil, err
+		return nil, &KeyError{Reason: err.Error()}
+	}
+	if keys == nil {
+		return nil, &KeyError{Reason: "no keys loaded"}
 	}
 	headerKey := keys.GetKey("header_key")
 	if headerKey == "" {
-		return nil, nil, errors.New("missing key - header_key")
+		return nil, &KeyError{Reason: "missing key - header_key"}
 	}
 	ncaHeader, err := DecryptNcaHeader(headerKey, encNcaHeader)
 	if err != nil {
-		return nil, nil, err
+		return nil, &KeyError{Reason: "invalid header_key - " + err.Error()}
+	}
+	//the header of a NCA always decrypts to a known magic ("NCA3", "NCA2"), whatever the file
+	if magic := string(ncaHeader.headerBytes[0x200:0x203]); magic != "NCA" {
+		return nil, &KeyError{Reason: "the header_key doesn't decrypt the NCA header (wrong header_key)"}
 	}
 
 	if ncaHeader.HasRightsId() {
 		//fail - need title keys
-		return nil, nil, errors.New("non standard encryption is not supported")
+		return nil, errors.New("non standard encryption is not supported")
+	}
+	return ncaHeader, nil
+}
+
+func openMetaNcaDataSection(reader io.ReaderAt, ncaOffset int64) (*fsHeader, []byte, error) {
+	ncaHeader, err := readNcaHeader(reader, ncaOffset)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	/*if ncaHeader.contentType != NcaContentType_Meta {
@@ -111,7 +142,7 @@ func decryptAesCtr(ncaHeader *ncaHeader, fsHeader *fsHeader, offset uint32, size
 	keyName := fmt.Sprintf("key_area_key_application_0%x", keyRevision)
 	KeyString := keys.GetKey(keyName)
 	if KeyString == "" {
-		return nil, errors.New(fmt.Sprintf("missing Key_area_key[%v]", keyName))
+		return nil, &KeyError{Reason: fmt.Sprintf("missing Key_area_key[%v]", keyName)}
 	}
 	key, _ := hex.DecodeString(KeyString)
 
@@ -0,0 +1,186 @@
+package switchfs
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"io/ioutil"
+)
+
+//https://github.com/nicoboss/nsz
+
+const (
+	nczSectionMagic = "NCZSECTN"
+	nczBlockMagic   = "NCZBLOCK"
+	//the beginning of the NCA is kept as is (encrypted and uncompressed) in the NCZ
+	nczHeaderSize = 0x4000
+)
+
+// openNczDataSection is the NCZ (compressed NCA) counterpart of openMetaNcaDataSection, only the blocks
+// covering the section are decompressed (the compressed data is already decrypted)
+func openNczDataSection(reader io.ReaderAt, nczOffset int64) (*fsHeader, []byte, error) {
+	ncaHeader, err := readNcaHeader(reader, nczOffset)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dataSectionIndex := 0
+
+	fsHeader, err := getFsHeader(ncaHeader, dataSectionIndex)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entry := getFsEntry(ncaHeader, dataSectionIndex)
+
+	if entry.Size == 0 {
+		return nil, nil, errors.New("empty section")
+	}
+	if entry.StartOffset < nczHeaderSize {
+		return nil, nil, errors.New("unexpected NCZ section offset")
+	}
+
+	body, err := newNczBodyReader(reader, nczOffset+nczHeaderSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer body.Close()
+
+	decoded := make([]byte, entry.Size)
+	_, err = body.ReadAt(decoded, int64(entry.StartOffset)-nczHeaderSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashInfo, err := fsHeader.getHashInfo()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return fsHeader, decoded[hashInfo.pfs0HeaderOffset:], nil
+}
+
+// nczBodyReader reads the decompressed NCZ body (the NCA content following the first 0x4000 bytes).
+// with block compression only the blocks covering the requested range are read and decompressed,
+// otherwise (a single zstd stream) the stream is decompressed up to the end of the requested range
+type nczBodyReader struct {
+	reader io.ReaderAt
+	//start of the compressed data
+	dataOffset int64
+	//zero when block compression is not used
+	blockSize        int64
+	decompressedSize int64
+	//the offset of each block in the reader, followed by the end offset of the last block
+	blockOffsets []int64
+	decoder      *zstd.Decoder
+}
+
+func newNczBodyReader(reader io.ReaderAt, offset int64) (*nczBodyReader, error) {
+	sectionHeader := make([]byte, 0x10)
+	if _, err := reader.ReadAt(sectionHeader, offset); err != nil {
+		return nil, errors.New("failed to read NCZ section header " + err.Error())
+	}
+	if string(sectionHeader[:0x8]) != nczSectionMagic {
+		return nil, errors.New("Invalid NCZ section header. Expected '" + nczSectionMagic + "', got '" + string(sectionHeader[:0x8]) + "'")
+	}
+	sectionCount := binary.LittleEndian.Uint64(sectionHeader[0x8:0x10])
+	offset += 0x10 + int64(sectionCount)*0x40
+
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	result := &nczBodyReader{reader: reader, dataOffset: offset, decoder: decoder}
+
+	blockHeader := make([]byte, 0x18)
+	n, err := reader.ReadAt(blockHeader, offset)
+	if n != len(blockHeader) || string(blockHeader[:0x8]) != nczBlockMagic {
+		//no block compression
+		return result, nil
+	}
+	blockSizeExponent := blockHeader[0xB]
+	if blockSizeExponent < 14 || blockSizeExponent > 32 {
+		decoder.Close()
+		return nil, errors.New("invalid NCZ block size")
+	}
+	numberOfBlocks := binary.LittleEndian.Uint32(blockHeader[0xC:0x10])
+	result.blockSize = int64(1) << blockSizeExponent
+	result.decompressedSize = int64(binary.LittleEndian.Uint64(blockHeader[0x10:0x18]))
+
+	blockSizes := make([]byte, 4*int64(numberOfBlocks))
+	if _, err := reader.ReadAt(blockSizes, offset+0x18); err != nil {
+		decoder.Close()
+		return nil, errors.New("failed to read NCZ block sizes " + err.Error())
+	}
+	result.dataOffset = offset + 0x18 + int64(len(blockSizes))
+	result.blockOffsets = make([]int64, numberOfBlocks+1)
+	result.blockOffsets[0] = result.dataOffset
+	for i := uint32(0); i < numberOfBlocks; i++ {
+		result.blockOffsets[i+1] = result.blockOffsets[i] + int64(binary.LittleEndian.Uint32(blockSizes[4*i:4*i+4]))
+	}
+	return result, nil
+}
+
+func (r *nczBodyReader) ReadAt(p []byte, off int64) (int, error) {
+	if r.blockSize == 0 {
+		return r.readStreamAt(p, off)
+	}
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos >= r.decompressedSize {
+			return n, io.EOF
+		}
+		blockId := pos / r.blockSize
+		block, err := r.readBlock(int(blockId))
+		if err != nil {
+			return n, err
+		}
+		start := pos - blockId*r.blockSize
+		if start >= int64(len(block)) {
+			return n, io.ErrUnexpectedEOF
+		}
+		n += copy(p[n:], block[start:])
+	}
+	return n, nil
+}
+
+func (r *nczBodyReader) readBlock(blockId int) ([]byte, error) {
+	if blockId >= len(r.blockOffsets)-1 {
+		return nil, io.EOF
+	}
+	decompressedBlockSize := r.blockSize
+	if blockId == len(r.blockOffsets)-2 && r.decompressedSize%r.blockSize != 0 {
+		decompressedBlockSize = r.decompressedSize % r.blockSize
+	}
+	compressed := make([]byte, r.blockOffsets[blockId+1]-r.blockOffsets[blockId])
+	n, err := r.reader.ReadAt(compressed, r.blockOffsets[blockId])
+	if n != len(compressed) {
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	//blocks which don't compress well are stored as is
+	if int64(len(compressed)) >= decompressedBlockSize {
+		return compressed, nil
+	}
+	return r.decoder.DecodeAll(compressed, make([]byte, 0, decompressedBlockSize))
+}
+
+func (r *nczBodyReader) readStreamAt(p []byte, off int64) (int, error) {
+	stream, err := zstd.NewReader(io.NewSectionReader(r.reader, r.dataOffset, 1<<62), zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return 0, err
+	}
+	defer stream.Close()
+	if _, err := io.CopyN(ioutil.Discard, stream, off); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(stream, p)
+}
+
+func (r *nczBodyReader) Close() {
+	r.decoder.Close()
+}
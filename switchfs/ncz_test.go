@@ -0,0 +1,111 @@
+package switchfs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/klauspost/compress/zstd"
+	"math/rand"
+	"testing"
+)
+
+// countingReaderAt counts the bytes read from the underlying reader
+type countingReaderAt struct {
+	reader    *bytes.Reader
+	bytesRead int64
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.reader.ReadAt(p, off)
+	c.bytesRead += int64(n)
+	return n, err
+}
+
+// buildNczBody fabricates a block compressed NCZ body (section header, block header and blocks),
+// alternating between compressible blocks and random (stored as is) blocks
+func buildNczBody(t *testing.T, blockSizeExponent byte, numberOfBlocks int) ([]byte, []byte) {
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer encoder.Close()
+
+	blockSize := 1 << blockSizeExponent
+	random := rand.New(rand.NewSource(1))
+	decompressed := make([]byte, 0, blockSize*numberOfBlocks)
+	var blocks [][]byte
+	for i := 0; i < numberOfBlocks; i++ {
+		block := make([]byte, blockSize)
+		if i%2 == 0 {
+			random.Read(block)
+		} else {
+			for j := range block {
+				block[j] = byte(i)
+			}
+		}
+		decompressed = append(decompressed, block...)
+		compressed := encoder.EncodeAll(block, nil)
+		if len(compressed) >= len(block) {
+			compressed = block
+		}
+		blocks = append(blocks, compressed)
+	}
+
+	body := []byte(nczSectionMagic)
+	body = append(body, make([]byte, 0x8+0x40)...)
+	binary.LittleEndian.PutUint64(body[0x8:0x10], 1)
+
+	blockHeader := make([]byte, 0x18)
+	copy(blockHeader, nczBlockMagic)
+	blockHeader[0x8] = 2
+	blockHeader[0xB] = blockSizeExponent
+	binary.LittleEndian.PutUint32(blockHeader[0xC:0x10], uint32(numberOfBlocks))
+	binary.LittleEndian.PutUint64(blockHeader[0x10:0x18], uint64(len(decompressed)))
+	body = append(body, blockHeader...)
+	for _, block := range blocks {
+		size := make([]byte, 4)
+		binary.LittleEndian.PutUint32(size, uint32(len(block)))
+		body = append(body, size...)
+	}
+	for _, block := range blocks {
+		body = append(body, block...)
+	}
+	return body, decompressed
+}
+
+func TestNczBodyReaderReadsBoundedBlocks(t *testing.T) {
+	const blockSizeExponent = 16
+	const numberOfBlocks = 256
+	blockSize := int64(1) << blockSizeExponent
+	body, decompressed := buildNczBody(t, blockSizeExponent, numberOfBlocks)
+
+	reader := &countingReaderAt{reader: bytes.NewReader(body)}
+	nczReader, err := newNczBodyReader(reader, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nczReader.Close()
+	headerBytes := reader.bytesRead
+
+	//a range crossing two blocks in the middle of the file
+	offset := 100*blockSize - 0x100
+	buffer := make([]byte, 0x200)
+	if _, err := nczReader.ReadAt(buffer, offset); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buffer, decompressed[offset:offset+int64(len(buffer))]) {
+		t.Fatalf("unexpected decompressed data")
+	}
+	if read := reader.bytesRead - headerBytes; read > 2*blockSize {
+		t.Fatalf("expected at most 2 blocks to be read, read %v bytes (file is %v bytes)", read, len(body))
+	}
+
+	//the last block
+	buffer = make([]byte, 0x10)
+	offset = int64(len(decompressed)) - 0x10
+	if _, err := nczReader.ReadAt(buffer, offset); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buffer, decompressed[offset:]) {
+		t.Fatalf("unexpected decompressed data at the end of the file")
+	}
+}
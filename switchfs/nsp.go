@@ -4,16 +4,12 @@ import (
 	"bytes"
 	"errors"
 	"go.uber.org/zap"
+	"io"
 	"strings"
 )
 
 func ReadNspMetadata(filePath string) (map[string]*ContentMetaAttributes, error) {
 
-	pfs0, err := ReadPfs0File(filePath)
-	if err != nil {
-		return nil, errors.New("Invalid NSP file, reason - [" + err.Error() + "]")
-	}
-
 	file, err := OpenFile(filePath)
 	if err != nil {
 		return nil, err
@@ -21,6 +17,23 @@ func ReadNspMetadata(filePath string) (map[string]*ContentMetaAttributes, error)
 
 	defer file.Close()
 
+	return readNspMetadata(file)
+}
+
+// ReadNspMetadataFrom reads the NSP/NSZ metadata from a reader (e.g. an archive entry, see OpenZipEntry, or a remote
+// file) holding size bytes, reads beyond size fail as for a truncated file. only the header, the meta NCA and
+// the control NCA sections are read, not the whole file
+func ReadNspMetadataFrom(ra io.ReaderAt, size int64) (map[string]*ContentMetaAttributes, error) {
+	return readNspMetadata(io.NewSectionReader(ra, 0, size))
+}
+
+func readNspMetadata(file io.ReaderAt) (map[string]*ContentMetaAttributes, error) {
+
+	pfs0, err := readPfs0(file, 0x0)
+	if err != nil {
+		return nil, errors.New("Invalid NSP file, reason - [" + err.Error() + "]")
+	}
+
 	contentMap := map[string]*ContentMetaAttributes{}
 
 	for _, pfs0File := range pfs0.Files {
@@ -46,9 +59,12 @@ func ReadNspMetadata(filePath string) (map[string]*ContentMetaAttributes, error)
 					zap.S().Debug("Failed to extract nacp [%v]\n", err.Error())
 				}
 				currCnmt.Ncap = nacp
+				if nacp != nil {
+					currCnmt.SaveDataSize = nacp.SaveDataSize
+				}
 			}
 
-			contentMap[currCnmt.TitleId] = currCnmt
+			addContentMeta(contentMap, currCnmt)
 
 		} /*else if strings.Contains(pfs0File.Name, ".cnmt.xml") {
 			xmlBytes := make([]byte, pfs0File.Size)
@@ -64,6 +80,57 @@ func ReadNspMetadata(filePath string) (map[string]*ContentMetaAttributes, error)
 			contentMap[currCnmt.TitleId] = currCnmt
 		}*/
 	}
+	setTicketStatus(contentMap, pfs0)
 	return contentMap, nil
 
 }
+
+// ReadNspCnmtXmlFrom reads the metadata from the .cnmt.xml files stored in the NSP (as added by some packing tools),
+// the NSP header and these files are not encrypted so no keys are required. an error is returned when the NSP holds
+// no .cnmt.xml file
+func ReadNspCnmtXmlFrom(ra io.ReaderAt, size int64) (map[string]*ContentMetaAttributes, error) {
+	file := io.NewSectionReader(ra, 0, size)
+	pfs0, err := readPfs0(file, 0x0)
+	if err != nil {
+		return nil, errors.New("Invalid NSP file, reason - [" + err.Error() + "]")
+	}
+	contentMap := map[string]*ContentMetaAttributes{}
+	for _, pfs0File := range pfs0.Files {
+		if !strings.HasSuffix(strings.ToLower(pfs0File.Name), ".cnmt.xml") {
+			continue
+		}
+		xmlBytes := make([]byte, pfs0File.Size)
+		if _, err := file.ReadAt(xmlBytes, int64(pfs0File.StartOffset)); err != nil {
+			return nil, err
+		}
+		cnmt, err := readXmlCnmt(xmlBytes)
+		if err != nil {
+			return nil, err
+		}
+		if cnmt.TitleId == "" {
+			return nil, errors.New("missing title id in " + pfs0File.Name)
+		}
+		cnmt.Source = MetadataSource_CnmtXml
+		addContentMeta(contentMap, cnmt)
+	}
+	if len(contentMap) == 0 {
+		return nil, errors.New("no cnmt.xml file in the NSP")
+	}
+	setTicketStatus(contentMap, pfs0)
+	return contentMap, nil
+}
+
+// setTicketStatus marks the contents having a ticket in the NSP, tickets are named after the rights id
+// ("<title id><key generation>.tik")
+func setTicketStatus(contentMap map[string]*ContentMetaAttributes, pfs0 *PFS0) {
+	for _, cnmt := range contentMap {
+		cnmt.Ticket = TicketStatus_Missing
+		for _, pfs0File := range pfs0.Files {
+			name := strings.ToLower(pfs0File.Name)
+			if strings.HasSuffix(name, ".tik") && strings.HasPrefix(name, strings.ToLower(cnmt.TitleId)) {
+				cnmt.Ticket = TicketStatus_Present
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package switchfs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/giwty/switch-library-manager/settings"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetTicketStatus(t *testing.T) {
+	pfs0 := &PFS0{Files: []fileEntry{
+		{Name: "0123456789abcdef0123456789abcdef.cnmt.nca"},
+		{Name: "01000000000108000000000000000005.tik"},
+		{Name: "01000000000108000000000000000005.cert"},
+	}}
+	contentMap := map[string]*ContentMetaAttributes{
+		"0100000000010800": {TitleId: "0100000000010800"},
+		"0100000000011001": {TitleId: "0100000000011001"},
+	}
+	setTicketStatus(contentMap, pfs0)
+	if contentMap["0100000000010800"].Ticket != TicketStatus_Present {
+		t.Errorf("expected the update ticket to be present, got %v", contentMap["0100000000010800"].Ticket)
+	}
+	if contentMap["0100000000011001"].Ticket != TicketStatus_Missing {
+		t.Errorf("expected the DLC ticket to be missing, got %v", contentMap["0100000000011001"].Ticket)
+	}
+}
+
+func TestReadMetadataFromBoundedReader(t *testing.T) {
+	//a valid XCI header magic, beyond the given size
+	data := make([]byte, 0x400)
+	copy(data[0x100:], "HEAD")
+	if _, err := ReadXciMetadataFrom(bytes.NewReader(data), 0x180); err == nil {
+		t.Errorf("expected an error reading the XCI header beyond the size")
+	}
+	if _, err := ReadXciMetadataFrom(bytes.NewReader(data[:0x200]), 0x200); err == nil {
+		t.Errorf("expected an error for a XCI without partitions")
+	}
+	if _, err := ReadNspMetadataFrom(bytes.NewReader([]byte("PFS0")), 4); err == nil {
+		t.Errorf("expected an error for a truncated NSP")
+	}
+}
+
+// buildPfs0 fabricates a PFS0 (e.g. a NSP) holding the given files, in order
+func buildPfs0(names []string, contents [][]byte) []byte {
+	var nameTable []byte
+	header := make([]byte, 0x10+PfsfileEntryTableSize*len(names))
+	copy(header, pfs0Magic)
+	binary.LittleEndian.PutUint32(header[0x4:0x8], uint32(len(names)))
+	offset := uint64(0)
+	for i, name := range names {
+		entry := header[0x10+PfsfileEntryTableSize*i:]
+		binary.LittleEndian.PutUint64(entry[0x0:0x8], offset)
+		binary.LittleEndian.PutUint64(entry[0x8:0x10], uint64(len(contents[i])))
+		binary.LittleEndian.PutUint32(entry[0x10:0x14], uint32(len(nameTable)))
+		nameTable = append(nameTable, append([]byte(name), 0)...)
+		offset += uint64(len(contents[i]))
+	}
+	binary.LittleEndian.PutUint32(header[0x8:0xC], uint32(len(nameTable)))
+	data := append(header, nameTable...)
+	for _, content := range contents {
+		data = append(data, content...)
+	}
+	return data
+}
+
+func TestReadNspCnmtXmlFrom(t *testing.T) {
+	cnmtXml := []byte(`<?xml version="1.0" encoding="utf-8"?>
+<ContentMeta>
+  <Type>Patch</Type>
+  <Id>0x0100000000010800</Id>
+  <Version>131072</Version>
+  <ApplicationId>0x0100000000010000</ApplicationId>
+</ContentMeta>`)
+	nsp := buildPfs0([]string{"0123456789abcdef0123456789abcdef.cnmt.nca", "0123456789abcdef0123456789abcdef.cnmt.xml",
+		"01000000000108000000000000000005.tik"}, [][]byte{[]byte("encrypted"), cnmtXml, []byte("ticket")})
+
+	contentMap, err := ReadNspCnmtXmlFrom(bytes.NewReader(nsp), int64(len(nsp)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	update, ok := contentMap["0100000000010800"]
+	if len(contentMap) != 1 || !ok {
+		t.Fatalf("expected the update metadata, got %v", contentMap)
+	}
+	if update.Version != 131072 || update.Type != "UPD" || update.Source != MetadataSource_CnmtXml ||
+		update.Ticket != TicketStatus_Present {
+		t.Errorf("unexpected metadata %+v", update)
+	}
+
+	withoutXml := buildPfs0([]string{"0123456789abcdef0123456789abcdef.cnmt.nca"}, [][]byte{[]byte("encrypted")})
+	if _, err := ReadNspCnmtXmlFrom(bytes.NewReader(withoutXml), int64(len(withoutXml))); err == nil {
+		t.Errorf("expected an error for a NSP without cnmt.xml")
+	}
+}
+
+func TestReadNspMetadataKeyError(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "slm-base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseFolder)
+	//well formed, but not the header_key the NCA headers are encrypted with
+	keys := "header_key = " + strings.Repeat("00", 32) + "\n"
+	if err := ioutil.WriteFile(filepath.Join(baseFolder, "prod.keys"), []byte(keys), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := settings.InitSwitchKeys(baseFolder); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		settings.ReadSettings(baseFolder).Prodkeys = ""
+		os.Remove(filepath.Join(baseFolder, "prod.keys"))
+		settings.InitSwitchKeys(baseFolder)
+	}()
+
+	data := buildPfs0([]string{"0123456789abcdef0123456789abcdef.cnmt.nca"}, [][]byte{make([]byte, 0xC00)})
+	_, err = ReadNspMetadataFrom(bytes.NewReader(data), int64(len(data)))
+	if !IsKeyError(err) {
+		t.Errorf("expected a key error, got %v", err)
+	}
+
+	//a malformed file is not a key error
+	_, err = ReadNspMetadataFrom(bytes.NewReader([]byte("not a PFS0 header")), 17)
+	if err == nil || IsKeyError(err) {
+		t.Errorf("expected a malformed file error, got %v", err)
+	}
+}
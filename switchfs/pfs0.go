@@ -45,6 +45,11 @@ func ReadPfs0File(filePath string) (*PFS0, error) {
 	return p, nil
 }
 
+// ReadPfs0From reads the PFS0 header at the start of the reader (e.g. a split file opened with SplitSchemes.OpenFile)
+func ReadPfs0From(reader io.ReaderAt) (*PFS0, error) {
+	return readPfs0(reader, 0x0)
+}
+
 func readPfs0(reader io.ReaderAt, offset int64) (*PFS0, error) {
 
 	header := make([]byte, 0xC)
@@ -4,11 +4,9 @@ import (
 	"errors"
 	"github.com/avast/retry-go"
 	"io"
-	"io/ioutil"
 	"os"
-	"path"
+	"path/filepath"
 	"strconv"
-	"strings"
 )
 
 type ReadAtCloser interface {
@@ -16,10 +14,43 @@ type ReadAtCloser interface {
 	io.Closer
 }
 
+// IOLimiter limits the number of concurrent reads across all the readers it wraps (see Limit),
+// a nil limiter does not limit the reads
+type IOLimiter struct {
+	semaphore chan struct{}
+}
+
+// NewIOLimiter returns a limiter allowing n concurrent reads, nil (unlimited) when n <= 0
+func NewIOLimiter(n int) *IOLimiter {
+	if n <= 0 {
+		return nil
+	}
+	return &IOLimiter{semaphore: make(chan struct{}, n)}
+}
+
+// Limit returns the reader with its reads counted against the limit
+func (l *IOLimiter) Limit(reader ReadAtCloser) ReadAtCloser {
+	if l == nil {
+		return reader
+	}
+	return &limitedReader{ReadAtCloser: reader, semaphore: l.semaphore}
+}
+
+type limitedReader struct {
+	ReadAtCloser
+	semaphore chan struct{}
+}
+
+func (r *limitedReader) ReadAt(p []byte, off int64) (int, error) {
+	r.semaphore <- struct{}{}
+	defer func() { <-r.semaphore }()
+	return r.ReadAtCloser.ReadAt(p, off)
+}
+
 type splitFile struct {
 	info      []os.FileInfo
 	files     []ReadAtCloser
-	path      string
+	parts     []string
 	chunkSize int64
 }
 
@@ -55,22 +86,32 @@ func (sp *fileWrapper) Close() error {
 	return nil
 }
 
+// NewSplitFileReader opens the split file the given part belongs to (see SplitFileParts)
 func NewSplitFileReader(filePath string) (*splitFile, error) {
-	result := splitFile{}
-	index := strings.LastIndex(filePath, string(os.PathSeparator))
-	splitFileFolder := filePath[:index]
-	files, err := ioutil.ReadDir(splitFileFolder)
+	return defaultSplitSchemes.newSplitFileReader(filePath)
+}
+
+func (s SplitSchemes) newSplitFileReader(filePath string) (*splitFile, error) {
+	parts, _, err := s.FileParts(filePath)
 	if err != nil {
 		return nil, err
 	}
-	result.path = splitFileFolder
-	result.chunkSize = files[0].Size()
-	result.info = make([]os.FileInfo, 0, len(files))
-	result.files = make([]ReadAtCloser, len(files))
-	for _, file := range files {
-		if _, err := strconv.Atoi(file.Name()[len(file.Name())-1:]); err == nil {
-			result.info = append(result.info, file)
+	if len(parts) == 0 {
+		return nil, errors.New("no split file parts found for " + filePath)
+	}
+	result := splitFile{parts: parts}
+	result.info = make([]os.FileInfo, 0, len(parts))
+	result.files = make([]ReadAtCloser, len(parts))
+	for _, part := range parts {
+		info, err := os.Stat(part)
+		if err != nil {
+			return nil, err
 		}
+		result.info = append(result.info, info)
+	}
+	result.chunkSize = result.info[0].Size()
+	if result.chunkSize == 0 {
+		return nil, errors.New("empty split file part " + parts[0])
 	}
 	return &result, nil
 }
@@ -79,12 +120,15 @@ func (sp *splitFile) ReadAt(p []byte, off int64) (n int, err error) {
 	//calculate the part containing the offset
 	part := int(off / sp.chunkSize)
 
-	if len(sp.info) < part {
+	if len(sp.info) <= part {
 		return 0, errors.New("missing part " + strconv.Itoa(part))
 	}
 
-	if len(sp.files) == 0 || sp.files[part] == nil {
-		file, _ := _openFile(path.Join(sp.path, sp.info[part].Name()))
+	if sp.files[part] == nil {
+		file, err := _openFile(sp.parts[part])
+		if err != nil {
+			return 0, err
+		}
 		sp.files[part] = file
 	}
 	off = off - sp.chunkSize*int64(part)
@@ -117,10 +161,17 @@ func (sp *splitFile) Close() error {
 	return nil
 }
 
+// OpenFile opens the file, or the whole split file for a split part named after the default schemes
+// (see SplitSchemes.OpenFile)
 func OpenFile(filePath string) (ReadAtCloser, error) {
+	return defaultSplitSchemes.OpenFile(filePath)
+}
+
+// OpenFile opens the file, or the whole split file when the file is named as a split part
+func (s SplitSchemes) OpenFile(filePath string) (ReadAtCloser, error) {
 	//check if it's a split file
-	if _, err := strconv.Atoi(filePath[len(filePath)-1:]); err == nil {
-		return NewSplitFileReader(filePath)
+	if _, ok := s.ParsePart(filepath.Base(filePath)); ok {
+		return s.newSplitFileReader(filePath)
 	} else {
 		return NewFileWrapper(filePath)
 	}
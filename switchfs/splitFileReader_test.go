@@ -0,0 +1,52 @@
+package switchfs
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type slowReader struct {
+	active  *int32
+	maxSeen *int32
+}
+
+func (r slowReader) ReadAt(p []byte, off int64) (int, error) {
+	active := atomic.AddInt32(r.active, 1)
+	defer atomic.AddInt32(r.active, -1)
+	for {
+		seen := atomic.LoadInt32(r.maxSeen)
+		if active <= seen || atomic.CompareAndSwapInt32(r.maxSeen, seen, active) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return len(p), nil
+}
+
+func (r slowReader) Close() error {
+	return nil
+}
+
+func TestIOLimiter(t *testing.T) {
+	if reader := (slowReader{}); NewIOLimiter(0).Limit(reader) != reader {
+		t.Error("expected a nil limiter to return the reader")
+	}
+
+	var active, maxSeen int32
+	limiter := NewIOLimiter(2)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		reader := limiter.Limit(slowReader{active: &active, maxSeen: &maxSeen})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reader.ReadAt(make([]byte, 1), 0)
+		}()
+	}
+	wg.Wait()
+	if maxSeen == 0 || maxSeen > 2 {
+		t.Errorf("expected at most 2 concurrent reads, got %v", maxSeen)
+	}
+}
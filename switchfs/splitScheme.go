@@ -0,0 +1,214 @@
+package switchfs
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// SplitScheme describes how the parts of a split file are named
+type SplitScheme struct {
+	Name string
+	//must contain the (?P<base>...) and (?P<part>...) groups
+	Pattern *regexp.Regexp
+	//number of the first part (e.g. 0 for ".00", 1 for ".part1")
+	FirstPart int
+}
+
+type SplitPart struct {
+	Scheme    string
+	Base      string
+	Part      int
+	FirstPart int
+}
+
+func (p SplitPart) IsFirst() bool {
+	return p.Part == p.FirstPart
+}
+
+// InFolder returns true for the parts named with their part number only, stored in a folder of their own
+// (e.g. "Game/01", "Game/02" as written by NAND backup style dumping tools) - such parts may be numbered from 0 or 1
+func (p SplitPart) InFolder() bool {
+	return p.Base == ""
+}
+
+// SplitSchemes are the naming schemes used to detect split files, checked in the given order.
+// a nil SplitSchemes uses the default schemes (see DefaultSplitSchemes)
+type SplitSchemes []SplitScheme
+
+var (
+	//used by the package functions, never modified
+	defaultSplitSchemes = DefaultSplitSchemes()
+)
+
+// DefaultSplitSchemes returns the commonly used naming schemes - ".part1/.part2", "_split00/_split01"
+// and ".00/.01" (or ".000/.001", also used for parts stored in a folder named after the file)
+func DefaultSplitSchemes() SplitSchemes {
+	return SplitSchemes{
+		{Name: "part", Pattern: regexp.MustCompile(`(?i)^(?P<base>.+)\.part(?P<part>[0-9]{1,3})$`), FirstPart: 1},
+		{Name: "split", Pattern: regexp.MustCompile(`(?i)^(?P<base>.+)_split(?P<part>[0-9]{1,3})$`), FirstPart: 0},
+		{Name: "numeric", Pattern: regexp.MustCompile(`^(?P<base>.*?)\.?(?P<part>[0-9]{2,3})$`), FirstPart: 0},
+	}
+}
+
+// NewSplitScheme compiles a custom naming scheme, the pattern must contain the (?P<base>...) and (?P<part>...) groups
+func NewSplitScheme(name string, pattern string, firstPart int) (SplitScheme, error) {
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return SplitScheme{}, fmt.Errorf("invalid split pattern [%v] - %v", pattern, err)
+	}
+	names := map[string]bool{}
+	for _, groupName := range regex.SubexpNames() {
+		names[groupName] = true
+	}
+	if !names["base"] || !names["part"] {
+		return SplitScheme{}, errors.New("split pattern [" + pattern + "] must contain the (?P<base>...) and (?P<part>...) groups")
+	}
+	return SplitScheme{Name: name, Pattern: regex, FirstPart: firstPart}, nil
+}
+
+func (s SplitSchemes) orDefault() SplitSchemes {
+	if s == nil {
+		return defaultSplitSchemes
+	}
+	return s
+}
+
+// ParseSplitPart returns the split part details of the file name using the default schemes (see SplitSchemes.ParsePart)
+func ParseSplitPart(fileName string) (SplitPart, bool) {
+	return defaultSplitSchemes.ParsePart(fileName)
+}
+
+// IsSplitPart checks the file using the default schemes (see SplitSchemes.IsSplitPart)
+func IsSplitPart(filePath string) bool {
+	return defaultSplitSchemes.IsSplitPart(filePath)
+}
+
+// IsFirstSplitPart checks the file using the default schemes (see SplitSchemes.IsFirstSplitPart)
+func IsFirstSplitPart(filePath string) bool {
+	return defaultSplitSchemes.IsFirstSplitPart(filePath)
+}
+
+// SplitFileParts lists the parts using the default schemes (see SplitSchemes.FileParts)
+func SplitFileParts(filePath string) ([]string, []string, error) {
+	return defaultSplitSchemes.FileParts(filePath)
+}
+
+// ParsePart returns the split part details of the file name, false if the name matches none of the split schemes
+func (s SplitSchemes) ParsePart(fileName string) (SplitPart, bool) {
+	for _, scheme := range s.orDefault() {
+		match := scheme.Pattern.FindStringSubmatch(fileName)
+		if match == nil {
+			continue
+		}
+		result := SplitPart{Scheme: scheme.Name, FirstPart: scheme.FirstPart}
+		for i, groupName := range scheme.Pattern.SubexpNames() {
+			switch groupName {
+			case "base":
+				result.Base = match[i]
+			case "part":
+				part, err := strconv.Atoi(match[i])
+				if err != nil {
+					return SplitPart{}, false
+				}
+				result.Part = part
+			}
+		}
+		return result, true
+	}
+	return SplitPart{}, false
+}
+
+// IsSplitPart returns true when the file is a part of a split file - its name matches a split scheme,
+// and other parts exist next to it or it is stored in a folder named after the file (e.g. "Game.xci/00").
+// a lone file whose name merely ends with digits (e.g. "Game [v0]00") is not a split file part
+func (s SplitSchemes) IsSplitPart(filePath string) bool {
+	if _, ok := s.ParsePart(filepath.Base(filePath)); !ok {
+		return false
+	}
+	if ClassifyFileName(filepath.Dir(filePath)) != FileType_Unsupported {
+		return true
+	}
+	parts, _, err := s.FileParts(filePath)
+	return err == nil && len(parts) > 1
+}
+
+// IsFirstSplitPart returns true when the file is the first part of its split file (see IsSplitPart), the part
+// representing the whole file. the first part of the parts stored in a folder of their own is the lowest numbered one
+func (s SplitSchemes) IsFirstSplitPart(filePath string) bool {
+	part, ok := s.ParsePart(filepath.Base(filePath))
+	if !ok {
+		return false
+	}
+	if !part.InFolder() {
+		return part.IsFirst()
+	}
+	parts, _, err := s.FileParts(filePath)
+	return err == nil && len(parts) != 0 && parts[0] == filePath
+}
+
+// FileParts returns the ordered paths of all the parts belonging to the same split file as filePath
+// (files in the same folder with the same base name and naming scheme, see SplitPart.InFolder). missing or duplicate part numbers and
+// parts of the same base name using another naming scheme are returned as warnings.
+func (s SplitSchemes) FileParts(filePath string) ([]string, []string, error) {
+	split, ok := s.ParsePart(filepath.Base(filePath))
+	if !ok {
+		return nil, nil, errors.New("[" + filePath + "] is not a split file part")
+	}
+	folder := filepath.Dir(filePath)
+	files, err := ioutil.ReadDir(folder)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var warnings []string
+	partNums := map[string]int{}
+	var parts []string
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		part, ok := s.ParsePart(file.Name())
+		if !ok || part.Base != split.Base {
+			continue
+		}
+		if part.Scheme != split.Scheme {
+			warnings = append(warnings, fmt.Sprintf("part [%v] uses a different naming scheme (%v, expected %v)",
+				file.Name(), part.Scheme, split.Scheme))
+			continue
+		}
+		partPath := filepath.Join(folder, file.Name())
+		partNums[partPath] = part.Part
+		parts = append(parts, partPath)
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		if partNums[parts[i]] != partNums[parts[j]] {
+			return partNums[parts[i]] < partNums[parts[j]]
+		}
+		return parts[i] < parts[j]
+	})
+
+	expected := split.FirstPart
+	//parts stored in a folder of their own may be numbered from 1 (e.g. "01", "02")
+	if split.InFolder() && len(parts) != 0 && partNums[parts[0]] == expected+1 {
+		expected++
+	}
+	for _, part := range parts {
+		switch {
+		case partNums[part] < expected:
+			warnings = append(warnings, fmt.Sprintf("duplicate part number %v (%v)", partNums[part], filepath.Base(part)))
+		case partNums[part] == expected+1:
+			warnings = append(warnings, fmt.Sprintf("missing part %v", expected))
+		case partNums[part] > expected:
+			warnings = append(warnings, fmt.Sprintf("missing parts %v-%v", expected, partNums[part]-1))
+		}
+		if partNums[part] >= expected {
+			expected = partNums[part] + 1
+		}
+	}
+	return parts, warnings, nil
+}
@@ -0,0 +1,96 @@
+package switchfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsSplitPart(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-split")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+
+	files := []string{
+		"Game [v0]00",
+		"Split.nsp.00", "Split.nsp.01",
+		"0",
+		filepath.Join("Folder.xci", "00"),
+		filepath.Join("parts", "00"), filepath.Join("parts", "01"),
+		filepath.Join("dump", "01"), filepath.Join("dump", "02"),
+	}
+	for _, file := range files {
+		path := filepath.Join(folder, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := map[string]bool{
+		//a lone file ending with digits
+		"Game [v0]00":                     false,
+		"Split.nsp.00":                    true,
+		"Split.nsp.01":                    true,
+		"0":                               false,
+		filepath.Join("Folder.xci", "00"): true,
+		filepath.Join("parts", "00"):      true,
+	}
+	for file, isSplit := range expected {
+		if IsSplitPart(filepath.Join(folder, file)) != isSplit {
+			t.Errorf("%v - expected split part %v", file, isSplit)
+		}
+	}
+
+	first := map[string]bool{
+		"Split.nsp.00":                    true,
+		"Split.nsp.01":                    false,
+		filepath.Join("Folder.xci", "00"): true,
+		filepath.Join("parts", "00"):      true,
+		filepath.Join("parts", "01"):      false,
+		//NAND backup style parts, numbered from 1
+		filepath.Join("dump", "01"): true,
+		filepath.Join("dump", "02"): false,
+	}
+	for file, isFirst := range first {
+		if IsFirstSplitPart(filepath.Join(folder, file)) != isFirst {
+			t.Errorf("%v - expected first split part %v", file, isFirst)
+		}
+	}
+}
+
+func TestSplitSchemesCustom(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-split")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+
+	for _, file := range []string{"Game.xci-0", "Game.xci-1"} {
+		if err := ioutil.WriteFile(filepath.Join(folder, file), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scheme, err := NewSplitScheme("letters", `^(?P<base>.+)-(?P<part>[0-9])$`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemes := append(SplitSchemes{scheme}, DefaultSplitSchemes()...)
+
+	first := filepath.Join(folder, "Game.xci-0")
+	if !schemes.IsFirstSplitPart(first) || schemes.ClassifyFile(first) != FileType_SplitPart {
+		t.Errorf("expected %v to be the first part with the custom scheme", first)
+	}
+	if parts, _, err := schemes.FileParts(first); err != nil || len(parts) != 2 {
+		t.Errorf("expected 2 parts, got %v (%v)", parts, err)
+	}
+	//the custom schemes don't change the package functions
+	if IsSplitPart(first) || ClassifyFile(first) != FileType_Unsupported {
+		t.Errorf("expected %v not to be a split part with the default schemes", first)
+	}
+}
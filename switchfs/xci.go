@@ -17,8 +17,19 @@ func ReadXciMetadata(filePath string) (map[string]*ContentMetaAttributes, error)
 
 	defer file.Close()
 
+	return readXciMetadata(file)
+}
+
+// ReadXciMetadataFrom reads the XCI/XCZ metadata from a reader (e.g. a remote file) holding size bytes,
+// reads beyond size fail as for a truncated file. only the partitions headers, the meta NCA and
+// the control NCA sections are read, not the whole file
+func ReadXciMetadataFrom(ra io.ReaderAt, size int64) (map[string]*ContentMetaAttributes, error) {
+	return readXciMetadata(io.NewSectionReader(ra, 0, size))
+}
+
+func readXciMetadata(file io.ReaderAt) (map[string]*ContentMetaAttributes, error) {
 	header := make([]byte, 0x200)
-	_, err = file.ReadAt(header, 0)
+	_, err := file.ReadAt(header, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -66,9 +77,12 @@ func ReadXciMetadata(filePath string) (map[string]*ContentMetaAttributes, error)
 					zap.S().Debug("Failed to extract nacp [%v]\n", err.Error())
 				}
 				currCnmt.Ncap = nacp
+				if nacp != nil {
+					currCnmt.SaveDataSize = nacp.SaveDataSize
+				}
 			}
 
-			contentMap[currCnmt.TitleId] = currCnmt
+			addContentMeta(contentMap, currCnmt)
 
 		} /* else if strings.Contains(pfs0File.Name, ".cnmt.xml") {
 			xmlBytes := make([]byte, pfs0File.Size)
@@ -0,0 +1,138 @@
+package switchfs
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+)
+
+type ZipEntry struct {
+	//the entry path inside the archive
+	Name string
+	//the uncompressed size
+	Size int64
+}
+
+// ZipEntries lists the files stored in the zip archive, ordered by name
+func ZipEntries(archivePath string) ([]ZipEntry, error) {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var entries []ZipEntry
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		entries = append(entries, ZipEntry{Name: file.Name, Size: int64(file.UncompressedSize64)})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// OpenZipEntry opens a file stored in the zip archive for reading, without extracting it.
+// stored (uncompressed) entries are read in place, compressed entries are decompressed as a stream -
+// reading backwards restarts the stream, so compressed entries are much slower to read
+func OpenZipEntry(archivePath string, name string) (ReadAtCloser, int64, error) {
+	file, err := _openFile(archivePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	reader, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	for _, entry := range reader.File {
+		if entry.Name != name {
+			continue
+		}
+		size := int64(entry.UncompressedSize64)
+		if entry.Method == zip.Store {
+			offset, err := entry.DataOffset()
+			if err != nil {
+				file.Close()
+				return nil, 0, err
+			}
+			return &zipStoredEntry{file: file, section: io.NewSectionReader(file, offset, size)}, size, nil
+		}
+		return &zipStreamEntry{file: file, entry: entry}, size, nil
+	}
+	file.Close()
+	return nil, 0, errors.New("no entry [" + name + "] in archive " + archivePath)
+}
+
+type zipStoredEntry struct {
+	file    *os.File
+	section *io.SectionReader
+}
+
+func (z *zipStoredEntry) ReadAt(p []byte, off int64) (int, error) {
+	return z.section.ReadAt(p, off)
+}
+
+func (z *zipStoredEntry) Close() error {
+	return z.file.Close()
+}
+
+type zipStreamEntry struct {
+	sync.Mutex
+	file   *os.File
+	entry  *zip.File
+	stream io.ReadCloser
+	//the position of the stream in the uncompressed data
+	position int64
+}
+
+func (z *zipStreamEntry) ReadAt(p []byte, off int64) (int, error) {
+	z.Lock()
+	defer z.Unlock()
+
+	if z.stream == nil || off < z.position {
+		if z.stream != nil {
+			z.stream.Close()
+		}
+		stream, err := z.entry.Open()
+		if err != nil {
+			z.stream = nil
+			return 0, err
+		}
+		z.stream = stream
+		z.position = 0
+	}
+	if off > z.position {
+		skipped, err := io.CopyN(ioutil.Discard, z.stream, off-z.position)
+		z.position += skipped
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := io.ReadFull(z.stream, p)
+	z.position += int64(n)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (z *zipStreamEntry) Close() error {
+	z.Lock()
+	defer z.Unlock()
+	if z.stream != nil {
+		z.stream.Close()
+	}
+	return z.file.Close()
+}
@@ -0,0 +1,81 @@
+package switchfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenZipEntry(t *testing.T) {
+	folder, err := ioutil.TempDir("", "slm-zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+
+	data := make([]byte, 256*1024)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	archivePath := filepath.Join(folder, "games.zip")
+	archive, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := zip.NewWriter(archive)
+	for _, entry := range []struct {
+		name   string
+		method uint16
+	}{{"stored.nsp", zip.Store}, {"sub/deflated.nsp", zip.Deflate}} {
+		w, err := writer.CreateHeader(&zip.FileHeader{Name: entry.name, Method: entry.method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive.Close()
+
+	entries, err := ZipEntries(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Name != "stored.nsp" || entries[1].Name != "sub/deflated.nsp" ||
+		entries[1].Size != int64(len(data)) {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+
+	for _, entry := range entries {
+		reader, size, err := OpenZipEntry(archivePath, entry.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != int64(len(data)) {
+			t.Errorf("[%v] expected size %v, got %v", entry.Name, len(data), size)
+		}
+		//forward, backward and overlapping reads
+		for _, off := range []int64{0, 200000, 1000, 1000, 150000} {
+			buf := make([]byte, 4096)
+			n, err := reader.ReadAt(buf, off)
+			if err != nil || n != len(buf) || !bytes.Equal(buf, data[off:off+int64(len(buf))]) {
+				t.Errorf("[%v] unexpected read at %v (%v bytes, %v)", entry.Name, off, n, err)
+			}
+		}
+		buf := make([]byte, 4096)
+		if _, err := reader.ReadAt(buf, size-100); err == nil {
+			t.Errorf("[%v] expected an error reading beyond the entry end", entry.Name)
+		}
+		reader.Close()
+	}
+
+	if _, _, err := OpenZipEntry(archivePath, "missing.nsp"); err == nil {
+		t.Errorf("expected an error for a missing entry")
+	}
+}